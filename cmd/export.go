@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/api"
+	"github.com/agidelle/effectivemobile/internal/config"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/agidelle/effectivemobile/internal/service"
+	"github.com/agidelle/effectivemobile/internal/storage"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exportCmd выгружает подписки в CSV. Логи идут в stderr, чтобы не смешиваться с выгрузкой в stdout.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export subscriptions to CSV",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadCfg()
+		if err != nil {
+			slog.Error("Error load config file .env", "error", err.Error())
+			os.Exit(1)
+		}
+
+		var filter domain.Filter
+		if userID, _ := cmd.Flags().GetString("user-id"); userID != "" {
+			filter.UserID = &userID
+		}
+		if serviceName, _ := cmd.Flags().GetString("service-name"); serviceName != "" {
+			filter.ServiceName = &serviceName
+		}
+		if expr, _ := cmd.Flags().GetString("filter"); expr != "" {
+			query, err := api.ParseQuery(expr)
+			if err != nil {
+				slog.Error("Invalid filter", "error", err)
+				os.Exit(1)
+			}
+			filter.Query = query
+		}
+
+		var out io.Writer = os.Stdout
+		if path, _ := cmd.Flags().GetString("output"); path != "" && path != "-" {
+			file, err := os.Create(path)
+			if err != nil {
+				slog.Error("Failed to create output file", "error", err.Error())
+				os.Exit(1)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		store := storage.NewPool(ctx, cfg)
+		defer store.CloseDB()
+
+		if err = service.NewService(store).ExportCSV(ctx, out, &filter); err != nil {
+			slog.Error("Export failed", "error", err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("user-id", "", "Export subscriptions of the user")
+	exportCmd.Flags().String("service-name", "", "Export subscriptions of the service")
+	exportCmd.Flags().String("filter", "", `Filter expression, e.g. 'price>=300 and service_name~"yandex"'`)
+	exportCmd.Flags().StringP("output", "o", "-", "Output file, - for stdout")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/agidelle/effectivemobile/internal/config"
+	"github.com/agidelle/effectivemobile/internal/service"
+	"github.com/agidelle/effectivemobile/internal/storage"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// importCmd загружает подписки из CSV и печатает отчет в stdout, логи идут в stderr.
+// Код возврата 2 - часть строк не загружена.
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import subscriptions from CSV",
+	Args:  cobra.MaximumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadCfg()
+		if err != nil {
+			slog.Error("Error load config file .env", "error", err.Error())
+			os.Exit(1)
+		}
+
+		var in io.Reader = os.Stdin
+		if len(args) == 1 && args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				slog.Error("Failed to open input file", "error", err.Error())
+				os.Exit(1)
+			}
+			defer file.Close()
+			in = file
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		store := storage.NewPool(ctx, cfg)
+		defer store.CloseDB()
+
+		report, err := service.NewService(store).ImportCSV(ctx, in, dryRun)
+		if err != nil {
+			slog.Error("Import failed", "error", err.Error())
+			os.Exit(1)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(report); err != nil {
+			slog.Error("Failed to encode report", "error", err.Error())
+		}
+		if report.Failed > 0 {
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	importCmd.Flags().Bool("dry-run", false, "Validate the file and report changes without saving them")
+	rootCmd.AddCommand(importCmd)
+}
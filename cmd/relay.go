@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/config"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/agidelle/effectivemobile/internal/service"
+	"github.com/agidelle/effectivemobile/internal/storage"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// relayCmd публикует события из outbox отдельным процессом, в этом случае в serve
+// relay отключается через SERVE_RELAY=false
+var relayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "Publish outbox events to configured sinks",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		initLogger()
+
+		cfg, err := config.LoadCfg()
+		if err != nil {
+			slog.Error("Error load config file .env", "error", err.Error())
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		store := storage.NewPool(ctx, cfg)
+		defer store.CloseDB()
+		var repo domain.Repository = store
+
+		slog.Info("Launch outbox relay", "sinks", cfg.Sinks())
+		service.NewRelay(repo, newEventSink(cfg, repo)).Run(ctx, cfg.OutboxRelayInterval)
+		slog.Info("Relay exiting")
+	},
+}
+
+// newEventSink собирает sink из OUTBOX_SINKS, имена проверяются при загрузке конфига
+func newEventSink(cfg *config.Config, repo domain.Repository) service.MultiSink {
+	var sinks service.MultiSink
+	for _, name := range cfg.Sinks() {
+		switch name {
+		case "webhook":
+			sinks = append(sinks, service.NewWebhookSink(repo))
+		case "log":
+			sinks = append(sinks, service.LogSink{})
+		}
+	}
+	return sinks
+}
+
+func init() {
+	rootCmd.AddCommand(relayCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/config"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/agidelle/effectivemobile/internal/service"
+	"github.com/agidelle/effectivemobile/internal/storage"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// reportCmd строит сводку за период и пишет ее в файл. Формат берется из --format
+// или из расширения файла.
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write subscriptions summary report to XLSX, PDF or JSON file",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), ".")
+		}
+		if _, ok := domain.ReportContentTypes[format]; !ok {
+			slog.Error("Unknown report format, use --format json, xlsx or pdf", "format", format)
+			os.Exit(1)
+		}
+
+		var filter domain.Filter
+		for flag, dst := range map[string]**time.Time{"start": &filter.StartDate, "end": &filter.EndDate} {
+			v, _ := cmd.Flags().GetString(flag)
+			t, err := time.Parse("01-2006", v)
+			if err != nil {
+				slog.Error("Invalid period, expected MM-YYYY", "flag", flag, "value", v)
+				os.Exit(1)
+			}
+			*dst = &t
+		}
+		if userID, _ := cmd.Flags().GetString("user-id"); userID != "" {
+			filter.UserID = &userID
+		}
+		if serviceName, _ := cmd.Flags().GetString("service-name"); serviceName != "" {
+			filter.ServiceName = &serviceName
+		}
+		if category, _ := cmd.Flags().GetString("category"); category != "" {
+			filter.Category = &category
+		}
+
+		cfg, err := config.LoadCfg()
+		if err != nil {
+			slog.Error("Error load config file .env", "error", err.Error())
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		store := storage.NewPool(ctx, cfg)
+		defer store.CloseDB()
+		svc := service.NewService(store)
+
+		report, err := svc.BuildReport(ctx, &filter)
+		if err != nil {
+			slog.Error("Failed to build report", "error", err.Error())
+			os.Exit(1)
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			slog.Error("Failed to create output file", "error", err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		if err = svc.RenderReport(file, report, format); err != nil {
+			slog.Error("Failed to write report", "error", err.Error())
+			os.Exit(1)
+		}
+		slog.Info("Report written", "file", output, "format", format, "subscriptions", len(report.Lines))
+	},
+}
+
+func init() {
+	reportCmd.Flags().String("start", "", "Period start, MM-YYYY")
+	reportCmd.Flags().String("end", "", "Period end, MM-YYYY")
+	reportCmd.Flags().String("user-id", "", "Report for the user, counting only their share of shared subscriptions")
+	reportCmd.Flags().String("service-name", "", "Report for the service")
+	reportCmd.Flags().String("category", "", "Report for the category")
+	reportCmd.Flags().String("format", "", "json, xlsx or pdf; by default taken from the output file extension")
+	reportCmd.Flags().StringP("output", "o", "", "Output file")
+	reportCmd.MarkFlagRequired("start")
+	reportCmd.MarkFlagRequired("end")
+	reportCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(reportCmd)
+}
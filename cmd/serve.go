@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"fmt"
 	"github.com/agidelle/effectivemobile/internal/api"
 	"github.com/agidelle/effectivemobile/internal/config"
 	"github.com/agidelle/effectivemobile/internal/domain"
@@ -32,13 +31,47 @@ var serveCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		// ctx живет до остановки сервера, по нему завершаются фоновые задачи
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
 
 		store := storage.NewPool(ctx, cfg)
 		var repo domain.Repository = store
-		var svc api.SubService = service.NewService(repo)
-		handler := api.NewHandler(svc)
+		opts := []service.Option{
+			service.WithWebhookRetry(cfg.WebhookMaxAttempts, cfg.WebhookRetryDelay),
+			service.WithExpiringWindow(cfg.ExpiringWindow),
+			service.WithReminderWindow(cfg.ReminderWindow),
+			service.WithAnomalyThresholds(cfg.AnomalyThresholdPercent, cfg.AnomalyJumpPercent),
+			service.WithJobFilterParser(api.JobFilter),
+			service.WithJobResultTTL(cfg.JobResultTTL),
+			service.WithIdempotencyTTL(cfg.IdempotencyTTL),
+			service.WithEventPollInterval(cfg.EventPollInterval),
+		}
+		if cfg.SMTPHost != "" {
+			opts = append(opts, service.WithNotifier(domain.ChannelEmail, service.NewSMTPNotifier(
+				cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)))
+		}
+		subService := service.NewService(repo, opts...)
+		var svc api.SubService = subService
+		handler := api.NewHandler(svc, api.WithBatchMaxSize(cfg.BatchMaxSize))
+
+		go subService.RunBudgetEvaluator(ctx, cfg.BudgetCheckInterval)
+		go subService.RunPriceChangeApplier(ctx, cfg.PriceChangeInterval)
+		go subService.RunWebhookDispatcher(ctx, cfg.WebhookPollInterval)
+		go subService.RunReminderScheduler(ctx, cfg.ReminderCheckInterval)
+		go subService.RunAnomalyDetector(ctx, cfg.AnomalyCheckInterval)
+		go subService.RunIdempotencyCleanup(ctx, cfg.IdempotencyCleanupInterval)
+		// JOB_WORKERS=0 - задачи только принимаются, выполняют их другие экземпляры
+		jobsDone := make(chan struct{})
+		go func() {
+			defer close(jobsDone)
+			if cfg.JobWorkers > 0 {
+				subService.RunJobWorkers(ctx, cfg.JobWorkers, cfg.JobPollInterval)
+			}
+		}()
+		if cfg.ServeRelay {
+			go service.NewRelay(repo, newEventSink(cfg, repo)).Run(ctx, cfg.OutboxRelayInterval)
+		}
 
 		r := chi.NewRouter()
 		r.Use(api.RecoverMiddleware)
@@ -47,9 +80,11 @@ var serveCmd = &cobra.Command{
 		handler.InitRoutes(r)
 
 		srv := &http.Server{
-			Addr:    ":" + fmt.Sprintf(cfg.AppPort),
+			Addr:    ":" + cfg.AppPort,
 			Handler: r,
 		}
+		// Shutdown не дожидается бесконечных SSE запросов: закрываем потоки, обработчики завершаются сами
+		srv.RegisterOnShutdown(subService.CloseEventStreams)
 
 		go func() {
 			slog.Info("Launch server", "port", cfg.AppPort)
@@ -70,6 +105,9 @@ var serveCmd = &cobra.Command{
 			slog.Error("Server Shutdown error", "error", err.Error())
 			os.Exit(1)
 		}
+		stop()
+		// прерванные задачи возвращаются в очередь до закрытия пула соединений
+		<-jobsDone
 		svc.CloseDB()
 		slog.Info("Server exiting")
 	},
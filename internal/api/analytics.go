@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxAnalyticsMonths ограничение длины периода отчетов
+const maxAnalyticsMonths = 120
+
+// ActiveSubscriptions godoc
+// @Summary      Активные подписки по месяцам
+// @Description  Число подписок и пользователей, за которые было списание в каждом месяце периода
+// @Tags         analytics
+// @Produce      json
+// @Param        start_date   query     string  true   "Начало периода MM-YYYY"
+// @Param        end_date     query     string  true   "Конец периода MM-YYYY"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Param        category     query     string  false  "Категория справочника"
+// @Success      200  {array}   domain.ActiveMonth
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/analytics/active [get]
+func (h *Handler) ActiveSubscriptions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	res, err := h.service.ActiveByMonth(ctx, filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeAnalytics(w, res)
+}
+
+// ARPU godoc
+// @Summary      Средняя выручка на пользователя по месяцам
+// @Description  Выручка по начислениям подписок с учетом истории цен и скидок промокодов, без налогов, как в сводке.
+// @Description  Делится на число платящих пользователей: владельцев подписок и участников совместных подписок по их долям
+// @Tags         analytics
+// @Produce      json
+// @Param        start_date   query     string  true   "Начало периода MM-YYYY"
+// @Param        end_date     query     string  true   "Конец периода MM-YYYY"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Param        category     query     string  false  "Категория справочника"
+// @Success      200  {array}   domain.ARPUMonth
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/analytics/arpu [get]
+func (h *Handler) ARPU(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	res, err := h.service.ARPUByMonth(ctx, filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeAnalytics(w, res)
+}
+
+// Churn godoc
+// @Summary      Новые и закончившиеся подписки по сервисам
+// @Description  new - подписки с первым списанием в месяце, ended - с последним списанием в месяце. Месяцы без изменений не выводятся
+// @Tags         analytics
+// @Produce      json
+// @Param        start_date   query     string  true   "Начало периода MM-YYYY"
+// @Param        end_date     query     string  true   "Конец периода MM-YYYY"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Param        category     query     string  false  "Категория справочника"
+// @Success      200  {array}   domain.ChurnMonth
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/analytics/churn [get]
+func (h *Handler) Churn(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	res, err := h.service.ChurnByMonth(ctx, filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeAnalytics(w, res)
+}
+
+// Cohorts godoc
+// @Summary      Когорты удержания по месяцу начала
+// @Description  Подписки, начавшиеся в периоде, группируются по месяцу начала. retained[k] - сколько действовало через k месяцев, до конца периода
+// @Tags         analytics
+// @Produce      json
+// @Param        start_date   query     string  true   "Начало периода MM-YYYY"
+// @Param        end_date     query     string  true   "Конец периода MM-YYYY"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Param        category     query     string  false  "Категория справочника"
+// @Success      200  {array}   domain.Cohort
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/analytics/cohorts [get]
+func (h *Handler) Cohorts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	res, err := h.service.Cohorts(ctx, filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeAnalytics(w, res)
+}
+
+// parseAnalyticsFilter период задается как в сводке: start_date и end_date в формате MM-YYYY, оба обязательны
+func parseAnalyticsFilter(r *http.Request) (*domain.AnalyticsFilter, error) {
+	var filter domain.AnalyticsFilter
+	q := r.URL.Query()
+
+	startStr, endStr := q.Get("start_date"), q.Get("end_date")
+	if startStr == "" || endStr == "" {
+		return nil, fmt.Errorf("start_date and end_date are required")
+	}
+	start, err := time.Parse(dateForm, startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date format, expected MM-YYYY")
+	}
+	end, err := time.Parse(dateForm, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end_date format, expected MM-YYYY")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date must not be before start_date")
+	}
+	if start.AddDate(0, maxAnalyticsMonths, 0).Before(end) {
+		return nil, fmt.Errorf("period must not exceed %d months", maxAnalyticsMonths)
+	}
+	filter.StartDate, filter.EndDate = start, end
+
+	if serviceName := q.Get("service_name"); serviceName != "" {
+		if len(serviceName) > 255 {
+			return nil, fmt.Errorf("service_name must not exceed 255 characters")
+		}
+		filter.ServiceName = &serviceName
+	}
+	if category := q.Get("category"); category != "" {
+		filter.Category = &category
+	}
+	return &filter, nil
+}
+
+func writeAnalytics(w http.ResponseWriter, res interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// ListPriceAnomalies godoc
+// @Summary      Аномалии цен подписок
+// @Description  Выбросы (outlier) относительно рекомендованной цены справочника или медианы цен других пользователей и резкие повышения (jump) относительно предыдущей цены подписки, новые первыми
+// @Tags         anomalies
+// @Produce      json
+// @Param        user_id       query     string  false  "ID пользователя"
+// @Param        service_name  query     string  false  "Название сервиса"
+// @Param        kind          query     string  false  "Тип аномалии (outlier, jump)"
+// @Param        limit         query     int     false  "Лимит"
+// @Success      200  {array}   domain.PriceAnomaly
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/price-anomalies [get]
+func (h *Handler) ListPriceAnomalies(w http.ResponseWriter, r *http.Request) {
+	var filter domain.PriceAnomalyFilter
+
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		if len(userID) != 36 {
+			http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &userID
+	}
+	if serviceName := r.URL.Query().Get("service_name"); serviceName != "" {
+		filter.ServiceName = &serviceName
+	}
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		if kind != domain.AnomalyOutlier && kind != domain.AnomalyJump {
+			http.Error(w, "kind must be outlier or jump", http.StatusBadRequest)
+			return
+		}
+		filter.Kind = &kind
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = &limit
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	anomalies, err := h.service.ListPriceAnomalies(ctx, &filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(anomalies); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
@@ -8,12 +8,16 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/agidelle/effectivemobile/internal/domain"
 	"github.com/go-chi/chi/v5"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,33 +25,179 @@ const (
 	dateForm   = "01-2006"
 	tOutnormal = 3 * time.Second
 	tOutlong   = 10 * time.Second
+	tOutexport = 5 * time.Minute
+
+	defaultUpcomingDays = 30
+	maxUpcomingDays     = 366
+
+	defaultForecastMonths = 12
+	maxForecastMonths     = 60
+
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	defaultBatchMaxSize = 500
 )
 
 type Handler struct {
-	service SubService
+	service      SubService
+	batchMaxSize int
+}
+
+type HandlerOption func(*Handler)
+
+// WithBatchMaxSize наибольшее число операций в POST /api/subscriptions/batch
+func WithBatchMaxSize(n int) HandlerOption {
+	return func(h *Handler) {
+		h.batchMaxSize = n
+	}
 }
 
 type SubService interface {
 	CloseDB()
 	Search(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error)
+	SearchPage(ctx context.Context, filter *domain.Filter) (*domain.Page, error)
 	CreateSubscription(ctx context.Context, input *domain.Subscription) error
 	UpdateSubscription(ctx context.Context, input *domain.Subscription) error
 	DeleteSubscription(ctx context.Context, filter *domain.Filter) error
-	GetSubscriptionsSummary(ctx context.Context, filter *domain.Filter) (int, error)
+	GetSubscriptionsSummary(ctx context.Context, filter *domain.Filter) (*domain.Summary, error)
+	CreateCoupon(ctx context.Context, coupon *domain.Coupon) error
+	ListCoupons(ctx context.Context) ([]*domain.Coupon, error)
+	DeleteCoupon(ctx context.Context, code string) error
+	AttachCoupon(ctx context.Context, userID, serviceName string, code *string, start *time.Time) error
+	SetTaxRate(ctx context.Context, rate *domain.TaxRate) error
+	ListTaxRates(ctx context.Context) ([]*domain.TaxRate, error)
+	DeleteTaxRate(ctx context.Context, region string) error
+	UpcomingCharges(ctx context.Context, filter *domain.Filter, days int) ([]*domain.Charge, error)
+	SetPriceChange(ctx context.Context, userID, serviceName string, change *domain.PriceChange) error
+	ListPriceChanges(ctx context.Context, userID, serviceName string) ([]domain.PriceChange, error)
+	DeletePriceChange(ctx context.Context, userID, serviceName string, effective time.Time) error
+	Forecast(ctx context.Context, filter *domain.Filter, months int, groupBy string) ([]*domain.ForecastMonth, error)
+	SetCatalogService(ctx context.Context, svc *domain.CatalogService) error
+	ListCatalog(ctx context.Context) ([]*domain.CatalogService, error)
+	DeleteCatalogService(ctx context.Context, serviceName string) error
+	CreateBudget(ctx context.Context, budget *domain.Budget) error
+	UpdateBudget(ctx context.Context, budget *domain.Budget) error
+	ListBudgets(ctx context.Context, userID *string) ([]*domain.Budget, error)
+	DeleteBudget(ctx context.Context, id int) error
+	ListBudgetAlerts(ctx context.Context, filter *domain.BudgetAlertFilter) ([]*domain.BudgetAlert, error)
+	CreateWebhookEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	ListWebhookEndpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id int) error
+	ListWebhookDeliveries(ctx context.Context, filter *domain.WebhookDeliveryFilter) ([]*domain.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, id int64) error
+	StreamEvents(ctx context.Context, userID string, lastEventID int64) (<-chan *domain.OutboxMessage, error)
+	SetNotificationPreferences(ctx context.Context, prefs *domain.NotificationPreferences) error
+	GetNotificationPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error)
+	DeleteNotificationPreferences(ctx context.Context, userID string) error
+	FindDuplicates(ctx context.Context, filter *domain.Filter) (*domain.DuplicateReport, error)
+	ActiveByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ActiveMonth, error)
+	ARPUByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ARPUMonth, error)
+	ChurnByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ChurnMonth, error)
+	Cohorts(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error)
+	ListPriceAnomalies(ctx context.Context, filter *domain.PriceAnomalyFilter) ([]*domain.PriceAnomaly, error)
+	ApplyBatch(ctx context.Context, mode string, ops []domain.BatchOperation) (*domain.BatchResponse, error)
+	ExportCSV(ctx context.Context, w io.Writer, filter *domain.Filter) error
+	ImportCSV(ctx context.Context, r io.Reader, dryRun bool) (*domain.ImportReport, error)
+	BuildReport(ctx context.Context, filter *domain.Filter) (*domain.Report, error)
+	RenderReport(w io.Writer, report *domain.Report, format string) error
+	CreateCalendarToken(ctx context.Context, userID string) (string, error)
+	DeleteCalendarToken(ctx context.Context, userID string) error
+	Calendar(ctx context.Context, userID, token string) (string, error)
+	EnqueueJob(ctx context.Context, kind string, params map[string]string) (*domain.Job, error)
+	GetJob(ctx context.Context, id string) (*domain.Job, error)
+	JobResult(ctx context.Context, id string) (*domain.JobResult, error)
+	BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotentResponse, error)
+	FinishIdempotentRequest(ctx context.Context, key string, resp *domain.IdempotentResponse) error
+	AbortIdempotentRequest(ctx context.Context, key string) error
 }
 
-func NewHandler(s SubService) *Handler {
-	return &Handler{service: s}
+func NewHandler(s SubService, opts ...HandlerOption) *Handler {
+	h := &Handler{service: s, batchMaxSize: defaultBatchMaxSize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handler) InitRoutes(r chi.Router) {
-	// Роуты для управления подписками
-	r.Get("/api/subscriptions", h.SearchSubscriptions)   // список подписок
-	r.Post("/api/subscriptions", h.CreateSubscription)   // создание новой подписки
-	r.Put("/api/subscriptions", h.UpdateSubscription)    // обновление подписки по ID
-	r.Delete("/api/subscriptions", h.DeleteSubscription) // удаление подписки по ID
+	// Роуты для управления подписками, изменяющие запросы принимают заголовок Idempotency-Key
+	r.Get("/api/subscriptions", h.SearchSubscriptions)                 // список подписок
+	r.Post("/api/subscriptions", h.idempotent(h.CreateSubscription))   // создание новой подписки
+	r.Put("/api/subscriptions", h.idempotent(h.UpdateSubscription))    // обновление подписки по ID
+	r.Delete("/api/subscriptions", h.idempotent(h.DeleteSubscription)) // удаление подписки по ID
+
+	r.Post("/api/subscriptions/batch", h.idempotent(h.BatchSubscriptions)) // пакет операций с подписками
+	r.Get("/api/subscriptions/export", h.ExportSubscriptions)              // выгрузка в CSV
+	r.Post("/api/subscriptions/import", h.ImportSubscriptions)             // загрузка из CSV
+	r.Post("/api/subscriptions/summary", h.GetSubscriptionsSummary)        // сводная информация по подпискам
+	r.Get("/api/subscriptions/upcoming", h.UpcomingCharges)                // предстоящие списания
+	r.Get("/api/subscriptions/forecast", h.Forecast)                       // прогноз расходов
+	r.Get("/api/subscriptions/duplicates", h.FindDuplicates)               // дубликаты и пересечения
+	// поток изменений (SSE) только своих подписок, поэтому JWT обязателен независимо от serve
+	r.With(JWTMiddleware).Get("/api/subscriptions/events", h.SubscriptionEvents)
+
+	// История и запланированные изменения цены
+	r.Get("/api/subscriptions/price-changes", h.ListPriceChanges)
+	r.Put("/api/subscriptions/price-changes", h.SetPriceChange)
+	r.Delete("/api/subscriptions/price-changes", h.DeletePriceChange)
+	r.Get("/api/price-anomalies", h.ListPriceAnomalies) // аномальные цены и резкие повышения
+	r.Get("/api/reports", h.Report)                     // сводка за период в JSON, XLSX или PDF
+
+	// фоновые задачи для долгих выгрузок и отчетов
+	r.Post("/api/jobs", h.CreateJob)
+	r.Get("/api/jobs/{id}", h.GetJob)
+	r.Get("/api/jobs/{id}/result", h.JobResult)
+
+	// календарь списаний по секретной ссылке
+	r.Get("/api/users/{user_id}/calendar.ics", h.Calendar)
+	// токен выдает и отзывает только сам пользователь, поэтому JWT обязателен независимо от serve
+	r.With(JWTMiddleware).Post("/api/users/{user_id}/calendar-token", h.CreateCalendarToken)
+	r.With(JWTMiddleware).Delete("/api/users/{user_id}/calendar-token", h.DeleteCalendarToken)
+
+	// Аналитика по подпискам
+	r.Route("/api/analytics", func(r chi.Router) {
+		r.Get("/active", h.ActiveSubscriptions)
+		r.Get("/arpu", h.ARPU)
+		r.Get("/churn", h.Churn)
+		r.Get("/cohorts", h.Cohorts)
+	})
 
-	r.Post("/api/subscriptions/summary", h.GetSubscriptionsSummary) // сводная информация по подпискам
+	// Справочник сервисов
+	r.Get("/api/catalog", h.ListCatalog)
+	r.Put("/api/catalog", h.SetCatalogService)
+	r.Delete("/api/catalog", h.DeleteCatalogService)
+
+	// Бюджеты и оповещения о превышении
+	r.Get("/api/budgets", h.ListBudgets)
+	r.Post("/api/budgets", h.CreateBudget)
+	r.Put("/api/budgets", h.UpdateBudget)
+	r.Delete("/api/budgets", h.DeleteBudget)
+	r.Get("/api/budgets/alerts", h.ListBudgetAlerts)
+
+	// Настройки напоминаний о списаниях
+	r.Get("/api/notification-preferences", h.GetNotificationPreferences)
+	r.Put("/api/notification-preferences", h.SetNotificationPreferences)
+	r.Delete("/api/notification-preferences", h.DeleteNotificationPreferences)
+
+	// Вебхуки о событиях подписок и журнал доставок
+	r.Get("/api/webhooks", h.ListWebhooks)
+	r.Post("/api/webhooks", h.CreateWebhook)
+	r.Delete("/api/webhooks", h.DeleteWebhook)
+	r.Get("/api/webhooks/deliveries", h.ListWebhookDeliveries)
+	r.Post("/api/webhooks/deliveries/{id}/replay", h.ReplayWebhookDelivery)
+
+	// Промокоды
+	r.Get("/api/coupons", h.ListCoupons)
+	r.Post("/api/coupons", h.CreateCoupon)
+	r.Delete("/api/coupons", h.DeleteCoupon)
+	r.Put("/api/subscriptions/coupon", h.AttachCoupon)    // привязка промокода к подписке
+	r.Delete("/api/subscriptions/coupon", h.DetachCoupon) // отвязка промокода
+
+	// Ставки налога по регионам
+	r.Get("/api/tax-rates", h.ListTaxRates)
+	r.Put("/api/tax-rates", h.SetTaxRate)
+	r.Delete("/api/tax-rates", h.DeleteTaxRate)
 }
 
 func RecoverMiddleware(next http.Handler) http.Handler {
@@ -69,85 +219,176 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 // @Produce      json
 // @Param        user_id      query     string  false  "ID пользователя"
 // @Param        service_name query     string  false  "Название сервиса"
-// @Param        price        query     int     false  "Цена"
+// @Param        price        query     string  false  "Цена, десятичная строка 299.99"
 // @Param        start_date   query     string  false  "Дата начала MM-YYYY"
 // @Param        end_date     query     string  false  "Дата окончания MM-YYYY"
 // @Param        limit        query     int     false  "Лимит"
 // @Param        offset       query     int     false  "Смещение"
+// @Param        q            query     string  false  "Нечеткий поиск по названию сервиса: подстрока или опечатка. Без sort результаты упорядочены по сходству"
+// @Param        price_min    query     string  false  "Минимальная цена включительно"
+// @Param        price_max    query     string  false  "Максимальная цена включительно"
+// @Param        active_from  query     string  false  "Действует хотя бы месяц в периоде с MM-YYYY"
+// @Param        active_to    query     string  false  "Действует хотя бы месяц в периоде по MM-YYYY"
+// @Param        started_after   query  string  false  "Начало строго после MM-YYYY"
+// @Param        started_before  query  string  false  "Начало строго до MM-YYYY"
+// @Param        sort         query     string  false  "Сортировка через запятую, минус - по убыванию: -start_date,price. Поля: id, user_id, service_name, category, price, start_date, end_date"
+// @Param        cursor       query     string  false  "Курсор следующей страницы из next_cursor; пустое значение - первая страница. Включает ответ domain.Page"
+// @Param        page_size    query     int     false  "Размер страницы при постраничном ответе, по умолчанию 50, не больше 500"
+// @Param        total        query     bool    false  "Посчитать общее число подписок по фильтру (total)"
+// @Param        fields       query     string  false  "Поля подписки в ответе через запятую, например id,service_name,price"
+// @Param        expand       query     string  false  "Связанные данные через запятую: price_changes, catalog, audit"
+// @Param        filter       query     string  false  "Выражение фильтра, например price>=300 and start_date<2025-01 and service_name~\"yandex\". Поля: user_id, service_name, category, price, start_date, end_date; операторы = != < <= > >= ~; and, or, not, скобки, null"
 // @Success      200  {array}  domain.Subscription
 // @Failure      400  {string}  string  "bad request"
 // @Failure      500  {string}  string  "internal error"
 // @Router       /api/subscriptions [get]
 func (h *Handler) SearchSubscriptions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSearchFilter(r.URL.Query())
+	if err != nil {
+		slog.Error("Invalid filter", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if expand := r.URL.Query().Get("expand"); expand != "" {
+		list, err := parseFieldList("expand", expand, domain.ExpandFields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Expand = list
+	}
+	var fields []string
+	if fieldsStr := r.URL.Query().Get("fields"); fieldsStr != "" {
+		list, err := parseFieldList("fields", fieldsStr, subscriptionFields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fields = list
+	}
+	// cursor или page_size включают постраничный ответ, без них ответ - массив, как раньше
+	query := r.URL.Query()
+	if query.Has("cursor") || query.Has("page_size") {
+		h.searchPage(w, r, filter, fields)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	subs, err := h.service.Search(ctx, filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if subs == nil {
+		subs = []*domain.Subscription{}
+	}
+	var body interface{} = subs
+	if fields != nil {
+		if body, err = selectFields(subs, fields); err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseSearchFilter фильтр поиска подписок из параметров запроса, общий для списка, выгрузки и задач выгрузки
+func parseSearchFilter(query url.Values) (*domain.Filter, error) {
 	var filter domain.Filter
 
-	userID := r.URL.Query().Get("user_id")
+	userID := query.Get("user_id")
 	if userID != "" && len(userID) == 36 {
 		filter.UserID = &userID
 	}
-	serviceName := r.URL.Query().Get("service_name")
+	serviceName := query.Get("service_name")
 	if serviceName != "" && len(serviceName) <= 255 {
 		filter.ServiceName = &serviceName
 	}
-	priceStr := r.URL.Query().Get("price")
+	priceStr := query.Get("price")
 	if priceStr != "" {
-		if price, err := strconv.Atoi(priceStr); err == nil {
+		if price, err := domain.ParseMoney(priceStr); err == nil {
 			filter.Price = &price
 		}
 	}
-	startDateStr := r.URL.Query().Get("start_date")
+	startDateStr := query.Get("start_date")
 	if startDateStr != "" {
 		if t, err := time.Parse(dateForm, startDateStr); err == nil {
 			filter.StartDate = &t
 		} else {
-			http.Error(w, "invalid start_date format, expected MM-YYYY", http.StatusBadRequest)
-			return
+			return nil, fmt.Errorf("invalid start_date format, expected MM-YYYY")
 		}
 	}
-	endDateStr := r.URL.Query().Get("end_date")
+	endDateStr := query.Get("end_date")
 	if endDateStr != "" {
 		if t, err := time.Parse(dateForm, endDateStr); err == nil {
 			filter.EndDate = &t
 		} else {
-			http.Error(w, "invalid start_date format, expected MM-YYYY", http.StatusBadRequest)
-			return
+			return nil, fmt.Errorf("invalid start_date format, expected MM-YYYY")
 		}
 	}
-	limitStr := r.URL.Query().Get("limit")
+	limitStr := query.Get("limit")
 	if limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
 			filter.Limit = &limit
 		}
 	}
-	offsetStr := r.URL.Query().Get("offset")
+	offsetStr := query.Get("offset")
 	if offsetStr != "" {
 		if offset, err := strconv.Atoi(offsetStr); err == nil {
 			filter.Offset = &offset
 		}
 	}
-	if err := validateFilter(&filter); err != nil {
-		slog.Error("Invalid filter", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	for name, dst := range map[string]**domain.Money{"price_min": &filter.PriceMin, "price_max": &filter.PriceMax} {
+		if v := query.Get(name); v != "" {
+			price, err := domain.ParseMoney(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s, expected decimal string", name)
+			}
+			*dst = &price
+		}
 	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
-	defer cancel()
-	subs, err := h.service.Search(ctx, &filter)
-	if err != nil {
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+	for name, dst := range map[string]**time.Time{
+		"active_from":    &filter.ActiveFrom,
+		"active_to":      &filter.ActiveTo,
+		"started_after":  &filter.StartedAfter,
+		"started_before": &filter.StartedBefore,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(dateForm, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s format, expected MM-YYYY", name)
+			}
+			*dst = &t
+		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if subs == nil {
-		subs = []*domain.Subscription{}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		filter.Q = &q
 	}
-	if err := json.NewEncoder(w).Encode(subs); err != nil {
-		slog.Error("Failed to encode to JSON", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	if sortStr := query.Get("sort"); sortStr != "" {
+		sort, err := parseSort(sortStr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Sort = sort
 	}
+	if expr := query.Get("filter"); expr != "" {
+		expr, err := ParseQuery(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Query = expr
+	}
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
 }
 
 // CreateSubscription godoc
@@ -157,8 +398,11 @@ func (h *Handler) SearchSubscriptions(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        subscription  body  domain.SubscriptionInput  true  "Данные подписки"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом и запросом возвращает сохраненный ответ"
 // @Success      201  {string}  string  "created"
 // @Failure      400  {string}  string  "bad request"
+// @Failure      409  {string}  string  "subscription already exists or request with this Idempotency-Key is in progress"
+// @Failure      422  {string}  string  "Idempotency-Key reused with different request"
 // @Failure      500  {string}  string  "internal error"
 // @Router       /api/subscriptions [post]
 func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
@@ -168,7 +412,7 @@ func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "error decode", http.StatusBadRequest)
 		return
 	}
-	err := validateSubscriptionInput(&input)
+	err := input.Validate()
 	if err != nil {
 		slog.Error("Invalid subscription input", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -182,6 +426,11 @@ func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if err := h.service.CreateSubscription(ctx, sub); err != nil {
+		// повтор создания без Idempotency-Key упирается в уникальный индекс, это не ошибка сервера
+		if err.Error() == "subscription already exists" {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
@@ -197,8 +446,11 @@ func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        subscription  body  domain.SubscriptionInput  true  "Данные подписки"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом и запросом возвращает сохраненный ответ"
 // @Success      200  {string}  string  "updated"
 // @Failure      400  {string}  string  "bad request"
+// @Failure      409  {string}  string  "request with this Idempotency-Key is in progress"
+// @Failure      422  {string}  string  "Idempotency-Key reused with different request"
 // @Failure      500  {string}  string  "internal error"
 // @Router       /api/subscriptions [put]
 func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
@@ -208,7 +460,7 @@ func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "error decode", http.StatusBadRequest)
 		return
 	}
-	err := validateSubscriptionInput(&input)
+	err := input.Validate()
 	if err != nil {
 		slog.Error("Invalid subscription input", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -238,9 +490,12 @@ func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        user_id      query     string  true  "ID пользователя"
 // @Param        service_name query     string  true  "Название сервиса"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом и запросом возвращает сохраненный ответ"
 // @Success      200  {string}  string  "deleted"
 // @Failure      400  {string}  string  "bad request"
 // @Failure      404  {string}  string  "not found"
+// @Failure      409  {string}  string  "request with this Idempotency-Key is in progress"
+// @Failure      422  {string}  string  "Idempotency-Key reused with different request"
 // @Failure      500  {string}  string  "internal error"
 // @Router       /api/subscriptions [delete]
 func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
@@ -280,13 +535,14 @@ func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 
 // GetSubscriptionsSummary godoc
 // @Summary      Получить сумму подписок за период
-// @Description  Сводная информация по подпискам за период
+// @Description  Сводная информация по подпискам за период: сумма к оплате, сумма без скидок, размер скидок и разбивка на сумму без налога, налог и сумму с налогом. При фильтре по user_id учитывается доля пользователя в совместных подписках
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        filter  body  domain.Filter  true  "Фильтр с датами"
-// @Success      200  {object}  map[string]int
+// @Success      200  {object}  domain.Summary
 // @Failure      400  {string}  string  "bad request"
+// @Failure      422  {string}  string  "amount overflow"
 // @Failure      500  {string}  string  "internal error"
 // @Router       /api/subscriptions/summary [post]
 func (h *Handler) GetSubscriptionsSummary(w http.ResponseWriter, r *http.Request) {
@@ -321,12 +577,15 @@ func (h *Handler) GetSubscriptionsSummary(w http.ResponseWriter, r *http.Request
 
 	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
 	defer cancel()
-	total, err := h.service.GetSubscriptionsSummary(ctx, &filter)
+	resp, err := h.service.GetSubscriptionsSummary(ctx, &filter)
 	if err != nil {
+		if errors.Is(err, domain.ErrMoneyOverflow) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
-	resp := map[string]int{"total_price": total}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -335,31 +594,54 @@ func (h *Handler) GetSubscriptionsSummary(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func validateSubscriptionInput(input *domain.SubscriptionInput) error {
-	if input.UserID == nil || *input.UserID == "" || len(*input.UserID) != 36 {
-		return fmt.Errorf("user_id is required correct format UUID")
-	}
-	if input.ServiceName == nil || *input.ServiceName == "" {
-		return fmt.Errorf("service_name is required")
+// UpcomingCharges godoc
+// @Summary      Предстоящие списания
+// @Description  Списания по подпискам в ближайшие days дней (по умолчанию 30). Списание происходит первого числа каждого месяца действия подписки
+// @Tags         subscriptions
+// @Produce      json
+// @Param        days         query     int     false  "Горизонт в днях, не более 366"
+// @Param        user_id      query     string  false  "ID пользователя"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Success      200  {array}   domain.Charge
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/upcoming [get]
+func (h *Handler) UpcomingCharges(w http.ResponseWriter, r *http.Request) {
+	var filter domain.Filter
+
+	days := defaultUpcomingDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 || d > maxUpcomingDays {
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+		days = d
 	}
-	if input.ServiceName != nil && len(*input.ServiceName) > 255 {
-		return fmt.Errorf("service_name must not exceed 255 characters")
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		filter.UserID = &userID
 	}
-	if input.Price == nil || *input.Price <= 0 {
-		return fmt.Errorf("price must be positive")
+	if serviceName := r.URL.Query().Get("service_name"); serviceName != "" {
+		filter.ServiceName = &serviceName
 	}
-	if input.StartDate == nil || *input.StartDate == "" {
-		return fmt.Errorf("start_date is required")
+	if err := validateFilter(&filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if _, err := time.Parse(dateForm, *input.StartDate); err != nil {
-		return fmt.Errorf("invalid start_date format, expected MM-YYYY")
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	charges, err := h.service.UpcomingCharges(ctx, &filter, days)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
-	if input.EndDate != nil && *input.EndDate != "" {
-		if _, err := time.Parse(dateForm, *input.EndDate); err != nil {
-			return fmt.Errorf("invalid end_date format, expected MM-YYYY")
-		}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(charges); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
 	}
-	return nil
 }
 
 func validateFilter(filter *domain.Filter) error {
@@ -382,6 +664,18 @@ func validateFilter(filter *domain.Filter) error {
 			return fmt.Errorf("invalid end_date format, expected MM-YYYY")
 		}
 	}
+	if filter.Q != nil && len(*filter.Q) > 255 {
+		return fmt.Errorf("q must not exceed 255 characters")
+	}
+	if filter.PriceMin != nil && *filter.PriceMin < 0 {
+		return fmt.Errorf("price_min must be non-negative")
+	}
+	if filter.PriceMin != nil && filter.PriceMax != nil && *filter.PriceMin > *filter.PriceMax {
+		return fmt.Errorf("price_min must not exceed price_max")
+	}
+	if filter.ActiveFrom != nil && filter.ActiveTo != nil && filter.ActiveFrom.After(*filter.ActiveTo) {
+		return fmt.Errorf("active_from must not be after active_to")
+	}
 	if filter.Limit != nil && *filter.Limit <= 0 {
 		return fmt.Errorf("limit must be positive")
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
@@ -11,6 +12,22 @@ import (
 // Пример реализации JWT аутентификации
 var jwtSecret = []byte("secretkey")
 
+type ctxKey string
+
+const userIDKey ctxKey = "user_id"
+
+// UserIDFromContext user_id из JWT, если запрос прошел через JWTMiddleware
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey).(string)
+	return userID, ok && userID != ""
+}
+
+// sameUser запрос прошел через JWTMiddleware от имени пользователя userID
+func sameUser(r *http.Request, userID string) bool {
+	authUserID, ok := UserIDFromContext(r.Context())
+	return ok && authUserID == userID
+}
+
 func GenerateJWT(userID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
@@ -47,6 +64,11 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if userID, ok := claims["user_id"].(string); ok {
+				r = r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// BatchSubscriptions godoc
+// @Summary      Пакет операций с подписками
+// @Description  Операции create, update и delete выполняются по порядку. В режиме atomic (по умолчанию) при ошибке любой операции ничего не сохраняется и возвращается 422 с результатами; в режиме partial каждая операция выполняется независимо. Повтор с тем же Idempotency-Key и другим телом - 422
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        batch  body  domain.BatchRequest  true  "Операции"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом и запросом возвращает сохраненный ответ"
+// @Success      200  {object}  domain.BatchResponse
+// @Failure      400  {string}  string  "bad request"
+// @Failure      409  {string}  string  "request with this Idempotency-Key is in progress"
+// @Failure      413  {string}  string  "batch too large"
+// @Failure      422  {object}  domain.BatchResponse
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/batch [post]
+func (h *Handler) BatchSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var req domain.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = domain.BatchAtomic
+	}
+	if req.Mode != domain.BatchAtomic && req.Mode != domain.BatchPartial {
+		http.Error(w, "mode must be atomic or partial", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		http.Error(w, "operations are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > h.batchMaxSize {
+		http.Error(w, "batch must not exceed "+strconv.Itoa(h.batchMaxSize)+" operations", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// ошибки проверки попадают в результаты, в пакет идут только корректные операции
+	resp := &domain.BatchResponse{Mode: req.Mode, Results: make([]domain.BatchResult, len(req.Operations))}
+	ops := make([]domain.BatchOperation, 0, len(req.Operations))
+	indexes := make([]int, 0, len(req.Operations))
+	for i := range req.Operations {
+		input := &req.Operations[i]
+		resp.Results[i] = domain.BatchResult{Index: i, Op: input.Op, Status: domain.BatchSkipped}
+		op, err := batchOperation(input)
+		if err != nil {
+			resp.Results[i].Status = domain.BatchFailed
+			resp.Results[i].Error = err.Error()
+			resp.Failed++
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+	if resp.Failed > 0 && req.Mode == domain.BatchAtomic {
+		writeBatchResponse(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+	if len(ops) == 0 {
+		writeBatchResponse(w, http.StatusOK, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+
+	applied, err := h.service.ApplyBatch(ctx, req.Mode, ops)
+	if err != nil && !errors.Is(err, domain.ErrBatchAborted) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	for j, res := range applied.Results {
+		res.Index = indexes[j]
+		resp.Results[indexes[j]] = res
+	}
+	resp.Succeeded = applied.Succeeded
+	resp.Failed += applied.Failed
+
+	status := http.StatusOK
+	if errors.Is(err, domain.ErrBatchAborted) {
+		status = http.StatusUnprocessableEntity
+	}
+	writeBatchResponse(w, status, resp)
+}
+
+// batchOperation проверяет операцию пакета теми же правилами, что и одиночные запросы
+func batchOperation(input *domain.BatchOperationInput) (domain.BatchOperation, error) {
+	op := domain.BatchOperation{Op: input.Op}
+	switch input.Op {
+	case domain.BatchCreate, domain.BatchUpdate:
+		if input.Subscription == nil {
+			return op, fmt.Errorf("subscription is required")
+		}
+		if err := input.Subscription.Validate(); err != nil {
+			return op, err
+		}
+		op.Subscription = domain.NewSubscription(input.Subscription.SubscriptionToOptions()...)
+	case domain.BatchDelete:
+		if input.UserID == nil || len(*input.UserID) != 36 {
+			return op, fmt.Errorf("user_id is required correct format UUID")
+		}
+		if input.ServiceName == nil || *input.ServiceName == "" || len(*input.ServiceName) > 255 {
+			return op, fmt.Errorf("service_name is required and must not exceed 255 characters")
+		}
+		op.UserID = *input.UserID
+		op.ServiceName = *input.ServiceName
+	default:
+		return op, fmt.Errorf("op must be create, update or delete")
+	}
+	return op, nil
+}
+
+func writeBatchResponse(w http.ResponseWriter, status int, resp *domain.BatchResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
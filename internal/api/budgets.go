@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+var defaultThresholds = []int{80, 100}
+
+// ListBudgets godoc
+// @Summary      Получить список бюджетов
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id  query     string  false  "ID пользователя"
+// @Success      200  {array}   domain.Budget
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/budgets [get]
+func (h *Handler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	var userID *string
+	if id := r.URL.Query().Get("user_id"); id != "" {
+		if len(id) != 36 {
+			http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+			return
+		}
+		userID = &id
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	budgets, err := h.service.ListBudgets(ctx, userID)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(budgets); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// CreateBudget godoc
+// @Summary      Создать месячный бюджет
+// @Description  Бюджет задается для пользователя, категории сервисов или пользователя в категории. Пороги в процентах, по умолчанию 80 и 100
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        budget  body  domain.Budget  true  "Бюджет"
+// @Success      201  {object}  domain.Budget
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/budgets [post]
+func (h *Handler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var budget domain.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validateBudget(&budget); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.CreateBudget(ctx, &budget); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(budget); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// UpdateBudget godoc
+// @Summary      Обновить бюджет
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        budget  body  domain.Budget  true  "Бюджет с id"
+// @Success      200  {string}  string  "updated"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/budgets [put]
+func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	var budget domain.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if budget.ID <= 0 {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	if err := validateBudget(&budget); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.UpdateBudget(ctx, &budget); err != nil {
+		if err.Error() == "budget not found" {
+			http.Error(w, "budget not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteBudget godoc
+// @Summary      Удалить бюджет
+// @Tags         budgets
+// @Produce      json
+// @Param        id  query     int  true  "ID бюджета"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/budgets [delete]
+func (h *Handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteBudget(ctx, id); err != nil {
+		if err.Error() == "budget not found" {
+			http.Error(w, "budget not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListBudgetAlerts godoc
+// @Summary      Оповещения о превышении бюджетов
+// @Description  Оповещения о пересечении порогов фактическими (actual) и прогнозными (forecast) расходами, новые первыми
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id    query     string  false  "ID пользователя"
+// @Param        budget_id  query     int     false  "ID бюджета"
+// @Param        limit      query     int     false  "Лимит"
+// @Success      200  {array}   domain.BudgetAlert
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/budgets/alerts [get]
+func (h *Handler) ListBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	var filter domain.BudgetAlertFilter
+
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		if len(userID) != 36 {
+			http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &userID
+	}
+	if budgetIDStr := r.URL.Query().Get("budget_id"); budgetIDStr != "" {
+		budgetID, err := strconv.Atoi(budgetIDStr)
+		if err != nil {
+			http.Error(w, "invalid budget_id", http.StatusBadRequest)
+			return
+		}
+		filter.BudgetID = &budgetID
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = &limit
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	alerts, err := h.service.ListBudgetAlerts(ctx, &filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(alerts); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+func validateBudget(budget *domain.Budget) error {
+	if budget.UserID == nil && budget.Category == nil {
+		return fmt.Errorf("user_id or category is required")
+	}
+	if budget.UserID != nil && len(*budget.UserID) != 36 {
+		return fmt.Errorf("user_id must be correct format UUID")
+	}
+	if budget.Category != nil && (*budget.Category == "" || len(*budget.Category) > 64) {
+		return fmt.Errorf("category must be 1 to 64 characters")
+	}
+	if budget.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if len(budget.Thresholds) == 0 {
+		budget.Thresholds = defaultThresholds
+	}
+	for _, t := range budget.Thresholds {
+		if t <= 0 || t > 1000 {
+			return fmt.Errorf("thresholds must be between 1 and 1000 percent")
+		}
+	}
+	if budget.WebhookURL != nil {
+		u, err := url.Parse(*budget.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be absolute http(s) URL")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+type calendarTokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// Calendar godoc
+// @Summary      Календарь списаний
+// @Description  Календарь iCalendar (RFC 5545) со списаниями по подпискам пользователя: повторяющееся событие первого числа каждого месяца с суммой доли пользователя и событие окончания подписки. Доступен по секретному токену из POST /api/users/{user_id}/calendar-token
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        user_id  path      string  true  "ID пользователя"
+// @Param        token    query     string  true  "Токен календаря"
+// @Success      200  {string}  string  "calendar"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/users/{user_id}/calendar.ics [get]
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	token := r.URL.Query().Get("token")
+	// без токена и для неверного токена ответ одинаковый, чтобы не раскрывать пользователей
+	if len(userID) != 36 || token == "" {
+		http.Error(w, "calendar not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	calendar, err := h.service.Calendar(ctx, userID, token)
+	if err != nil {
+		if errors.Is(err, domain.ErrCalendarToken) {
+			http.Error(w, "calendar not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="subscriptions.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(calendar)); err != nil {
+		slog.Error("Failed to write calendar", "error", err)
+	}
+}
+
+// CreateCalendarToken godoc
+// @Summary      Выпустить токен календаря
+// @Description  Создает секретный токен ссылки на календарь списаний, прежний токен перестает действовать. Токен показывается один раз. Нужен JWT пользователя user_id
+// @Tags         calendar
+// @Produce      json
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Param        Authorization  header    string  true  "Bearer JWT"
+// @Success      201  {object}  calendarTokenResponse
+// @Failure      400  {string}  string  "bad request"
+// @Failure      401  {string}  string  "unauthorized"
+// @Failure      403  {string}  string  "forbidden"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/users/{user_id}/calendar-token [post]
+func (h *Handler) CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	if len(userID) != 36 {
+		http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+		return
+	}
+	if !sameUser(r, userID) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	token, err := h.service.CreateCalendarToken(ctx, userID)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := calendarTokenResponse{
+		Token: token,
+		URL:   "/api/users/" + url.PathEscape(userID) + "/calendar.ics?token=" + url.QueryEscape(token),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// DeleteCalendarToken godoc
+// @Summary      Отозвать токен календаря
+// @Description  Ссылка на календарь перестает работать. Нужен JWT пользователя user_id
+// @Tags         calendar
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Param        Authorization  header    string  true  "Bearer JWT"
+// @Success      200  {string}  string  "deleted"
+// @Failure      401  {string}  string  "unauthorized"
+// @Failure      403  {string}  string  "forbidden"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/users/{user_id}/calendar-token [delete]
+func (h *Handler) DeleteCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	if !sameUser(r, userID) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	if err := h.service.DeleteCalendarToken(ctx, userID); err != nil {
+		if err.Error() == "calendar token not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
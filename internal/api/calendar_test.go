@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type calendarService struct {
+	SubService
+	issued  []string
+	deleted []string
+}
+
+func (s *calendarService) CreateCalendarToken(ctx context.Context, userID string) (string, error) {
+	s.issued = append(s.issued, userID)
+	return "token", nil
+}
+
+func (s *calendarService) DeleteCalendarToken(ctx context.Context, userID string) error {
+	s.deleted = append(s.deleted, userID)
+	return nil
+}
+
+func TestCalendarTokenRequiresOwner(t *testing.T) {
+	const owner = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	const other = "7f1c2a4e-8d3b-4c5a-9e6f-1a2b3c4d5e6f"
+	ownerJWT, err := GenerateJWT(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		auth   string
+		want   int
+	}{
+		{"issue without JWT", http.MethodPost, "", http.StatusUnauthorized},
+		{"issue with invalid JWT", http.MethodPost, "Bearer bad", http.StatusUnauthorized},
+		{"issue for another user", http.MethodPost, "Bearer " + ownerJWT, http.StatusForbidden},
+		{"revoke without JWT", http.MethodDelete, "", http.StatusUnauthorized},
+		{"revoke for another user", http.MethodDelete, "Bearer " + ownerJWT, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &calendarService{}
+			r := chi.NewRouter()
+			NewHandler(svc).InitRoutes(r)
+			req := httptest.NewRequest(tt.method, "/api/users/"+other+"/calendar-token", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if len(svc.issued) != 0 || len(svc.deleted) != 0 {
+				t.Errorf("token changed: issued %v, deleted %v", svc.issued, svc.deleted)
+			}
+		})
+	}
+
+	svc := &calendarService{}
+	r := chi.NewRouter()
+	NewHandler(svc).InitRoutes(r)
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/api/users/"+owner+"/calendar-token", nil)
+		req.Header.Set("Authorization", "Bearer "+ownerJWT)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+			t.Errorf("%s by owner: status = %d", method, w.Code)
+		}
+	}
+	if len(svc.issued) != 1 || len(svc.deleted) != 1 {
+		t.Errorf("owner requests: issued %v, deleted %v", svc.issued, svc.deleted)
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+)
+
+// ListCatalog godoc
+// @Summary      Получить справочник сервисов
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {array}   domain.CatalogService
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/catalog [get]
+func (h *Handler) ListCatalog(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	catalog, err := h.service.ListCatalog(ctx)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(catalog); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// SetCatalogService godoc
+// @Summary      Добавить или обновить сервис в справочнике
+// @Tags         catalog
+// @Accept       json
+// @Produce      json
+// @Param        service  body  domain.CatalogService  true  "Сервис"
+// @Success      200  {string}  string  "saved"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/catalog [put]
+func (h *Handler) SetCatalogService(w http.ResponseWriter, r *http.Request) {
+	var svc domain.CatalogService
+	if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validateCatalogService(&svc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.SetCatalogService(ctx, &svc); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteCatalogService godoc
+// @Summary      Удалить сервис из справочника
+// @Tags         catalog
+// @Produce      json
+// @Param        service_name  query     string  true  "Название сервиса"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/catalog [delete]
+func (h *Handler) DeleteCatalogService(w http.ResponseWriter, r *http.Request) {
+	serviceName := r.URL.Query().Get("service_name")
+	if serviceName == "" {
+		http.Error(w, "service_name is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteCatalogService(ctx, serviceName); err != nil {
+		if err.Error() == "catalog service not found" {
+			http.Error(w, "catalog service not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateCatalogService(svc *domain.CatalogService) error {
+	if svc.ServiceName == "" || len(svc.ServiceName) > 255 {
+		return fmt.Errorf("service_name is required and must not exceed 255 characters")
+	}
+	if svc.Category == "" || len(svc.Category) > 64 {
+		return fmt.Errorf("category is required and must not exceed 64 characters")
+	}
+	if svc.ListPrice != nil && *svc.ListPrice <= 0 {
+		return fmt.Errorf("list_price must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ListCoupons godoc
+// @Summary      Получить список промокодов
+// @Tags         coupons
+// @Produce      json
+// @Success      200  {array}   domain.Coupon
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/coupons [get]
+func (h *Handler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	coupons, err := h.service.ListCoupons(ctx)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(coupons); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// CreateCoupon godoc
+// @Summary      Создать промокод
+// @Description  Скидка percent процентов (kind=percent) или фиксированной суммой amount (kind=fixed) на duration_months месяцев
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        coupon  body  domain.Coupon  true  "Промокод"
+// @Success      201  {string}  string  "created"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/coupons [post]
+func (h *Handler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var coupon domain.Coupon
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validateCoupon(&coupon); err != nil {
+		slog.Error("Invalid coupon", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.CreateCoupon(ctx, &coupon); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteCoupon godoc
+// @Summary      Удалить промокод
+// @Tags         coupons
+// @Produce      json
+// @Param        code  query     string  true  "Промокод"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/coupons [delete]
+func (h *Handler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteCoupon(ctx, code); err != nil {
+		if err.Error() == "coupon not found" {
+			http.Error(w, "coupon not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// AttachCoupon godoc
+// @Summary      Привязать промокод к подписке
+// @Description  Скидка действует с start_date (MM-YYYY), по умолчанию с начала подписки
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        input  body  domain.CouponAttachInput  true  "Подписка и промокод"
+// @Success      200  {string}  string  "updated"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/coupon [put]
+func (h *Handler) AttachCoupon(w http.ResponseWriter, r *http.Request) {
+	var input domain.CouponAttachInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if input.UserID == nil || len(*input.UserID) != 36 {
+		http.Error(w, "user_id is required correct format UUID", http.StatusBadRequest)
+		return
+	}
+	if input.ServiceName == nil || *input.ServiceName == "" {
+		http.Error(w, "service_name is required", http.StatusBadRequest)
+		return
+	}
+	if input.CouponCode == nil || *input.CouponCode == "" {
+		http.Error(w, "coupon_code is required", http.StatusBadRequest)
+		return
+	}
+	var start *time.Time
+	if input.StartDate != nil && *input.StartDate != "" {
+		t, err := time.Parse(dateForm, *input.StartDate)
+		if err != nil {
+			http.Error(w, "invalid start_date format, expected MM-YYYY", http.StatusBadRequest)
+			return
+		}
+		start = &t
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	err := h.service.AttachCoupon(ctx, *input.UserID, *input.ServiceName, input.CouponCode, start)
+	h.writeCouponResult(w, err)
+}
+
+// DetachCoupon godoc
+// @Summary      Отвязать промокод от подписки
+// @Tags         coupons
+// @Produce      json
+// @Param        user_id      query     string  true  "ID пользователя"
+// @Param        service_name query     string  true  "Название сервиса"
+// @Success      200  {string}  string  "updated"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/coupon [delete]
+func (h *Handler) DetachCoupon(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	serviceName := r.URL.Query().Get("service_name")
+	if len(userID) != 36 || serviceName == "" {
+		http.Error(w, "user_id and service_name are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	err := h.service.AttachCoupon(ctx, userID, serviceName, nil, nil)
+	h.writeCouponResult(w, err)
+}
+
+func (h *Handler) writeCouponResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		switch err.Error() {
+		case "subscription not found", "coupon not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateCoupon(coupon *domain.Coupon) error {
+	if coupon.Code == "" || len(coupon.Code) > 64 {
+		return fmt.Errorf("code is required and must not exceed 64 characters")
+	}
+	switch coupon.Kind {
+	case domain.CouponPercent:
+		if coupon.Percent <= 0 || coupon.Percent > 100 || coupon.Amount != 0 {
+			return fmt.Errorf("percent must be between 1 and 100, amount must be empty")
+		}
+	case domain.CouponFixed:
+		if coupon.Amount <= 0 || coupon.Percent != 0 {
+			return fmt.Errorf("amount must be positive, percent must be empty")
+		}
+	default:
+		return fmt.Errorf("kind must be percent or fixed")
+	}
+	if coupon.DurationMonths <= 0 {
+		return fmt.Errorf("duration_months must be positive")
+	}
+	return nil
+}
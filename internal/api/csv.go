@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// maxImportBytes наибольший размер файла импорта
+const maxImportBytes = 32 << 20
+
+// ExportSubscriptions godoc
+// @Summary      Выгрузка подписок
+// @Description  Подписки по тем же фильтрам, что и GET /api/subscriptions, в формате CSV. Выгрузка передается по мере чтения из БД. Колонки: user_id, service_name, price, start_date, end_date, tax_inclusive, tax_region, category
+// @Tags         subscriptions
+// @Produce      text/csv
+// @Param        format        query     string  false  "Формат выгрузки, поддерживается csv"
+// @Param        user_id       query     string  false  "ID пользователя"
+// @Param        service_name  query     string  false  "Название сервиса"
+// @Param        filter        query     string  false  "Выражение фильтра"
+// @Param        q             query     string  false  "Нечеткий поиск по названию сервиса"
+// @Param        sort          query     string  false  "Сортировка"
+// @Success      200  {string}  string  "csv"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/export [get]
+func (h *Handler) ExportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if format := r.URL.Query().Get("format"); format != "" && format != "csv" {
+		http.Error(w, "format must be csv", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseSearchFilter(r.URL.Query())
+	if err != nil {
+		slog.Error("Invalid filter", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutexport)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+	out := &exportWriter{ResponseWriter: w}
+	if err := h.service.ExportCSV(ctx, out, filter); err != nil {
+		// после начала передачи статус уже не изменить, клиент получит оборванный файл
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+}
+
+// exportWriter запоминает, начата ли передача ответа
+type exportWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+// ImportSubscriptions godoc
+// @Summary      Загрузка подписок из CSV
+// @Description  CSV с заголовком, колонки как в выгрузке: обязательны user_id, service_name, price, start_date. Подписка с теми же user_id и service_name обновляется, иначе создается. Строки с ошибками пропускаются и перечисляются в отчете. Файл передается телом запроса (text/csv) или полем file в multipart/form-data
+// @Tags         subscriptions
+// @Accept       text/csv
+// @Produce      json
+// @Param        dry_run  query     bool  false  "Только проверить, ничего не сохраняя"
+// @Success      200  {object}  domain.ImportReport
+// @Failure      400  {string}  string  "bad request"
+// @Failure      413  {string}  string  "file too large"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/import [post]
+func (h *Handler) ImportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+		dryRun = b
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			if isBodyTooLarge(err) {
+				http.Error(w, "file must not exceed 32MB", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutexport)
+	defer cancel()
+
+	report, err := h.service.ImportCSV(ctx, body, dryRun)
+	if err != nil {
+		switch {
+		case isBodyTooLarge(err):
+			http.Error(w, "file must not exceed 32MB", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, domain.ErrImportTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, domain.ErrInvalidCSV):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
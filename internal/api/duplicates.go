@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// FindDuplicates godoc
+// @Summary      Дубликаты и пересекающиеся подписки
+// @Description  Подписки пользователя, действующие одновременно: один сервис под разными названиями (same_service)
+// @Description  или разные сервисы одной категории справочника (same_category). Экономия в месяц считается,
+// @Description  если оставить в группе только самую дорогую подписку. По умолчанию проверяется текущий месяц.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id      query     string  false  "ID пользователя, суммы по его доле"
+// @Param        start_date   query     string  false  "Начало периода MM-YYYY"
+// @Param        end_date     query     string  false  "Конец периода MM-YYYY"
+// @Success      200  {object}  domain.DuplicateReport
+// @Failure      400  {string}  string  "bad request"
+// @Failure      422  {string}  string  "amount overflow"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/duplicates [get]
+func (h *Handler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	var filter domain.Filter
+
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+	if startStr := r.URL.Query().Get("start_date"); startStr != "" {
+		t, err := time.Parse(dateForm, startStr)
+		if err != nil {
+			http.Error(w, "invalid start_date format, expected MM-YYYY", http.StatusBadRequest)
+			return
+		}
+		filter.StartDate = &t
+	}
+	if endStr := r.URL.Query().Get("end_date"); endStr != "" {
+		t, err := time.Parse(dateForm, endStr)
+		if err != nil {
+			http.Error(w, "invalid end_date format, expected MM-YYYY", http.StatusBadRequest)
+			return
+		}
+		if filter.StartDate == nil {
+			http.Error(w, "start_date is required with end_date", http.StatusBadRequest)
+			return
+		}
+		if t.Before(*filter.StartDate) {
+			http.Error(w, "end_date must not be before start_date", http.StatusBadRequest)
+			return
+		}
+		filter.EndDate = &t
+	}
+	if err := validateFilter(&filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	report, err := h.service.FindDuplicates(ctx, &filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrMoneyOverflow) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
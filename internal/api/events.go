@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// SubscriptionEvents godoc
+// @Summary      Поток изменений подписок (Server-Sent Events)
+// @Description  События subscription.created, subscription.updated, subscription.deleted по подпискам пользователя.
+// @Description  Пользователь берется из JWT, поток доступен только авторизованному пользователю.
+// @Description  id события - номер публикации в журнале, при переподключении клиент передает Last-Event-ID и получает пропущенные события.
+// @Tags         subscriptions
+// @Produce      text/event-stream
+// @Param        Authorization  header    string  true   "Bearer JWT"
+// @Param        Last-Event-ID  header    int     false  "ID последнего полученного события"
+// @Success      200  {string}  string  "event stream"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      401  {string}  string  "unauthorized"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/events [get]
+func (h *Handler) SubscriptionEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if len(userID) != 36 {
+		http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+		return
+	}
+
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// поток живет, пока клиент подключен, таймаут запроса не применяется
+	events, err := h.service.StreamEvents(r.Context(), userID, lastEventID)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				// сервер останавливается или клиент не успевает читать, клиент переподключится
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, msg.EventType, msg.Payload); err != nil {
+				slog.Warn("Failed to write event", "user_id", userID, "error", err)
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
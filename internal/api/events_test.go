@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+type eventsService struct {
+	SubService
+	userIDs []string
+}
+
+func (s *eventsService) StreamEvents(ctx context.Context, userID string, lastEventID int64) (<-chan *domain.OutboxMessage, error) {
+	s.userIDs = append(s.userIDs, userID)
+	events := make(chan *domain.OutboxMessage, 1)
+	events <- &domain.OutboxMessage{Seq: lastEventID + 1, EventType: domain.EventCreated, Payload: []byte(`{}`)}
+	close(events)
+	return events, nil
+}
+
+func TestSubscriptionEventsRequiresJWT(t *testing.T) {
+	const owner = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	const other = "7f1c2a4e-8d3b-4c5a-9e6f-1a2b3c4d5e6f"
+	ownerJWT, err := GenerateJWT(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		auth string
+		want int
+	}{
+		{"without JWT", "/api/subscriptions/events", "", http.StatusUnauthorized},
+		{"user_id without JWT", "/api/subscriptions/events?user_id=" + other, "", http.StatusUnauthorized},
+		{"invalid JWT", "/api/subscriptions/events", "Bearer bad", http.StatusUnauthorized},
+		{"own stream", "/api/subscriptions/events?user_id=" + other, "Bearer " + ownerJWT, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &eventsService{}
+			r := chi.NewRouter()
+			NewHandler(svc).InitRoutes(r)
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("Last-Event-ID", "4")
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				if len(svc.userIDs) != 0 {
+					t.Errorf("stream opened for %v without authorization", svc.userIDs)
+				}
+				return
+			}
+			// поток открыт для пользователя из JWT, параметр user_id не учитывается
+			if len(svc.userIDs) != 1 || svc.userIDs[0] != owner {
+				t.Errorf("stream opened for %v, want [%s]", svc.userIDs, owner)
+			}
+			if !strings.Contains(w.Body.String(), "id: 5\nevent: subscription.created\n") {
+				t.Errorf("unexpected body %q", w.Body.String())
+			}
+		})
+	}
+}
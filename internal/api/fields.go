@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"strings"
+)
+
+// subscriptionFields поля подписки в JSON, допустимые в параметре fields
+var subscriptionFields = jsonFieldNames(reflect.TypeOf(domain.Subscription{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFieldList разбирает список через запятую и проверяет каждое значение по allowed
+func parseFieldList(param, s string, allowed map[string]bool) ([]string, error) {
+	var list []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if !allowed[name] {
+			return nil, fmt.Errorf("unknown %s value %q", param, name)
+		}
+		seen[name] = true
+		list = append(list, name)
+	}
+	return list, nil
+}
+
+// selectFields оставляет в JSON подписок только поля fields. Поля без значения
+// (omitempty) в ответ не попадают, как и без fields.
+func selectFields(subs []*domain.Subscription, fields []string) ([]map[string]json.RawMessage, error) {
+	res := make([]map[string]json.RawMessage, 0, len(subs))
+	for _, sub := range subs {
+		data, err := json.Marshal(sub)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		item := make(map[string]json.RawMessage, len(fields))
+		for _, name := range fields {
+			if v, ok := all[name]; ok {
+				item[name] = v
+			}
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseFieldList(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr string
+	}{
+		{name: "fields", s: "id,service_name,price", want: []string{"id", "service_name", "price"}},
+		{name: "spaces, empty and duplicates", s: " id, ,price,id", want: []string{"id", "price"}},
+		{name: "nested json field", s: "next_charge_date,members", want: []string{"next_charge_date", "members"}},
+		{name: "unknown", s: "id,password", wantErr: `unknown fields value "password"`},
+		// поле без json тега недоступно
+		{name: "hidden field", s: "TaxInclusiveSet", wantErr: `unknown fields value "TaxInclusiveSet"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFieldList("fields", tt.s, subscriptionFields)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseFieldList(%q) error = %v, want %q", tt.s, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFieldList(%q) error = %v", tt.s, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFieldList(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseFieldList("expand", "price_changes,members", domain.ExpandFields); err == nil ||
+		err.Error() != `unknown expand value "members"` {
+		t.Errorf("parseFieldList(expand) error = %v", err)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	subs := []*domain.Subscription{
+		{ID: 1, UserID: "123e4567-e89b-12d3-a456-426614174000", ServiceName: "Netflix", Price: 99900},
+	}
+	got, err := selectFields(subs, []string{"id", "price", "end_date"})
+	if err != nil {
+		t.Fatalf("selectFields() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("selectFields() returned %d items", len(got))
+	}
+	keys := make([]string, 0, len(got[0]))
+	for k := range got[0] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// end_date пустая и omitempty, поэтому в ответ не попадает
+	if !reflect.DeepEqual(keys, []string{"id", "price"}) {
+		t.Errorf("selectFields() keys = %v, want [id price]", keys)
+	}
+	if string(got[0]["id"]) != "1" || string(got[0]["price"]) != `"999.00"` {
+		t.Errorf("selectFields() = id %s, price %s", got[0]["id"], got[0]["price"])
+	}
+}
+
+func TestSearchSubscriptions_UnknownField(t *testing.T) {
+	r := chi.NewRouter()
+	NewHandler(&pageService{}).InitRoutes(r)
+	for _, query := range []string{"fields=id,password", "expand=members"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/subscriptions?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Forecast godoc
+// @Summary      Прогноз расходов
+// @Description  Помесячный прогноз расходов на months месяцев начиная со следующего (по умолчанию 12) с учетом дат окончания, запланированных изменений цены и скидок
+// @Tags         subscriptions
+// @Produce      json
+// @Param        months       query     int     false  "Горизонт в месяцах, не более 60"
+// @Param        group_by     query     string  false  "Группировка: service (по умолчанию) или user"
+// @Param        user_id      query     string  false  "ID пользователя"
+// @Param        service_name query     string  false  "Название сервиса"
+// @Success      200  {array}   domain.ForecastMonth
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/forecast [get]
+func (h *Handler) Forecast(w http.ResponseWriter, r *http.Request) {
+	var filter domain.Filter
+
+	months := defaultForecastMonths
+	if monthsStr := r.URL.Query().Get("months"); monthsStr != "" {
+		m, err := strconv.Atoi(monthsStr)
+		if err != nil || m <= 0 || m > maxForecastMonths {
+			http.Error(w, "months must be between 1 and 60", http.StatusBadRequest)
+			return
+		}
+		months = m
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = domain.GroupByService
+	case domain.GroupByService, domain.GroupByUser:
+	default:
+		http.Error(w, "group_by must be service or user", http.StatusBadRequest)
+		return
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+	if serviceName := r.URL.Query().Get("service_name"); serviceName != "" {
+		filter.ServiceName = &serviceName
+	}
+	if err := validateFilter(&filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	forecast, err := h.service.Forecast(ctx, &filter, months, groupBy)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(forecast); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// ListPriceChanges godoc
+// @Summary      История и запланированные изменения цены подписки
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id      query     string  true  "ID пользователя"
+// @Param        service_name query     string  true  "Название сервиса"
+// @Success      200  {array}   domain.PriceChange
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/price-changes [get]
+func (h *Handler) ListPriceChanges(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	serviceName := r.URL.Query().Get("service_name")
+	if len(userID) != 36 || serviceName == "" {
+		http.Error(w, "user_id and service_name are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	changes, err := h.service.ListPriceChanges(ctx, userID, serviceName)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// SetPriceChange godoc
+// @Summary      Задать изменение цены подписки
+// @Description  С месяца effective_date (MM-YYYY) подписка стоит price. Изменение на тот же месяц перезаписывается. Когда месяц наступает, price становится ценой подписки
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        change  body  domain.PriceChangeInput  true  "Изменение цены"
+// @Success      200  {string}  string  "saved"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/price-changes [put]
+func (h *Handler) SetPriceChange(w http.ResponseWriter, r *http.Request) {
+	var input domain.PriceChangeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if input.UserID == nil || len(*input.UserID) != 36 || input.ServiceName == nil || *input.ServiceName == "" {
+		http.Error(w, "user_id and service_name are required", http.StatusBadRequest)
+		return
+	}
+	if input.Price == nil || *input.Price <= 0 {
+		http.Error(w, "price must be positive", http.StatusBadRequest)
+		return
+	}
+	if input.EffectiveDate == nil {
+		http.Error(w, "effective_date is required", http.StatusBadRequest)
+		return
+	}
+	effective, err := time.Parse(dateForm, *input.EffectiveDate)
+	if err != nil {
+		http.Error(w, "invalid effective_date format, expected MM-YYYY", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	err = h.service.SetPriceChange(ctx, *input.UserID, *input.ServiceName,
+		&domain.PriceChange{EffectiveDate: effective, Price: *input.Price})
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			http.Error(w, "subscription not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeletePriceChange godoc
+// @Summary      Удалить изменение цены подписки
+// @Description  Если изменение уже вступило в силу, цена подписки пересчитывается по оставшейся истории
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id        query     string  true  "ID пользователя"
+// @Param        service_name   query     string  true  "Название сервиса"
+// @Param        effective_date query     string  true  "Месяц изменения MM-YYYY"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/subscriptions/price-changes [delete]
+func (h *Handler) DeletePriceChange(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	serviceName := r.URL.Query().Get("service_name")
+	if len(userID) != 36 || serviceName == "" {
+		http.Error(w, "user_id and service_name are required", http.StatusBadRequest)
+		return
+	}
+	effective, err := time.Parse(dateForm, r.URL.Query().Get("effective_date"))
+	if err != nil {
+		http.Error(w, "invalid effective_date format, expected MM-YYYY", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	if err := h.service.DeletePriceChange(ctx, userID, serviceName, effective); err != nil {
+		if err.Error() == "price change not found" {
+			http.Error(w, "price change not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
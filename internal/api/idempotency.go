@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// maxIdempotentBodyBytes наибольший размер тела запроса с Idempotency-Key, тело читается целиком для хэша
+const maxIdempotentBodyBytes = 8 << 20
+
+// idempotent обрабатывает заголовок Idempotency-Key: первый запрос с ключом выполняется и его ответ
+// сохраняется, повтор с тем же методом, адресом и телом получает сохраненный ответ с заголовком
+// Idempotent-Replayed. Ключ с другим запросом - 422, пока первый запрос выполняется - 409.
+// После ответа 5xx ключ освобождается, чтобы запрос можно было повторить.
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > domain.MaxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key must not exceed "+strconv.Itoa(domain.MaxIdempotencyKeyLength)+" characters",
+				http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			if isBodyTooLarge(err) {
+				http.Error(w, "request body must not exceed 8MB", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "error reading body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// с JWT ключи разных пользователей не пересекаются
+		if userID, ok := UserIDFromContext(r.Context()); ok {
+			key = userID + "/" + key
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+		saved, err := h.service.BeginIdempotentRequest(ctx, key, idempotencyRequestHash(r, body))
+		cancel()
+		switch {
+		case errors.Is(err, domain.ErrIdempotencyKeyReused):
+			http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, domain.ErrIdempotencyInProgress):
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "request with this Idempotency-Key is in progress", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		case saved != nil:
+			if saved.ContentType != "" {
+				w.Header().Set("Content-Type", saved.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(saved.StatusCode)
+			if _, err := w.Write(saved.Body); err != nil {
+				slog.Error("Failed to write idempotent response", "error", err)
+			}
+			return
+		}
+
+		// изменения могли сохраниться, даже если клиент уже отключился: ответ записывается в любом случае
+		saveCtx, cancelSave := context.WithTimeout(context.WithoutCancel(r.Context()), tOutnormal)
+		defer cancelSave()
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		defer func() {
+			// при панике обработчика ключ освобождается, RecoverMiddleware вернет 500
+			if p := recover(); p != nil {
+				_ = h.service.AbortIdempotentRequest(saveCtx, key)
+				panic(p)
+			}
+		}()
+		next(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			_ = h.service.AbortIdempotentRequest(saveCtx, key)
+			return
+		}
+		resp := &domain.IdempotentResponse{
+			StatusCode:  rec.status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		}
+		if resp.StatusCode == 0 {
+			resp.StatusCode = http.StatusOK
+		}
+		if err := h.service.FinishIdempotentRequest(saveCtx, key, resp); err != nil {
+			slog.Error("Failed to save idempotent response", "error", err)
+		}
+	}
+}
+
+// idempotencyRequestHash хэш метода, пути, параметров в порядке ключей и тела запроса
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecorder передает ответ клиенту и запоминает статус и тело
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotencyRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// idempotencyService хранит ключи в памяти так же, как BeginIdempotentRequest сервиса
+type idempotencyService struct {
+	SubService
+	hashes    map[string]string
+	responses map[string]*domain.IdempotentResponse
+	created   map[string]bool
+	creates   int
+	createErr error
+}
+
+func newIdempotencyService() *idempotencyService {
+	return &idempotencyService{
+		hashes:    map[string]string{},
+		responses: map[string]*domain.IdempotentResponse{},
+		created:   map[string]bool{},
+	}
+}
+
+func (s *idempotencyService) BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotentResponse, error) {
+	hash, ok := s.hashes[key]
+	if !ok {
+		s.hashes[key] = requestHash
+		return nil, nil
+	}
+	if hash != requestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if s.responses[key] == nil {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+	return s.responses[key], nil
+}
+
+func (s *idempotencyService) FinishIdempotentRequest(ctx context.Context, key string, resp *domain.IdempotentResponse) error {
+	s.responses[key] = resp
+	return nil
+}
+
+func (s *idempotencyService) AbortIdempotentRequest(ctx context.Context, key string) error {
+	delete(s.hashes, key)
+	delete(s.responses, key)
+	return nil
+}
+
+func (s *idempotencyService) CreateSubscription(ctx context.Context, sub *domain.Subscription) error {
+	s.creates++
+	if s.createErr != nil {
+		return s.createErr
+	}
+	name := sub.UserID + "/" + sub.ServiceName
+	if s.created[name] {
+		return errors.New("subscription already exists")
+	}
+	s.created[name] = true
+	return nil
+}
+
+const idempotencyTestBody = `{"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","service_name":"Yandex Plus","price":"400","start_date":"07-2025"}`
+
+func postSubscription(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newIdempotencyRouter(svc SubService) http.Handler {
+	r := chi.NewRouter()
+	NewHandler(svc).InitRoutes(r)
+	return r
+}
+
+func TestIdempotent_Replay(t *testing.T) {
+	svc := newIdempotencyService()
+	r := newIdempotencyRouter(svc)
+
+	first := postSubscription(r, "key-1", idempotencyTestBody)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: status = %d, headers = %v", first.Code, first.Header())
+	}
+	retry := postSubscription(r, "key-1", idempotencyTestBody)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry status = %d, want %d", retry.Code, http.StatusCreated)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("retry Content-Type = %q, want %q", retry.Header().Get("Content-Type"), first.Header().Get("Content-Type"))
+	}
+	if svc.creates != 1 {
+		t.Errorf("subscription created %d times, want 1", svc.creates)
+	}
+}
+
+func TestIdempotent_DifferentBody(t *testing.T) {
+	svc := newIdempotencyService()
+	r := newIdempotencyRouter(svc)
+
+	postSubscription(r, "key-1", idempotencyTestBody)
+	other := strings.Replace(idempotencyTestBody, `"400"`, `"500"`, 1)
+	w := postSubscription(r, "key-1", other)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if svc.creates != 1 {
+		t.Errorf("subscription created %d times, want 1", svc.creates)
+	}
+}
+
+func TestIdempotent_InProgress(t *testing.T) {
+	svc := newIdempotencyService()
+	r := newIdempotencyRouter(svc)
+
+	// первый запрос занял ключ, но ответ еще не сохранен
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(idempotencyTestBody))
+	svc.hashes["key-1"] = idempotencyRequestHash(req, []byte(idempotencyTestBody))
+
+	w := postSubscription(r, "key-1", idempotencyTestBody)
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After is not set")
+	}
+	if svc.creates != 0 {
+		t.Errorf("subscription created %d times, want 0", svc.creates)
+	}
+}
+
+func TestIdempotent_ServerErrorReleasesKey(t *testing.T) {
+	svc := newIdempotencyService()
+	svc.createErr = errors.New("connection reset")
+	r := newIdempotencyRouter(svc)
+
+	if w := postSubscription(r, "key-1", idempotencyTestBody); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	svc.createErr = nil
+	if w := postSubscription(r, "key-1", idempotencyTestBody); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after 500: status = %d, replayed = %q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if svc.creates != 2 {
+		t.Errorf("create called %d times, want 2", svc.creates)
+	}
+}
+
+func TestIdempotent_KeyTooLong(t *testing.T) {
+	svc := newIdempotencyService()
+	r := newIdempotencyRouter(svc)
+
+	w := postSubscription(r, strings.Repeat("k", domain.MaxIdempotencyKeyLength+1), idempotencyTestBody)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCreateSubscription_Duplicate(t *testing.T) {
+	svc := newIdempotencyService()
+	r := newIdempotencyRouter(svc)
+
+	postSubscription(r, "", idempotencyTestBody)
+	// повтор без ключа не должен выглядеть как ошибка сервера
+	if w := postSubscription(r, "", idempotencyTestBody); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	// ответ 409 сохраняется и повторяется по тому же ключу
+	if w := postSubscription(r, "key-2", idempotencyTestBody); w.Code != http.StatusConflict {
+		t.Errorf("status with new key = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := postSubscription(r, "key-2", idempotencyTestBody); w.Code != http.StatusConflict || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replayed status = %d, replayed = %q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type jobRequest struct {
+	// Kind вид задачи: export - выгрузка в CSV, summary - отчет за период
+	Kind string `json:"kind" example:"summary"`
+	// Params параметры как у GET /api/subscriptions/export или GET /api/reports, включая format
+	Params map[string]string `json:"params" swaggertype:"object,string" example:"start_date:01-2025,end_date:12-2025,format:xlsx"`
+}
+
+// JobFilter разбирает параметры фоновой задачи так же, как параметры соответствующего запроса.
+// Передается сервису через service.WithJobFilterParser.
+func JobFilter(kind string, params map[string]string) (*domain.Filter, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	switch kind {
+	case domain.JobExport:
+		return parseSearchFilter(query)
+	case domain.JobSummary:
+		return parseReportFilter(query)
+	}
+	return nil, fmt.Errorf("unknown job kind %q", kind)
+}
+
+// CreateJob godoc
+// @Summary      Поставить задачу в очередь
+// @Description  Фоновая выгрузка (export) или отчет за период (summary) для объемов, которые не укладываются в таймаут запроса. Задачу выполняют воркеры сервера, статус - GET /api/jobs/{id}, результат - GET /api/jobs/{id}/result
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        job  body      jobRequest  true  "Вид и параметры задачи"
+// @Success      202  {object}  domain.Job
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/jobs [post]
+func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	job, err := h.service.EnqueueJob(ctx, req.Kind, req.Params)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidJob) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// GetJob godoc
+// @Summary      Статус задачи
+// @Description  Статус (queued, running, done, failed), число обработанных подписок и ссылка на результат выполненной задачи. Завершенные задачи хранятся JOB_RESULT_TTL
+// @Tags         jobs
+// @Produce      json
+// @Param        id   path      string  true  "ID задачи"
+// @Success      200  {object}  domain.Job
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/jobs/{id} [get]
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if len(id) != 36 {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+	job, err := h.service.GetJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrJobNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if job.Status == domain.JobDone {
+		job.ResultURL = "/api/jobs/" + job.ID + "/result"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// JobResult godoc
+// @Summary      Результат задачи
+// @Description  Файл выполненной задачи: CSV для export, JSON, XLSX или PDF для summary
+// @Tags         jobs
+// @Produce      text/csv
+// @Produce      json
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce      application/pdf
+// @Param        id   path      string  true  "ID задачи"
+// @Success      200  {string}  string  "file"
+// @Failure      404  {string}  string  "not found"
+// @Failure      409  {string}  string  "job is not done"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/jobs/{id}/result [get]
+func (h *Handler) JobResult(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if len(id) != 36 {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	res, err := h.service.JobResult(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrJobNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, domain.ErrJobNotReady):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", res.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+res.FileName+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(res.Data)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(res.Data); err != nil {
+		slog.Error("Failed to write job result", "id", id, "error", err)
+	}
+}
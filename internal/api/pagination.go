@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// searchPage постраничный поиск по курсору. Ссылка на следующую страницу
+// передается в next_cursor и в заголовке Link с rel="next". fields - поля подписок в ответе.
+func (h *Handler) searchPage(w http.ResponseWriter, r *http.Request, filter *domain.Filter, fields []string) {
+	query := r.URL.Query()
+	if filter.Offset != nil {
+		http.Error(w, "offset cannot be combined with cursor pagination", http.StatusBadRequest)
+		return
+	}
+
+	// limit тоже задает размер страницы, page_size важнее
+	pageSize := defaultPageSize
+	if filter.Limit != nil {
+		pageSize = *filter.Limit
+	}
+	if v := query.Get("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 || size > maxPageSize {
+			http.Error(w, "page_size must be between 1 and "+strconv.Itoa(maxPageSize), http.StatusBadRequest)
+			return
+		}
+		pageSize = size
+	}
+	if pageSize > maxPageSize {
+		http.Error(w, "page_size must be between 1 and "+strconv.Itoa(maxPageSize), http.StatusBadRequest)
+		return
+	}
+	filter.Limit = &pageSize
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		// курсор проверяется до запроса в БД, чтобы сразу ответить 400
+		if _, err := domain.DecodeCursor(cursor, domain.PageSort(filter.Sort)); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		filter.Cursor = &cursor
+	}
+	if v := query.Get("total"); v != "" {
+		withTotal, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "total must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.WithTotal = withTotal
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	page, err := h.service.SearchPage(ctx, filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if page.NextCursor != nil {
+		next := r.URL.Query()
+		next.Set("cursor", *page.NextCursor)
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+next.Encode()+`>; rel="next"`)
+	}
+	var body interface{} = page
+	if fields != nil {
+		items, err := selectFields(page.Items, fields)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		body = struct {
+			Items      []map[string]json.RawMessage `json:"items"`
+			NextCursor *string                      `json:"next_cursor"`
+			Total      *int                         `json:"total,omitempty"`
+		}{Items: items, NextCursor: page.NextCursor, Total: page.Total}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// pageService постраничный поиск в памяти: subs уже упорядочены по -price,id, страница
+// начинается после подписки, для которой выдан курсор
+type pageService struct {
+	SubService
+	subs    []*domain.Subscription
+	filters []*domain.Filter
+}
+
+func (s *pageService) SearchPage(ctx context.Context, filter *domain.Filter) (*domain.Page, error) {
+	s.filters = append(s.filters, filter)
+	sort := domain.PageSort(filter.Sort)
+	subs := make([]*domain.Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		if filter.UserID == nil || sub.UserID == *filter.UserID {
+			subs = append(subs, sub)
+		}
+	}
+	if filter.Cursor != nil {
+		for i, sub := range subs {
+			if domain.EncodeCursor(sort, sub) == *filter.Cursor {
+				subs = subs[i+1:]
+				break
+			}
+		}
+	}
+	page := &domain.Page{Items: subs}
+	if len(subs) > *filter.Limit {
+		page.Items = subs[:*filter.Limit]
+		next := domain.EncodeCursor(sort, page.Items[len(page.Items)-1])
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+func TestSearchSubscriptionsCursor(t *testing.T) {
+	const userID = "123e4567-e89b-12d3-a456-426614174000"
+	svc := &pageService{subs: []*domain.Subscription{
+		{ID: 1, UserID: userID, ServiceName: "Netflix", Price: 99900},
+		{ID: 4, UserID: userID, ServiceName: "Yandex Plus", Price: 39900},
+		{ID: 2, UserID: userID, ServiceName: "Okko", Price: 29900},
+		{ID: 3, UserID: userID, ServiceName: "Spotify", Price: 29900},
+		{ID: 5, UserID: "223e4567-e89b-12d3-a456-426614174000", ServiceName: "Kion", Price: 19900},
+	}}
+	r := chi.NewRouter()
+	NewHandler(svc).InitRoutes(r)
+	linkRe := regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+	// одинаковые цены 2 и 3 разделяются по id, ни одна строка не теряется и не повторяется
+	target := "/api/subscriptions?user_id=" + userID + "&sort=-price&page_size=2"
+	var ids []int
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("too many pages")
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, body %s", target, w.Code, w.Body.String())
+		}
+		var page domain.Page
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatalf("decode page: %v", err)
+		}
+		for _, sub := range page.Items {
+			ids = append(ids, sub.ID)
+		}
+
+		link := w.Header().Get("Link")
+		if page.NextCursor == nil {
+			if link != "" {
+				t.Errorf("Link %q on the last page", link)
+			}
+			target = ""
+			continue
+		}
+		m := linkRe.FindStringSubmatch(link)
+		if m == nil {
+			t.Fatalf("Link = %q, want rel=next", link)
+		}
+		next, err := url.Parse(m[1])
+		if err != nil {
+			t.Fatalf("parse Link: %v", err)
+		}
+		// ссылка сохраняет параметры запроса и передает тот же курсор, что и next_cursor
+		query := next.Query()
+		if next.Path != "/api/subscriptions" || query.Get("cursor") != *page.NextCursor ||
+			query.Get("sort") != "-price" || query.Get("page_size") != "2" || query.Get("user_id") != userID {
+			t.Fatalf("Link = %q, next_cursor %q", link, *page.NextCursor)
+		}
+		target = m[1]
+	}
+	if !reflect.DeepEqual(ids, []int{1, 4, 2, 3}) {
+		t.Errorf("pages ids = %v, want [1 4 2 3]", ids)
+	}
+	// курсор из ответа принят сервисом без изменений
+	if len(svc.filters) != 2 || svc.filters[1].Cursor == nil {
+		t.Fatalf("SearchPage calls %d, want 2 with cursor", len(svc.filters))
+	}
+
+	// курсор другой сортировки отклоняется до обращения к сервису
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/subscriptions?sort=price&cursor="+url.QueryEscape(*svc.filters[1].Cursor), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || len(svc.filters) != 2 {
+		t.Errorf("foreign cursor status = %d, SearchPage calls %d", w.Code, len(svc.filters))
+	}
+}
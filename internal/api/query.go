@@ -0,0 +1,295 @@
+package api
+
+import (
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"strings"
+)
+
+const (
+	maxQueryLength      = 1000
+	maxQueryComparisons = 32
+	maxQueryDepth       = 16
+	maxSortFields       = 5
+)
+
+// Выражение фильтра подписок:
+//
+//	expr       = or
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//
+// op: = != < <= > >= ~ (подстрока без учета регистра), value: слово без пробелов
+// (299.99, 2025-01, null) или строка в двойных кавычках с экранированием \" и \\.
+// Пример: price>=300 and start_date<2025-01 and service_name~"yandex"
+
+const (
+	tokenIdent = iota
+	tokenOp
+	tokenValue
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+type queryToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+type queryParser struct {
+	input       string
+	pos         int
+	tok         queryToken
+	depth       int
+	comparisons int
+}
+
+// ParseQuery разбирает выражение фильтра, поля и операторы проверяются по белому списку domain.QueryFields
+func ParseQuery(input string) (domain.Expr, error) {
+	if len(input) > maxQueryLength {
+		return nil, fmt.Errorf("filter must not exceed %d characters", maxQueryLength)
+	}
+	p := &queryParser{input: input}
+	if err := p.next(false); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return expr, nil
+}
+
+func (p *queryParser) parseOr() (domain.Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		if err := p.next(false); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &domain.OrExpr{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (domain.Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		if err := p.next(false); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &domain.AndExpr{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (domain.Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxQueryDepth {
+		return nil, p.errorf("filter is nested too deeply")
+	}
+
+	switch {
+	case p.isKeyword("not"):
+		if err := p.next(false); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &domain.NotExpr{Expr: expr}, nil
+	case p.tok.kind == tokenLParen:
+		if err := p.next(false); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("expected )")
+		}
+		if err := p.next(false); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (domain.Expr, error) {
+	if p.tok.kind != tokenIdent {
+		return nil, p.errorf("expected field name")
+	}
+	field := p.tok
+	if err := p.next(false); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenOp {
+		return nil, p.errorf("expected operator after %q", field.text)
+	}
+	op := p.tok.text
+	// после оператора читается значение, а не имя поля
+	if err := p.next(true); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenValue && p.tok.kind != tokenString {
+		return nil, p.errorf("expected value after %s", op)
+	}
+	value := p.tok
+
+	p.comparisons++
+	if p.comparisons > maxQueryComparisons {
+		return nil, fmt.Errorf("filter must not contain more than %d conditions", maxQueryComparisons)
+	}
+	cmp, err := domain.NewComparison(field.text, op, value.text, value.kind == tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter at position %d: %v", field.pos+1, err)
+	}
+	if err := p.next(false); err != nil {
+		return nil, err
+	}
+	return cmp, nil
+}
+
+func (p *queryParser) isKeyword(word string) bool {
+	return p.tok.kind == tokenIdent && strings.EqualFold(p.tok.text, word)
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter at position %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next читает следующий токен, wantValue - ожидается значение после оператора
+func (p *queryParser) next(wantValue bool) error {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = queryToken{kind: tokenEOF, pos: start}
+		return nil
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		p.tok = queryToken{kind: tokenLParen, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = queryToken{kind: tokenRParen, text: ")", pos: start}
+	case c == '"':
+		text, err := p.readString()
+		if err != nil {
+			return err
+		}
+		p.tok = queryToken{kind: tokenString, text: text, pos: start}
+	case strings.IndexByte("=!<>~", c) >= 0:
+		for p.pos < len(p.input) && strings.IndexByte("=!<>~", p.input[p.pos]) >= 0 {
+			p.pos++
+		}
+		op := p.input[start:p.pos]
+		switch op {
+		case domain.OpEq, domain.OpNe, domain.OpLt, domain.OpLe, domain.OpGt, domain.OpGe, domain.OpContains:
+		default:
+			return fmt.Errorf("invalid filter at position %d: unknown operator %q", start+1, op)
+		}
+		p.tok = queryToken{kind: tokenOp, text: op, pos: start}
+	case wantValue:
+		for p.pos < len(p.input) && !isQueryDelimiter(p.input[p.pos]) {
+			p.pos++
+		}
+		p.tok = queryToken{kind: tokenValue, text: p.input[start:p.pos], pos: start}
+	case isQueryIdentChar(c):
+		for p.pos < len(p.input) && isQueryIdentChar(p.input[p.pos]) {
+			p.pos++
+		}
+		p.tok = queryToken{kind: tokenIdent, text: p.input[start:p.pos], pos: start}
+	default:
+		return fmt.Errorf("invalid filter at position %d: unexpected character %q", start+1, c)
+	}
+	return nil
+}
+
+func (p *queryParser) readString() (string, error) {
+	start := p.pos
+	p.pos++ // открывающая кавычка
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			if p.pos+1 >= len(p.input) {
+				return "", fmt.Errorf("invalid filter at position %d: unterminated string", start+1)
+			}
+			p.pos++
+			c = p.input[p.pos]
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", fmt.Errorf("invalid filter at position %d: unterminated string", start+1)
+}
+
+func isQueryIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isQueryDelimiter(c byte) bool {
+	return c == ' ' || c == '\t' || c == '(' || c == ')' || c == '"'
+}
+
+// parseSort разбирает параметр sort: поля через запятую, "-" перед полем - по убыванию
+func parseSort(s string) ([]domain.SortField, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > maxSortFields {
+		return nil, fmt.Errorf("sort must not contain more than %d fields", maxSortFields)
+	}
+	sort := make([]domain.SortField, 0, len(parts))
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		var f domain.SortField
+		if strings.HasPrefix(part, "-") {
+			f.Desc = true
+			part = part[1:]
+		}
+		f.Field = part
+		if !domain.SortFields[f.Field] {
+			return nil, fmt.Errorf("unknown sort field %q", f.Field)
+		}
+		if seen[f.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", f.Field)
+		}
+		seen[f.Field] = true
+		sort = append(sort, f)
+	}
+	return sort, nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    string
+		want    []domain.SortField
+		wantErr string
+	}{
+		{name: "single field", sort: "price", want: []domain.SortField{{Field: "price"}}},
+		{name: "desc and spaces", sort: "-start_date, price", want: []domain.SortField{
+			{Field: "start_date", Desc: true}, {Field: "price"}}},
+		{name: "id", sort: "-id", want: []domain.SortField{{Field: "id", Desc: true}}},
+		// поля вне белого списка не попадают в ORDER BY
+		{name: "unknown field", sort: "password", wantErr: `unknown sort field "password"`},
+		{name: "sql injection", sort: "price; DROP TABLE subscriptions", wantErr: `unknown sort field "price; DROP TABLE subscriptions"`},
+		{name: "empty field", sort: "price,", wantErr: `unknown sort field ""`},
+		{name: "duplicate", sort: "price,-price", wantErr: `duplicate sort field "price"`},
+		{name: "too many fields", sort: "id,user_id,service_name,category,price,start_date",
+			wantErr: "sort must not contain more than 5 fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSort(tt.sort)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseSort(%q) error = %v, want %q", tt.sort, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSort(%q) error = %v", tt.sort, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort(%q) = %+v, want %+v", tt.sort, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchFilter_Ranges(t *testing.T) {
+	month := func(m time.Month, y int) *time.Time {
+		t := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	money := func(m domain.Money) *domain.Money { return &m }
+
+	tests := []struct {
+		name    string
+		query   string
+		check   func(f *domain.Filter) bool
+		wantErr string
+	}{
+		{name: "price range", query: "price_min=99.90&price_max=500",
+			check: func(f *domain.Filter) bool {
+				return reflect.DeepEqual(f.PriceMin, money(9990)) && reflect.DeepEqual(f.PriceMax, money(50000))
+			}},
+		{name: "active period", query: "active_from=01-2025&active_to=06-2025",
+			check: func(f *domain.Filter) bool {
+				return reflect.DeepEqual(f.ActiveFrom, month(time.January, 2025)) && reflect.DeepEqual(f.ActiveTo, month(time.June, 2025))
+			}},
+		{name: "started bounds", query: "started_after=12-2024&started_before=03-2025",
+			check: func(f *domain.Filter) bool {
+				return reflect.DeepEqual(f.StartedAfter, month(time.December, 2024)) &&
+					reflect.DeepEqual(f.StartedBefore, month(time.March, 2025))
+			}},
+		{name: "invalid price", query: "price_min=abc", wantErr: "invalid price_min, expected decimal string"},
+		{name: "negative price", query: "price_min=-1", wantErr: "price_min must be non-negative"},
+		{name: "min above max", query: "price_min=500&price_max=100", wantErr: "price_min must not exceed price_max"},
+		{name: "invalid month", query: "started_after=2025-01", wantErr: "invalid started_after format, expected MM-YYYY"},
+		{name: "reversed period", query: "active_from=06-2025&active_to=01-2025", wantErr: "active_from must not be after active_to"},
+		{name: "unknown sort", query: "sort=-secret", wantErr: `unknown sort field "secret"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := parseSearchFilter(query)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseSearchFilter(%q) error = %v, want %q", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSearchFilter(%q) error = %v", tt.query, err)
+			}
+			if !tt.check(filter) {
+				t.Errorf("parseSearchFilter(%q) = %+v", tt.query, filter)
+			}
+		})
+	}
+}
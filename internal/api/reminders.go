@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+)
+
+const maxReminderDays = 60
+
+// GetNotificationPreferences godoc
+// @Summary      Настройки напоминаний пользователя
+// @Tags         reminders
+// @Produce      json
+// @Param        user_id  query     string  true  "ID пользователя"
+// @Success      200  {object}  domain.NotificationPreferences
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/notification-preferences [get]
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if len(userID) != 36 {
+		http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	prefs, err := h.service.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		if err.Error() == "notification preferences not found" {
+			http.Error(w, "notification preferences not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// SetNotificationPreferences godoc
+// @Summary      Задать настройки напоминаний
+// @Description  Канал доставки: log, email или webhook. renewal - напоминать о продлении, ending - о последнем списании.
+// @Description  days_before - за сколько дней напоминать (1-60), по умолчанию REMINDER_WINDOW.
+// @Description  Пользователи без настроек получают напоминания в лог.
+// @Tags         reminders
+// @Accept       json
+// @Produce      json
+// @Param        preferences  body  domain.NotificationPreferences  true  "Настройки"
+// @Success      200  {string}  string  "saved"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/notification-preferences [put]
+func (h *Handler) SetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs := domain.NotificationPreferences{Channel: domain.ChannelLog, Renewal: true, Ending: true}
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validatePreferences(&prefs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.SetNotificationPreferences(ctx, &prefs); err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteNotificationPreferences godoc
+// @Summary      Удалить настройки напоминаний
+// @Tags         reminders
+// @Produce      json
+// @Param        user_id  query     string  true  "ID пользователя"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/notification-preferences [delete]
+func (h *Handler) DeleteNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if len(userID) != 36 {
+		http.Error(w, "user_id must be correct format UUID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteNotificationPreferences(ctx, userID); err != nil {
+		if err.Error() == "notification preferences not found" {
+			http.Error(w, "notification preferences not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func validatePreferences(prefs *domain.NotificationPreferences) error {
+	if len(prefs.UserID) != 36 {
+		return fmt.Errorf("user_id must be correct format UUID")
+	}
+	switch prefs.Channel {
+	case domain.ChannelLog:
+	case domain.ChannelEmail:
+		if prefs.Email == nil {
+			return fmt.Errorf("email is required for email channel")
+		}
+	case domain.ChannelWebhook:
+		if prefs.WebhookURL == nil {
+			return fmt.Errorf("webhook_url is required for webhook channel")
+		}
+	default:
+		return fmt.Errorf("channel must be log, email or webhook")
+	}
+	if prefs.Email != nil {
+		if _, err := mail.ParseAddress(*prefs.Email); err != nil || len(*prefs.Email) > 255 {
+			return fmt.Errorf("invalid email")
+		}
+	}
+	if prefs.WebhookURL != nil {
+		u, err := url.Parse(*prefs.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be absolute http(s) URL")
+		}
+	}
+	if prefs.DaysBefore != nil && (*prefs.DaysBefore <= 0 || *prefs.DaysBefore > maxReminderDays) {
+		return fmt.Errorf("days_before must be between 1 and %d", maxReminderDays)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report godoc
+// @Summary      Отчет по подпискам за период
+// @Description  Сводка как в POST /api/subscriptions/summary и вклад каждой подписки. Формат выбирается параметром format или заголовком Accept: application/json, application/vnd.openxmlformats-officedocument.spreadsheetml.sheet (XLSX), application/pdf
+// @Tags         reports
+// @Produce      json
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce      application/pdf
+// @Param        start_date    query     string  true   "Начало периода (MM-YYYY)"
+// @Param        end_date      query     string  true   "Конец периода (MM-YYYY)"
+// @Param        user_id       query     string  false  "ID пользователя"
+// @Param        service_name  query     string  false  "Название сервиса"
+// @Param        category      query     string  false  "Категория"
+// @Param        format        query     string  false  "Формат: json, xlsx, pdf"
+// @Success      200  {object}  domain.Report
+// @Failure      400  {string}  string  "bad request"
+// @Failure      406  {string}  string  "not acceptable"
+// @Failure      422  {string}  string  "amount overflow"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/reports [get]
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" {
+		if _, ok := domain.ReportContentTypes[format]; !ok {
+			http.Error(w, "format must be json, xlsx or pdf", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var ok bool
+		if format, ok = negotiateReportFormat(r.Header.Get("Accept")); !ok {
+			http.Error(w, "supported formats: application/json, application/pdf, "+
+				domain.ReportContentTypes[domain.ReportXLSX], http.StatusNotAcceptable)
+			return
+		}
+	}
+
+	filter, err := parseReportFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutlong)
+	defer cancel()
+	report, err := h.service.BuildReport(ctx, filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrMoneyOverflow) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// отчет собирается целиком, чтобы при ошибке вернуть 500, а не оборванный файл
+	var buf bytes.Buffer
+	if err = h.service.RenderReport(&buf, report, format); err != nil {
+		slog.Error("Failed to render report", "format", format, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", domain.ReportContentTypes[format])
+	w.Header().Set("Vary", "Accept")
+	if format != domain.ReportJSON {
+		name := "subscriptions-" + filter.StartDate.Format(dateForm) + "-" + filter.EndDate.Format(dateForm) + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	if _, err = buf.WriteTo(w); err != nil {
+		slog.Error("Failed to write report", "error", err)
+	}
+}
+
+// parseReportFilter период и фильтры отчета из параметров запроса, общий для отчета и задач сводки
+func parseReportFilter(query url.Values) (*domain.Filter, error) {
+	var filter domain.Filter
+	for name, dst := range map[string]**time.Time{"start_date": &filter.StartDate, "end_date": &filter.EndDate} {
+		v := query.Get(name)
+		if v == "" {
+			return nil, fmt.Errorf("start_date and end_date are required")
+		}
+		t, err := time.Parse(dateForm, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s format, expected MM-YYYY", name)
+		}
+		*dst = &t
+	}
+	if filter.StartDate.After(*filter.EndDate) {
+		return nil, fmt.Errorf("start_date must not be after end_date")
+	}
+	if userID := query.Get("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+	if serviceName := query.Get("service_name"); serviceName != "" {
+		filter.ServiceName = &serviceName
+	}
+	if category := query.Get("category"); category != "" {
+		filter.Category = &category
+	}
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// negotiateReportFormat выбирает формат отчета по заголовку Accept с учетом q.
+// Без заголовка и для */* отчет отдается в JSON.
+func negotiateReportFormat(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return domain.ReportJSON, true
+	}
+	best, bestQ, bestExact := "", 0.0, false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		format := ""
+		switch mediaType {
+		case "*/*", "application/*", domain.ReportContentTypes[domain.ReportJSON]:
+			format = domain.ReportJSON
+		case domain.ReportContentTypes[domain.ReportXLSX]:
+			format = domain.ReportXLSX
+		case domain.ReportContentTypes[domain.ReportPDF]:
+			format = domain.ReportPDF
+		}
+		// при равном q точный тип важнее шаблона, среди равных выигрывает указанный раньше
+		exact := !strings.Contains(mediaType, "*")
+		if format != "" && q > 0 && (q > bestQ || q == bestQ && exact && !bestExact) {
+			best, bestQ, bestExact = format, q, exact
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"net/http"
+)
+
+// ListTaxRates godoc
+// @Summary      Получить ставки налога по регионам
+// @Tags         tax
+// @Produce      json
+// @Success      200  {array}   domain.TaxRate
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/tax-rates [get]
+func (h *Handler) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	rates, err := h.service.ListTaxRates(ctx)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rates); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// SetTaxRate godoc
+// @Summary      Задать ставку налога региона
+// @Description  Ставка в базисных пунктах: 2000 = 20%. Существующая ставка региона перезаписывается
+// @Tags         tax
+// @Accept       json
+// @Produce      json
+// @Param        rate  body  domain.TaxRate  true  "Ставка налога"
+// @Success      200  {string}  string  "saved"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/tax-rates [put]
+func (h *Handler) SetTaxRate(w http.ResponseWriter, r *http.Request) {
+	var rate domain.TaxRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validateTaxRate(&rate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.SetTaxRate(ctx, &rate); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteTaxRate godoc
+// @Summary      Удалить ставку налога региона
+// @Tags         tax
+// @Produce      json
+// @Param        region  query     string  true  "Регион"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/tax-rates [delete]
+func (h *Handler) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		http.Error(w, "region is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteTaxRate(ctx, region); err != nil {
+		if err.Error() == "tax rate not found" {
+			http.Error(w, "tax rate not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateTaxRate(rate *domain.TaxRate) error {
+	if rate.Region == "" || len(rate.Region) > 8 {
+		return fmt.Errorf("region must be 1 to 8 characters")
+	}
+	if rate.RateBP < 0 || rate.RateBP > 10000 {
+		return fmt.Errorf("rate_bp must be between 0 and 10000")
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+var webhookEvents = map[string]bool{
+	domain.EventCreated:      true,
+	domain.EventUpdated:      true,
+	domain.EventDeleted:      true,
+	domain.EventExpiring:     true,
+	domain.EventBudgetAlert:  true,
+	domain.EventPriceAnomaly: true,
+}
+
+// ListWebhooks godoc
+// @Summary      Получить список получателей вебхуков
+// @Description  Секреты в списке не возвращаются
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}   domain.WebhookEndpoint
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/webhooks [get]
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	endpoints, err := h.service.ListWebhookEndpoints(ctx)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(endpoints); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// CreateWebhook godoc
+// @Summary      Зарегистрировать получателя вебхуков
+// @Description  События: subscription.created, subscription.updated, subscription.deleted, subscription.expiring, budget.alert.
+// @Description  Пустой список events - все события. Если secret не передан, он генерируется и возвращается только в этом ответе.
+// @Description  Запрос подписывается заголовком X-Webhook-Signature: t=<unix>,v1=<hex HMAC-SHA256 от "<unix>.<тело>">
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        endpoint  body  domain.WebhookEndpoint  true  "Получатель"
+// @Success      201  {object}  domain.WebhookEndpoint
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/webhooks [post]
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint := domain.WebhookEndpoint{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&endpoint); err != nil {
+		slog.Error("Failed to decode json", "error", err)
+		http.Error(w, "error decode", http.StatusBadRequest)
+		return
+	}
+	if err := validateWebhook(&endpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.CreateWebhookEndpoint(ctx, &endpoint); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(endpoint); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// DeleteWebhook godoc
+// @Summary      Удалить получателя вебхуков
+// @Description  Журнал доставок получателя удаляется вместе с ним
+// @Tags         webhooks
+// @Produce      json
+// @Param        id  query     int  true  "ID получателя"
+// @Success      200  {string}  string  "deleted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/webhooks [delete]
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.DeleteWebhookEndpoint(ctx, id); err != nil {
+		if err.Error() == "webhook endpoint not found" {
+			http.Error(w, "webhook endpoint not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      Журнал доставок вебхуков
+// @Description  Доставки с числом попыток, последним кодом ответа и ошибкой, новые первыми
+// @Tags         webhooks
+// @Produce      json
+// @Param        endpoint_id  query     int     false  "ID получателя"
+// @Param        status       query     string  false  "Статус: pending, delivered, failed"
+// @Param        limit        query     int     false  "Лимит"
+// @Success      200  {array}   domain.WebhookDelivery
+// @Failure      400  {string}  string  "bad request"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/webhooks/deliveries [get]
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	var filter domain.WebhookDeliveryFilter
+
+	if endpointIDStr := r.URL.Query().Get("endpoint_id"); endpointIDStr != "" {
+		endpointID, err := strconv.Atoi(endpointIDStr)
+		if err != nil {
+			http.Error(w, "invalid endpoint_id", http.StatusBadRequest)
+			return
+		}
+		filter.EndpointID = &endpointID
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		if status != domain.DeliveryPending && status != domain.DeliveryDelivered && status != domain.DeliveryFailed {
+			http.Error(w, "status must be pending, delivered or failed", http.StatusBadRequest)
+			return
+		}
+		filter.Status = &status
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = &limit
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	deliveries, err := h.service.ListWebhookDeliveries(ctx, &filter)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		slog.Error("Failed to encode to JSON", "error", err)
+	}
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary      Повторить доставку вебхука
+// @Description  Ставит доставку в очередь заново со сброшенным счетчиком попыток
+// @Tags         webhooks
+// @Produce      json
+// @Param        id  path      int  true  "ID доставки"
+// @Success      202  {string}  string  "accepted"
+// @Failure      400  {string}  string  "bad request"
+// @Failure      404  {string}  string  "not found"
+// @Failure      500  {string}  string  "internal error"
+// @Router       /api/webhooks/deliveries/{id}/replay [post]
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tOutnormal)
+	defer cancel()
+
+	if err := h.service.ReplayWebhookDelivery(ctx, id); err != nil {
+		if err.Error() == "webhook delivery not found" {
+			http.Error(w, "webhook delivery not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func validateWebhook(endpoint *domain.WebhookEndpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be absolute http(s) URL")
+	}
+	if endpoint.Secret != "" && (len(endpoint.Secret) < 16 || len(endpoint.Secret) > 128) {
+		return fmt.Errorf("secret must be 16 to 128 characters")
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+	for _, event := range endpoint.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
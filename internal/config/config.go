@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,6 +15,51 @@ type Config struct {
 	DBUser     string `mapstructure:"DB_USER"`
 	DBPassword string `mapstructure:"DB_PASSWORD"`
 	AppPort    string `mapstructure:"APP_PORT"`
+
+	BudgetCheckInterval time.Duration `mapstructure:"BUDGET_CHECK_INTERVAL"`
+	PriceChangeInterval time.Duration `mapstructure:"PRICE_CHANGE_INTERVAL"`
+
+	WebhookPollInterval time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WebhookMaxAttempts  int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryDelay   time.Duration `mapstructure:"WEBHOOK_RETRY_DELAY"`
+	ExpiringWindow      time.Duration `mapstructure:"EXPIRING_WINDOW"`
+
+	OutboxRelayInterval time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	OutboxSinks         string        `mapstructure:"OUTBOX_SINKS"`
+	ServeRelay          bool          `mapstructure:"SERVE_RELAY"`
+	EventPollInterval   time.Duration `mapstructure:"EVENT_POLL_INTERVAL"`
+
+	ReminderCheckInterval time.Duration `mapstructure:"REMINDER_CHECK_INTERVAL"`
+	ReminderWindow        time.Duration `mapstructure:"REMINDER_WINDOW"`
+	SMTPHost              string        `mapstructure:"SMTP_HOST"`
+	SMTPPort              string        `mapstructure:"SMTP_PORT"`
+	SMTPUsername          string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword          string        `mapstructure:"SMTP_PASSWORD"`
+	SMTPFrom              string        `mapstructure:"SMTP_FROM"`
+
+	AnomalyCheckInterval    time.Duration `mapstructure:"ANOMALY_CHECK_INTERVAL"`
+	AnomalyThresholdPercent int           `mapstructure:"ANOMALY_THRESHOLD_PERCENT"`
+	AnomalyJumpPercent      int           `mapstructure:"ANOMALY_JUMP_PERCENT"`
+
+	BatchMaxSize int `mapstructure:"BATCH_MAX_SIZE"`
+
+	JobWorkers      int           `mapstructure:"JOB_WORKERS"`
+	JobPollInterval time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	JobResultTTL    time.Duration `mapstructure:"JOB_RESULT_TTL"`
+
+	IdempotencyTTL             time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	IdempotencyCleanupInterval time.Duration `mapstructure:"IDEMPOTENCY_CLEANUP_INTERVAL"`
+}
+
+// Sinks список получателей событий из OUTBOX_SINKS через запятую
+func (c *Config) Sinks() []string {
+	var sinks []string
+	for _, name := range strings.Split(c.OutboxSinks, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sinks = append(sinks, name)
+		}
+	}
+	return sinks
 }
 
 func LoadCfg() (*Config, error) {
@@ -27,6 +74,34 @@ func LoadCfg() (*Config, error) {
 		}
 	}
 
+	// Значения по умолчанию для необязательных параметров
+	viper.SetDefault("BUDGET_CHECK_INTERVAL", time.Hour)
+	viper.SetDefault("PRICE_CHANGE_INTERVAL", time.Hour)
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", 5*time.Second)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_RETRY_DELAY", 30*time.Second)
+	viper.SetDefault("EXPIRING_WINDOW", 7*24*time.Hour)
+	viper.SetDefault("OUTBOX_RELAY_INTERVAL", time.Second)
+	viper.SetDefault("OUTBOX_SINKS", "webhook")
+	viper.SetDefault("SERVE_RELAY", true)
+	viper.SetDefault("EVENT_POLL_INTERVAL", time.Second)
+	viper.SetDefault("REMINDER_CHECK_INTERVAL", time.Hour)
+	viper.SetDefault("REMINDER_WINDOW", 3*24*time.Hour)
+	viper.SetDefault("SMTP_HOST", "")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("SMTP_USERNAME", "")
+	viper.SetDefault("SMTP_PASSWORD", "")
+	viper.SetDefault("SMTP_FROM", "")
+	viper.SetDefault("ANOMALY_CHECK_INTERVAL", 24*time.Hour)
+	viper.SetDefault("ANOMALY_THRESHOLD_PERCENT", 30)
+	viper.SetDefault("ANOMALY_JUMP_PERCENT", 20)
+	viper.SetDefault("BATCH_MAX_SIZE", 500)
+	viper.SetDefault("JOB_WORKERS", 2)
+	viper.SetDefault("JOB_POLL_INTERVAL", time.Second)
+	viper.SetDefault("JOB_RESULT_TTL", 24*time.Hour)
+	viper.SetDefault("IDEMPOTENCY_TTL", 24*time.Hour)
+	viper.SetDefault("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour)
+
 	viper.AutomaticEnv()
 
 	var cfg Config
@@ -55,6 +130,71 @@ func LoadCfg() (*Config, error) {
 	if port <= 0 || port > 65535 {
 		return nil, fmt.Errorf("incorrect port db: %d", port)
 	}
+	if cfg.BudgetCheckInterval <= 0 {
+		return nil, fmt.Errorf("incorrect budget check interval: %s", cfg.BudgetCheckInterval)
+	}
+	if cfg.PriceChangeInterval <= 0 {
+		return nil, fmt.Errorf("incorrect price change interval: %s", cfg.PriceChangeInterval)
+	}
+	if cfg.WebhookPollInterval <= 0 {
+		return nil, fmt.Errorf("incorrect webhook poll interval: %s", cfg.WebhookPollInterval)
+	}
+	if cfg.WebhookMaxAttempts <= 0 {
+		return nil, fmt.Errorf("incorrect webhook max attempts: %d", cfg.WebhookMaxAttempts)
+	}
+	if cfg.WebhookRetryDelay <= 0 {
+		return nil, fmt.Errorf("incorrect webhook retry delay: %s", cfg.WebhookRetryDelay)
+	}
+	if cfg.ExpiringWindow <= 0 {
+		return nil, fmt.Errorf("incorrect expiring window: %s", cfg.ExpiringWindow)
+	}
+	if cfg.OutboxRelayInterval <= 0 {
+		return nil, fmt.Errorf("incorrect outbox relay interval: %s", cfg.OutboxRelayInterval)
+	}
+	if cfg.EventPollInterval <= 0 {
+		return nil, fmt.Errorf("incorrect event poll interval: %s", cfg.EventPollInterval)
+	}
+	if cfg.ReminderCheckInterval <= 0 {
+		return nil, fmt.Errorf("incorrect reminder check interval: %s", cfg.ReminderCheckInterval)
+	}
+	if cfg.ReminderWindow <= 0 {
+		return nil, fmt.Errorf("incorrect reminder window: %s", cfg.ReminderWindow)
+	}
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("SMTP from address not specified")
+	}
+	if cfg.AnomalyCheckInterval <= 0 {
+		return nil, fmt.Errorf("incorrect anomaly check interval: %s", cfg.AnomalyCheckInterval)
+	}
+	if cfg.AnomalyThresholdPercent <= 0 {
+		return nil, fmt.Errorf("incorrect anomaly threshold percent: %d", cfg.AnomalyThresholdPercent)
+	}
+	if cfg.AnomalyJumpPercent <= 0 {
+		return nil, fmt.Errorf("incorrect anomaly jump percent: %d", cfg.AnomalyJumpPercent)
+	}
+	if cfg.BatchMaxSize <= 0 {
+		return nil, fmt.Errorf("incorrect batch max size: %d", cfg.BatchMaxSize)
+	}
+	if cfg.JobWorkers < 0 {
+		return nil, fmt.Errorf("incorrect job workers: %d", cfg.JobWorkers)
+	}
+	if cfg.JobPollInterval <= 0 {
+		return nil, fmt.Errorf("incorrect job poll interval: %s", cfg.JobPollInterval)
+	}
+	if cfg.JobResultTTL <= 0 {
+		return nil, fmt.Errorf("incorrect job result ttl: %s", cfg.JobResultTTL)
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		return nil, fmt.Errorf("incorrect idempotency ttl: %s", cfg.IdempotencyTTL)
+	}
+	if cfg.IdempotencyCleanupInterval <= 0 {
+		return nil, fmt.Errorf("incorrect idempotency cleanup interval: %s", cfg.IdempotencyCleanupInterval)
+	}
+	for _, sink := range cfg.Sinks() {
+		if sink != "webhook" && sink != "log" {
+			return nil, fmt.Errorf("unknown outbox sink: %s", sink)
+		}
+	}
 
 	return &cfg, nil
 }
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AnalyticsFilter период отчетов по месяцам включительно, даты - первые числа месяцев
+type AnalyticsFilter struct {
+	StartDate   time.Time
+	EndDate     time.Time
+	ServiceName *string
+	Category    *string
+}
+
+// ActiveMonth число подписок и пользователей, за которые было списание в месяце
+type ActiveMonth struct {
+	Month         time.Time `json:"month"`
+	Subscriptions int       `json:"subscriptions"`
+	Users         int       `json:"users"`
+}
+
+// ARPUMonth выручка по начислениям подписок (с учетом истории цен и скидок, без налогов),
+// число платящих пользователей с учетом участников совместных подписок и средняя выручка на пользователя
+type ARPUMonth struct {
+	Month   time.Time `json:"month"`
+	Revenue Money     `json:"revenue" swaggertype:"string"`
+	Users   int       `json:"users"`
+	ARPU    Money     `json:"arpu" swaggertype:"string"`
+}
+
+// ChurnMonth новые подписки сервиса (первое списание в месяце) и закончившиеся (последнее списание в месяце)
+type ChurnMonth struct {
+	Month       time.Time `json:"month"`
+	ServiceName string    `json:"service_name"`
+	New         int       `json:"new"`
+	Ended       int       `json:"ended"`
+}
+
+// Cohort подписки, начавшиеся в одном месяце. Retained[k] - сколько из них действовало
+// через k месяцев после начала, Retention[k] - та же доля от Size.
+type Cohort struct {
+	Cohort    time.Time `json:"cohort"`
+	Size      int       `json:"size"`
+	Retained  []int     `json:"retained"`
+	Retention []float64 `json:"retention"`
+}
+
+type AnalyticsRepository interface {
+	ActiveByMonth(ctx context.Context, filter *AnalyticsFilter) ([]*ActiveMonth, error)
+	ARPUByMonth(ctx context.Context, filter *AnalyticsFilter) ([]*ARPUMonth, error)
+	ChurnByMonth(ctx context.Context, filter *AnalyticsFilter) ([]*ChurnMonth, error)
+	// Cohorts заполняет Size и Retained, Retention считается в сервисе
+	Cohorts(ctx context.Context, filter *AnalyticsFilter) ([]*Cohort, error)
+}
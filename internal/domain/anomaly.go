@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const EventPriceAnomaly = "price.anomaly"
+
+const (
+	// AnomalyOutlier цена заметно отличается от рекомендованной или от цен других пользователей
+	AnomalyOutlier = "outlier"
+	// AnomalyJump резкое повышение цены относительно предыдущей цены подписки
+	AnomalyJump = "jump"
+)
+
+const (
+	ReferenceCatalog    = "catalog"
+	ReferencePeerMedian = "peer_median"
+	ReferencePrevious   = "previous_price"
+)
+
+const (
+	// minAnomalyPeers сколько подписок на сервис нужно, чтобы медиане можно было доверять
+	minAnomalyPeers = 3
+	// jumpLookbackMonths за сколько месяцев назад рассматриваются изменения цены
+	jumpLookbackMonths = 3
+)
+
+// PriceAnomaly подозрительная цена подписки. DeviationBP - отклонение от ReferencePrice
+// в базисных пунктах (1% = 100), отрицательное если цена ниже эталона.
+// EffectiveDate - месяц, с которого действует цена.
+type PriceAnomaly struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	UserID         string    `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	Kind           string    `json:"kind"`
+	Price          Money     `json:"price" swaggertype:"string"`
+	ReferencePrice Money     `json:"reference_price" swaggertype:"string"`
+	Reference      string    `json:"reference"`
+	DeviationBP    int       `json:"deviation_bp"`
+	EffectiveDate  time.Time `json:"effective_date"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PriceAnomalyFilter struct {
+	UserID      *string
+	ServiceName *string
+	Kind        *string
+	Limit       *int
+}
+
+type AnomalyRepository interface {
+	// SavePriceAnomaly сохраняет аномалию и событие price.anomaly,
+	// created=false если об этой цене уже сообщалось
+	SavePriceAnomaly(ctx context.Context, anomaly *PriceAnomaly) (created bool, err error)
+	ListPriceAnomalies(ctx context.Context, filter *PriceAnomalyFilter) ([]*PriceAnomaly, error)
+}
+
+// DetectPriceAnomalies ищет аномалии цен подписок, действующих в месяце now.
+// Эталон для сравнения - рекомендованная цена из справочника, а если ее нет -
+// медиана цен на тот же service_name (не меньше minAnomalyPeers подписок).
+// Выбросом считается отклонение от эталона не меньше outlierPct процентов в любую сторону,
+// скачком - повышение цены не меньше чем на jumpPct процентов за последние месяцы.
+func DetectPriceAnomalies(subs []*Subscription, listPrices map[string]Money, now time.Time, outlierPct, jumpPct int) ([]*PriceAnomaly, error) {
+	month := MonthStart(now)
+
+	active := make([]*Subscription, 0, len(subs))
+	peers := make(map[string][]Money)
+	for _, sub := range subs {
+		if sub.StartDate.After(month) || (sub.EndDate != nil && sub.EndDate.Before(month)) {
+			continue
+		}
+		active = append(active, sub)
+		peers[sub.ServiceName] = append(peers[sub.ServiceName], sub.PriceAt(month))
+	}
+
+	anomalies := make([]*PriceAnomaly, 0)
+	for _, sub := range active {
+		price := sub.PriceAt(month)
+
+		ref, reference := Money(0), ""
+		if listPrice, ok := listPrices[sub.ServiceName]; ok && listPrice > 0 {
+			ref, reference = listPrice, ReferenceCatalog
+		} else if prices := peers[sub.ServiceName]; len(prices) >= minAnomalyPeers {
+			ref, reference = medianMoney(prices), ReferencePeerMedian
+		}
+		if reference != "" {
+			deviation, err := deviationBP(price, ref)
+			if err != nil {
+				return nil, err
+			}
+			if abs(deviation) >= outlierPct*100 {
+				anomalies = append(anomalies, &PriceAnomaly{
+					SubscriptionID: sub.ID,
+					UserID:         sub.UserID,
+					ServiceName:    sub.ServiceName,
+					Kind:           AnomalyOutlier,
+					Price:          price,
+					ReferencePrice: ref,
+					Reference:      reference,
+					DeviationBP:    deviation,
+					EffectiveDate:  sub.priceEffectiveDate(month),
+				})
+			}
+		}
+
+		// Изменения цены отсортированы по дате, предыдущая цена - цена до изменения
+		from := month.AddDate(0, -jumpLookbackMonths+1, 0)
+		previous := sub.Price
+		for _, c := range sub.PriceChanges {
+			if c.EffectiveDate.After(month) {
+				break
+			}
+			if !c.EffectiveDate.Before(from) && c.Price > previous && previous > 0 {
+				deviation, err := deviationBP(c.Price, previous)
+				if err != nil {
+					return nil, err
+				}
+				if deviation >= jumpPct*100 {
+					anomalies = append(anomalies, &PriceAnomaly{
+						SubscriptionID: sub.ID,
+						UserID:         sub.UserID,
+						ServiceName:    sub.ServiceName,
+						Kind:           AnomalyJump,
+						Price:          c.Price,
+						ReferencePrice: previous,
+						Reference:      ReferencePrevious,
+						DeviationBP:    deviation,
+						EffectiveDate:  c.EffectiveDate,
+					})
+				}
+			}
+			previous = c.Price
+		}
+	}
+	return anomalies, nil
+}
+
+// priceEffectiveDate с какого месяца действует цена подписки на month
+func (s *Subscription) priceEffectiveDate(month time.Time) time.Time {
+	effective := MonthStart(s.StartDate)
+	for _, c := range s.PriceChanges {
+		if c.EffectiveDate.After(month) {
+			break
+		}
+		effective = c.EffectiveDate
+	}
+	return effective
+}
+
+// deviationBP отклонение price от ref в базисных пунктах
+func deviationBP(price, ref Money) (int, error) {
+	deviation, err := (price - ref).MulDiv(10000, int64(ref))
+	if err != nil {
+		return 0, err
+	}
+	return int(deviation), nil
+}
+
+// medianMoney медиана цен, для четного числа - среднее двух центральных с округлением вниз
+func medianMoney(prices []Money) Money {
+	sorted := append([]Money(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return sorted[mid-1] + (sorted[mid]-sorted[mid-1])/2
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
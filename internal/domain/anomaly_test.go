@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectPriceAnomalies(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	subs := []*Subscription{
+		// медиана Netflix 999.00
+		{ID: 1, UserID: "a", ServiceName: "Netflix", Price: 99900, StartDate: jan},
+		{ID: 2, UserID: "b", ServiceName: "Netflix", Price: 99900, StartDate: jan},
+		{ID: 3, UserID: "c", ServiceName: "Netflix", Price: 199900, StartDate: jan},
+		// цена подросла на 25%, но до выброса не дотягивает
+		{ID: 4, UserID: "d", ServiceName: "Netflix", Price: 79900, StartDate: jan,
+			PriceChanges: []PriceChange{{EffectiveDate: apr, Price: 99900}}},
+		// для Spotify есть рекомендованная цена, подписка дешевле на 50%
+		{ID: 5, UserID: "a", ServiceName: "Spotify", Price: 8450, StartDate: jan},
+		// меньше трех подписок, медиане не доверяем
+		{ID: 6, UserID: "a", ServiceName: "Kinopoisk", Price: 29900, StartDate: jan},
+		{ID: 7, UserID: "b", ServiceName: "Kinopoisk", Price: 99900, StartDate: jan},
+		// уже закончилась
+		{ID: 8, UserID: "e", ServiceName: "Netflix", Price: 999900, StartDate: jan, EndDate: &apr},
+		// повышение вступит в силу только в июне
+		{ID: 9, UserID: "e", ServiceName: "Spotify", Price: 16900, StartDate: jan,
+			PriceChanges: []PriceChange{{EffectiveDate: may.AddDate(0, 1, 0), Price: 99900}}},
+	}
+	listPrices := map[string]Money{"Spotify": 16900}
+
+	got, err := DetectPriceAnomalies(subs, listPrices, now, 30, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []PriceAnomaly{
+		{SubscriptionID: 3, Kind: AnomalyOutlier, Price: 199900, ReferencePrice: 99900,
+			Reference: ReferencePeerMedian, DeviationBP: 10010, EffectiveDate: jan},
+		{SubscriptionID: 4, Kind: AnomalyJump, Price: 99900, ReferencePrice: 79900,
+			Reference: ReferencePrevious, DeviationBP: 2503, EffectiveDate: apr},
+		{SubscriptionID: 5, Kind: AnomalyOutlier, Price: 8450, ReferencePrice: 16900,
+			Reference: ReferenceCatalog, DeviationBP: -5000, EffectiveDate: jan},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d anomalies, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.SubscriptionID != w.SubscriptionID || g.Kind != w.Kind || g.Price != w.Price ||
+			g.ReferencePrice != w.ReferencePrice || g.Reference != w.Reference ||
+			g.DeviationBP != w.DeviationBP || !g.EffectiveDate.Equal(w.EffectiveDate) {
+			t.Errorf("anomaly %d = %+v, want %+v", i, *g, w)
+		}
+	}
+}
+
+func TestDetectPriceAnomalies_OldJumpIgnored(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*Subscription{{ID: 1, UserID: "a", ServiceName: "Netflix", Price: 50000, StartDate: jan,
+		PriceChanges: []PriceChange{{EffectiveDate: jan.AddDate(0, 1, 0), Price: 99900}}}}
+
+	got, err := DetectPriceAnomalies(subs, nil, now, 30, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %+v, want no anomalies", got)
+	}
+}
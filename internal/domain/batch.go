@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+const (
+	// BatchAtomic все операции выполняются в одной транзакции, ошибка любой отменяет все
+	BatchAtomic = "atomic"
+	// BatchPartial операции выполняются независимо, ошибка одной не отменяет остальные
+	BatchPartial = "partial"
+)
+
+// Статусы операций пакета
+const (
+	BatchOK         = "ok"
+	BatchFailed     = "failed"
+	BatchRolledBack = "rolled_back" // выполнена, но отменена из-за ошибки другой операции
+	BatchSkipped    = "skipped"     // не выполнялась из-за ошибки другой операции
+)
+
+// ErrBatchAborted пакет в режиме atomic отменен, подробности в результатах операций
+var ErrBatchAborted = errors.New("batch aborted")
+
+// BatchOperationInput операция пакета в запросе: для create и update - подписка,
+// для delete - user_id и service_name
+type BatchOperationInput struct {
+	Op           string             `json:"op" example:"create"`
+	Subscription *SubscriptionInput `json:"subscription,omitempty"`
+	UserID       *string            `json:"user_id,omitempty"`
+	ServiceName  *string            `json:"service_name,omitempty"`
+}
+
+type BatchRequest struct {
+	Mode       string                `json:"mode" example:"atomic"`
+	Operations []BatchOperationInput `json:"operations"`
+}
+
+// BatchOperation проверенная операция пакета
+type BatchOperation struct {
+	Op           string
+	Subscription *Subscription
+	UserID       string
+	ServiceName  string
+}
+
+// BatchResult результат операции с номером Index в запросе. ID - подписка, созданная или обновленная операцией.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+type BatchRepository interface {
+	// ApplyBatch выполняет операции по порядку. В режиме atomic при ошибке операции
+	// ничего не сохраняется и возвращается ErrBatchAborted вместе с результатами.
+	// Ошибки отдельных операций (не найдена, уже существует) попадают в результаты,
+	// ошибка err означает сбой всего пакета. month - текущий расчетный месяц, как в Update.
+	ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool, month time.Time) ([]BatchResult, error)
+}
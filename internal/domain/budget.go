@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AlertActual   = "actual"
+	AlertForecast = "forecast"
+)
+
+// Budget месячный бюджет пользователя, категории сервисов или пользователя в категории.
+// Thresholds - пороги в процентах от Amount, при пересечении которых создается оповещение.
+type Budget struct {
+	ID         int     `json:"id"`
+	UserID     *string `json:"user_id,omitempty"`
+	Category   *string `json:"category,omitempty"`
+	Amount     Money   `json:"amount" swaggertype:"string" example:"1500.00"`
+	Thresholds []int   `json:"thresholds"`
+	WebhookURL *string `json:"webhook_url,omitempty"`
+}
+
+// BudgetAlert оповещение о пересечении порога бюджета фактическими (actual) или
+// прогнозными (forecast) расходами за месяц
+type BudgetAlert struct {
+	ID           int       `json:"id"`
+	BudgetID     int       `json:"budget_id"`
+	UserID       *string   `json:"user_id,omitempty"`
+	Category     *string   `json:"category,omitempty"`
+	Month        time.Time `json:"month"`
+	Kind         string    `json:"kind"`
+	Threshold    int       `json:"threshold"`
+	Spend        Money     `json:"spend" swaggertype:"string"`
+	BudgetAmount Money     `json:"budget_amount" swaggertype:"string"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type BudgetAlertFilter struct {
+	UserID   *string
+	BudgetID *int
+	Limit    *int
+}
+
+type BudgetRepository interface {
+	CreateBudget(ctx context.Context, budget *Budget) error
+	UpdateBudget(ctx context.Context, budget *Budget) error
+	ListBudgets(ctx context.Context, userID *string) ([]*Budget, error)
+	DeleteBudget(ctx context.Context, id int) error
+	// SaveBudgetAlert сохраняет оповещение, created=false если такое уже было за этот месяц
+	SaveBudgetAlert(ctx context.Context, alert *BudgetAlert) (created bool, err error)
+	ListBudgetAlerts(ctx context.Context, filter *BudgetAlertFilter) ([]*BudgetAlert, error)
+}
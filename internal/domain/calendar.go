@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrCalendarToken токен календаря не выдан или не совпадает
+var ErrCalendarToken = errors.New("calendar not found")
+
+// calendarHorizonMonths на сколько месяцев вперед раскладываются списания бессрочной подписки,
+// чтобы найти период с неизменной суммой; последний такой период повторяется без ограничения
+const calendarHorizonMonths = 12
+
+type CalendarRepository interface {
+	// SetCalendarToken сохраняет хэш токена календаря пользователя, прежний токен перестает действовать
+	SetCalendarToken(ctx context.Context, userID, tokenHash string) error
+	CheckCalendarToken(ctx context.Context, userID, tokenHash string) (bool, error)
+	DeleteCalendarToken(ctx context.Context, userID string) error
+}
+
+// BuildCalendar календарь iCalendar (RFC 5545) со списаниями по подпискам пользователя userID.
+// Списание происходит первого числа каждого месяца, месяцы с одинаковой суммой (доля пользователя
+// с учетом изменений цены и промокодов) объединяются в повторяющееся событие. Для подписки
+// с датой окончания добавляется событие в последний день ее последнего месяца.
+func BuildCalendar(subs []*Subscription, userID string, now time.Time) (string, error) {
+	sorted := append([]*Subscription(nil), subs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var b strings.Builder
+	stamp := now.UTC().Format("20060102T150405Z")
+	writeCalendarLine(&b, "BEGIN:VCALENDAR")
+	writeCalendarLine(&b, "VERSION:2.0")
+	writeCalendarLine(&b, "PRODID:-//effectivemobile//subscriptions//RU")
+	writeCalendarLine(&b, "CALSCALE:GREGORIAN")
+	writeCalendarLine(&b, "METHOD:PUBLISH")
+	writeCalendarLine(&b, "X-WR-CALNAME:"+calendarText("Подписки"))
+
+	horizon := MonthStart(now).AddDate(0, calendarHorizonMonths, 0)
+	for _, sub := range sorted {
+		num, den := sub.ShareOf(userID)
+		if num == 0 {
+			continue
+		}
+		last := horizon
+		if sub.EndDate != nil {
+			last = MonthStart(*sub.EndDate)
+		}
+
+		// периоды подряд идущих месяцев с одинаковой суммой списания
+		type segment struct {
+			start  time.Time
+			months int
+			amount Money
+		}
+		var segments []segment
+		for month := MonthStart(sub.StartDate); !month.After(last); month = month.AddDate(0, 1, 0) {
+			price, discount := sub.ChargeAt(month)
+			amount, err := (price - discount).MulDiv(int64(num), int64(den))
+			if err != nil {
+				return "", err
+			}
+			if n := len(segments); n > 0 && segments[n-1].amount == amount {
+				segments[n-1].months++
+				continue
+			}
+			segments = append(segments, segment{start: month, months: 1, amount: amount})
+		}
+
+		for i, seg := range segments {
+			writeCalendarLine(&b, "BEGIN:VEVENT")
+			writeCalendarLine(&b, fmt.Sprintf("UID:sub-%d-charge-%s@effectivemobile", sub.ID, seg.start.Format("200601")))
+			writeCalendarLine(&b, "DTSTAMP:"+stamp)
+			writeCalendarLine(&b, "DTSTART;VALUE=DATE:"+seg.start.Format("20060102"))
+			if sub.EndDate == nil && i == len(segments)-1 {
+				writeCalendarLine(&b, "RRULE:FREQ=MONTHLY")
+			} else if seg.months > 1 {
+				writeCalendarLine(&b, fmt.Sprintf("RRULE:FREQ=MONTHLY;COUNT=%d", seg.months))
+			}
+			writeCalendarLine(&b, "SUMMARY:"+calendarText(fmt.Sprintf("Списание по подписке %s: %s", sub.ServiceName, seg.amount)))
+			writeCalendarLine(&b, "TRANSP:TRANSPARENT")
+			writeCalendarLine(&b, "END:VEVENT")
+		}
+
+		if sub.EndDate != nil {
+			end := MonthStart(*sub.EndDate).AddDate(0, 1, -1)
+			writeCalendarLine(&b, "BEGIN:VEVENT")
+			writeCalendarLine(&b, fmt.Sprintf("UID:sub-%d-end@effectivemobile", sub.ID))
+			writeCalendarLine(&b, "DTSTAMP:"+stamp)
+			writeCalendarLine(&b, "DTSTART;VALUE=DATE:"+end.Format("20060102"))
+			writeCalendarLine(&b, "SUMMARY:"+calendarText(fmt.Sprintf("Подписка %s заканчивается", sub.ServiceName)))
+			writeCalendarLine(&b, "TRANSP:TRANSPARENT")
+			writeCalendarLine(&b, "END:VEVENT")
+		}
+	}
+	writeCalendarLine(&b, "END:VCALENDAR")
+	return b.String(), nil
+}
+
+// calendarText экранирует значение типа TEXT
+func calendarText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeCalendarLine пишет строку с CRLF, перенося ее по 75 октетов без разрыва символов UTF-8
+func writeCalendarLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// после переноса строка начинается с пробела, он входит в 75 октетов
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	now := time.Date(2025, 3, 15, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*Subscription{
+		{
+			ID: 2, UserID: "u1", ServiceName: "Yandex, Plus", Price: 29900,
+			StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: &end,
+		},
+		{
+			ID: 1, UserID: "u1", ServiceName: "Netflix", Price: 100000,
+			StartDate:    time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+			PriceChanges: []PriceChange{{EffectiveDate: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Price: 120000}},
+			Members:      []Member{{UserID: "u1", Share: 1}, {UserID: "u2", Share: 1}},
+		},
+		{ID: 3, UserID: "u3", ServiceName: "Spotify", Price: 10000, StartDate: end},
+	}
+
+	got, err := BuildCalendar(subs, "u1", now)
+	if err != nil {
+		t.Fatalf("BuildCalendar() error = %v", err)
+	}
+	// развернутые строки без переносов для проверки содержимого
+	unfolded := strings.ReplaceAll(got, "\r\n ", "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		// доля u1 в совместной подписке - половина, до изменения цены 3 месяца
+		"UID:sub-1-charge-202411@effectivemobile\r\nDTSTAMP:20250315T100000Z\r\nDTSTART;VALUE=DATE:20241101\r\nRRULE:FREQ=MONTHLY;COUNT=3\r\nSUMMARY:Списание по подписке Netflix: 500.00\r\n",
+		// после изменения цены событие повторяется без ограничения
+		"DTSTART;VALUE=DATE:20250201\r\nRRULE:FREQ=MONTHLY\r\nSUMMARY:Списание по подписке Netflix: 600.00\r\n",
+		"DTSTART;VALUE=DATE:20250101\r\nRRULE:FREQ=MONTHLY;COUNT=6\r\nSUMMARY:Списание по подписке Yandex\\, Plus: 299.00\r\n",
+		"UID:sub-2-end@effectivemobile\r\nDTSTAMP:20250315T100000Z\r\nDTSTART;VALUE=DATE:20250630\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Spotify") {
+		t.Error("calendar contains subscription of another user")
+	}
+	if strings.Index(got, "sub-1-") > strings.Index(got, "sub-2-") {
+		t.Error("events are not ordered by subscription id")
+	}
+	for _, line := range strings.Split(got, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is longer than 75 octets: %q", line)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "context"
+
+// CatalogService запись справочника сервисов. ListPrice - рекомендованная цена,
+// используется для поиска аномалий цены
+type CatalogService struct {
+	ServiceName string `json:"service_name"`
+	Category    string `json:"category"`
+	ListPrice   *Money `json:"list_price,omitempty" swaggertype:"string"`
+}
+
+type CatalogRepository interface {
+	// SetCatalogService создает или обновляет запись справочника
+	SetCatalogService(ctx context.Context, svc *CatalogService) error
+	ListCatalog(ctx context.Context) ([]*CatalogService, error)
+	DeleteCatalogService(ctx context.Context, serviceName string) error
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Charge предстоящее списание по подписке
+type Charge struct {
+	UserID      string    `json:"user_id"`
+	ServiceName string    `json:"service_name"`
+	ChargeDate  time.Time `json:"charge_date"`
+	Amount      Money     `json:"amount" swaggertype:"string"`
+	Discount    Money     `json:"discount,omitempty" swaggertype:"string"`
+}
+
+// MonthStart первое число месяца, в котором находится t
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ChargeAt начисление по подписке за месяц month: цена с учетом изменений цены и скидка по промокоду
+func (s *Subscription) ChargeAt(month time.Time) (price, discount Money) {
+	price = s.PriceAt(month)
+	return price, s.DiscountAt(month, price)
+}
+
+// NextCharge дата ближайшего списания не раньше now. Списание происходит первого числа
+// каждого месяца начиная с месяца StartDate и заканчивая месяцем EndDate включительно.
+// Возвращает nil, если подписка закончилась.
+func (s *Subscription) NextCharge(now time.Time) *time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	next := MonthStart(today)
+	if next.Before(today) {
+		next = next.AddDate(0, 1, 0)
+	}
+	if start := MonthStart(s.StartDate); next.Before(start) {
+		next = start
+	}
+	if s.EndDate != nil && next.After(MonthStart(*s.EndDate)) {
+		return nil
+	}
+	return &next
+}
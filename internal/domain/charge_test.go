@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscription_NextCharge(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{StartDate: start, EndDate: &end}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want *time.Time
+	}{
+		{name: "before start", now: time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), want: &start},
+		{name: "first day of month", now: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), want: func() *time.Time {
+			d := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+			return &d
+		}()},
+		{name: "last charge", now: time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), want: &end},
+		{name: "ended", now: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sub.NextCharge(tt.now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NextCharge() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscription_DueReminder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{ID: 1, UserID: "u", ServiceName: "Netflix", Price: 29900, StartDate: start, EndDate: &end}
+	all := &NotificationPreferences{Renewal: true, Ending: true}
+	window := 3 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		prefs    *NotificationPreferences
+		wantKind string
+		wantDate time.Time
+	}{
+		{name: "renewal in window", now: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC), prefs: all,
+			wantKind: ReminderRenewal, wantDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "outside window", now: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), prefs: all},
+		{name: "last charge", now: time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC), prefs: all,
+			wantKind: ReminderEnding, wantDate: end},
+		{name: "renewal disabled", now: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC),
+			prefs: &NotificationPreferences{Ending: true}},
+		{name: "ended", now: time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), prefs: all},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sub.DueReminder(tt.now, window, tt.prefs)
+			if tt.wantKind == "" {
+				if got != nil {
+					t.Errorf("DueReminder() got = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Kind != tt.wantKind || !got.ChargeDate.Equal(tt.wantDate) || got.Amount != 29900 {
+				t.Errorf("DueReminder() got = %+v, want %s at %v", got, tt.wantKind, tt.wantDate)
+			}
+		})
+	}
+}
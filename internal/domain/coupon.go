@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CouponPercent = "percent"
+	CouponFixed   = "fixed"
+)
+
+// Coupon промокод: скидка Percent процентов (kind=percent) или фиксированной суммой Amount
+// (kind=fixed) на DurationMonths месяцев
+type Coupon struct {
+	Code           string `json:"code"`
+	Kind           string `json:"kind"`
+	Percent        int    `json:"percent,omitempty"`
+	Amount         Money  `json:"amount,omitempty" swaggertype:"string" example:"50.00"`
+	DurationMonths int    `json:"duration_months"`
+}
+
+type CouponAttachInput struct {
+	UserID      *string `json:"user_id"`
+	ServiceName *string `json:"service_name"`
+	CouponCode  *string `json:"coupon_code"`
+	StartDate   *string `json:"start_date,omitempty"`
+}
+
+type CouponRepository interface {
+	CreateCoupon(ctx context.Context, coupon *Coupon) error
+	ListCoupons(ctx context.Context) ([]*Coupon, error)
+	DeleteCoupon(ctx context.Context, code string) error
+	// AttachCoupon привязывает промокод к подписке, code == nil отвязывает его
+	AttachCoupon(ctx context.Context, userID, serviceName string, code *string, start *time.Time) error
+}
+
+// DiscountFor возвращает размер скидки для месячной цены, скидка не превышает цену
+func (c *Coupon) DiscountFor(price Money) Money {
+	var discount Money
+	switch c.Kind {
+	case CouponPercent:
+		// результат не больше цены, переполнение невозможно
+		discount, _ = price.MulDiv(int64(c.Percent), 100)
+	case CouponFixed:
+		discount = c.Amount
+	}
+	if discount > price {
+		return price
+	}
+	return discount
+}
+
+// DiscountAt возвращает скидку по подписке за месяц month с учетом срока действия промокода
+func (s *Subscription) DiscountAt(month time.Time, price Money) Money {
+	if s.Coupon == nil || s.CouponStart == nil {
+		return 0
+	}
+	couponEnd := s.CouponStart.AddDate(0, s.Coupon.DurationMonths, 0)
+	if month.Before(*s.CouponStart) || !month.Before(couponEnd) {
+		return 0
+	}
+	return s.Coupon.DiscountFor(price)
+}
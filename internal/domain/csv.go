@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVColumns колонки выгрузки подписок. category только выгружается,
+// при импорте она берется из справочника и игнорируется.
+var CSVColumns = []string{"user_id", "service_name", "price", "start_date", "end_date", "tax_inclusive", "tax_region", "category"}
+
+// csvRequired колонки, без которых файл импорта не принимается
+var csvRequired = []string{"user_id", "service_name", "price", "start_date"}
+
+// MaxImportRows наибольшее число строк в одном импорте
+const MaxImportRows = 10000
+
+// ErrInvalidCSV файл импорта не удалось разобрать или в нем неверный заголовок
+var ErrInvalidCSV = errors.New("invalid csv")
+
+// ErrImportTooLarge в файле импорта больше MaxImportRows строк
+var ErrImportTooLarge = fmt.Errorf("import must not exceed %d rows", MaxImportRows)
+
+// Статусы строк импорта
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+)
+
+// ImportResult результат записи подписки при импорте, ID - созданная или обновленная подписка
+type ImportResult struct {
+	Status string
+	ID     int
+	Error  string
+}
+
+// ImportRowError ошибка строки файла, Row - номер строки с учетом заголовка
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportReport итог импорта. При DryRun данные проверены и записаны в транзакцию,
+// которая затем откатывается: счетчики показывают, что было бы сделано.
+type ImportReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Rows    int              `json:"rows"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+type ImportRepository interface {
+	// StreamSubscriptions вызывает fn для каждой подписки по фильтру Search, не накапливая их в памяти.
+	// Участники и связанные данные (Expand) не загружаются.
+	StreamSubscriptions(ctx context.Context, filter *Filter, fn func(*Subscription) error) error
+	// ImportSubscriptions создает или обновляет подписки по (user_id, service_name).
+	// Ошибки отдельных подписок попадают в результаты, err означает сбой всего импорта.
+	// При dryRun изменения откатываются. month - текущий расчетный месяц, как в Update.
+	ImportSubscriptions(ctx context.Context, subs []*Subscription, dryRun bool, month time.Time) ([]ImportResult, error)
+}
+
+// CSVRecord строка выгрузки подписки в порядке CSVColumns
+func CSVRecord(sub *Subscription) []string {
+	record := make([]string, len(CSVColumns))
+	record[0] = sub.UserID
+	record[1] = sub.ServiceName
+	record[2] = sub.Price.String()
+	record[3] = sub.StartDate.Format(dateForm)
+	if sub.EndDate != nil {
+		record[4] = sub.EndDate.Format(dateForm)
+	}
+	record[5] = strconv.FormatBool(sub.TaxInclusive)
+	if sub.TaxRegion != nil {
+		record[6] = *sub.TaxRegion
+	}
+	if sub.Category != nil {
+		record[7] = *sub.Category
+	}
+	return record
+}
+
+// ParseCSVHeader проверяет заголовок файла импорта и возвращает номера колонок по именам
+func ParseCSVHeader(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(CSVColumns))
+	for _, c := range CSVColumns {
+		known[c] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		// BOM в начале файла из Excel
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvRequired {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q is required", name)
+		}
+	}
+	return columns, nil
+}
+
+// ParseCSVRecord разбирает строку файла импорта, пустые необязательные значения означают их отсутствие.
+// Проверка значений - SubscriptionInput.Validate.
+func ParseCSVRecord(columns map[string]int, record []string) (*SubscriptionInput, error) {
+	value := func(name string) *string {
+		i, ok := columns[name]
+		if !ok {
+			return nil
+		}
+		v := strings.TrimSpace(record[i])
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+
+	input := &SubscriptionInput{
+		UserID:      value("user_id"),
+		ServiceName: value("service_name"),
+		StartDate:   value("start_date"),
+		EndDate:     value("end_date"),
+		TaxRegion:   value("tax_region"),
+	}
+	if v := value("price"); v != nil {
+		price, err := ParseMoney(*v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price: %v", err)
+		}
+		input.Price = &price
+	}
+	if v := value("tax_inclusive"); v != nil {
+		inclusive, err := strconv.ParseBool(*v)
+		if err != nil {
+			return nil, fmt.Errorf("tax_inclusive must be true or false")
+		}
+		input.TaxInclusive = &inclusive
+	}
+	return input, nil
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const cursorDateForm = "2006-01-02"
+
+// Page страница результатов поиска. NextCursor пустой на последней странице,
+// Total заполняется только по запросу.
+type Page struct {
+	Items      []*Subscription `json:"items"`
+	NextCursor *string         `json:"next_cursor"`
+	Total      *int            `json:"total,omitempty"`
+}
+
+// Cursor позиция последней строки страницы: значения полей сортировки PageSort.
+// Values имеют тип поля (int, string, Money, time.Time) или nil для null.
+type Cursor struct {
+	Values []interface{}
+}
+
+// cursorPayload содержимое непрозрачного курсора. Sort сохраняется, чтобы курсор
+// нельзя было применить к поиску с другой сортировкой.
+type cursorPayload struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// PageSort сортировка для постраничного поиска: последним полем всегда идет id,
+// поэтому порядок строк однозначен и курсор указывает ровно на одну строку
+func PageSort(sort []SortField) []SortField {
+	for _, f := range sort {
+		if f.Field == "id" {
+			return sort
+		}
+	}
+	return append(append([]SortField(nil), sort...), SortField{Field: "id"})
+}
+
+// SortKey строковое представление сортировки, как в параметре sort
+func SortKey(sort []SortField) string {
+	parts := make([]string, 0, len(sort))
+	for _, f := range sort {
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeCursor курсор, указывающий на позицию после sub при сортировке sort (см. PageSort)
+func EncodeCursor(sort []SortField, sub *Subscription) string {
+	payload := cursorPayload{Sort: SortKey(sort)}
+	for _, f := range sort {
+		var raw []byte
+		value, ok := subscriptionField(sub, f.Field)
+		switch v := value.(type) {
+		case time.Time:
+			raw, _ = json.Marshal(v.Format(cursorDateForm))
+		case Money:
+			raw, _ = json.Marshal(int64(v))
+		default:
+			if ok {
+				raw, _ = json.Marshal(v)
+			} else {
+				raw = []byte("null")
+			}
+		}
+		payload.Values = append(payload.Values, raw)
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает курсор и проверяет, что он выдан для той же сортировки
+func DecodeCursor(cursor string, sort []SortField) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Sort != SortKey(sort) || len(payload.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	for i, f := range sort {
+		raw := payload.Values[i]
+		if string(raw) == "null" {
+			if !QueryFields[f.Field].Nullable {
+				return nil, ErrInvalidCursor
+			}
+			c.Values = append(c.Values, nil)
+			continue
+		}
+		value, err := decodeCursorValue(f.Field, raw)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		c.Values = append(c.Values, value)
+	}
+	return c, nil
+}
+
+func decodeCursorValue(field string, raw json.RawMessage) (interface{}, error) {
+	if field == "id" {
+		var id int
+		err := json.Unmarshal(raw, &id)
+		return id, err
+	}
+	spec, ok := QueryFields[field]
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	switch spec.Type {
+	case FieldMoney:
+		var v int64
+		err := json.Unmarshal(raw, &v)
+		return Money(v), err
+	case FieldMonth:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return time.Parse(cursorDateForm, s)
+	default:
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{ID: 42, UserID: "u1", ServiceName: "Netflix", Price: 99900, StartDate: jan}
+	sort := PageSort([]SortField{{Field: "start_date", Desc: true}, {Field: "end_date"}, {Field: "price"}})
+
+	if got := SortKey(sort); got != "-start_date,end_date,price,id" {
+		t.Fatalf("SortKey() = %q", got)
+	}
+
+	cursor, err := DecodeCursor(EncodeCursor(sort, sub), sort)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error: %v", err)
+	}
+	want := []interface{}{jan, nil, Money(99900), 42}
+	if len(cursor.Values) != len(want) {
+		t.Fatalf("got %d values, want %d", len(cursor.Values), len(want))
+	}
+	for i, w := range want {
+		got := cursor.Values[i]
+		if tw, ok := w.(time.Time); ok {
+			if tg, ok := got.(time.Time); !ok || !tg.Equal(tw) {
+				t.Errorf("value %d = %v, want %v", i, got, w)
+			}
+			continue
+		}
+		if got != w {
+			t.Errorf("value %d = %#v, want %#v", i, got, w)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	sort := PageSort(nil)
+	valid := EncodeCursor(sort, &Subscription{ID: 1})
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   []SortField
+	}{
+		{name: "not base64", cursor: "!!!", sort: sort},
+		{name: "not json", cursor: "bm90IGpzb24", sort: sort},
+		{name: "other sort", cursor: valid, sort: PageSort([]SortField{{Field: "price"}})},
+		{name: "null in required field", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"price,id","v":[null,1]}`)),
+			sort: PageSort([]SortField{{Field: "price"}})},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeCursor(tt.cursor, tt.sort); err != ErrInvalidCursor {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
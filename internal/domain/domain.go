@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 )
@@ -9,46 +10,153 @@ import (
 const dateForm = "01-2006"
 
 type Subscription struct {
-	UserID      string     `json:"user_id"`
-	ServiceName string     `json:"service_name"`
-	Price       int        `json:"price"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     *time.Time `json:"end_date,omitempty"`
+	ID           int           `json:"id"`
+	UserID       string        `json:"user_id"`
+	ServiceName  string        `json:"service_name"`
+	Price        Money         `json:"price" swaggertype:"string" example:"299.99"`
+	StartDate    time.Time     `json:"start_date"`
+	EndDate      *time.Time    `json:"end_date,omitempty"`
+	Members      []Member      `json:"members,omitempty"`
+	Coupon       *Coupon       `json:"coupon,omitempty"`
+	CouponStart  *time.Time    `json:"coupon_start,omitempty"`
+	TaxInclusive bool          `json:"tax_inclusive"`
+	TaxRegion    *string       `json:"tax_region,omitempty"`
+	TaxRate      *TaxRate      `json:"tax_rate,omitempty"`
+	PriceChanges []PriceChange `json:"price_changes,omitempty"`
+	Category     *string       `json:"category,omitempty"`
+	// вычисляемое поле, в БД не хранится
+	NextChargeDate *time.Time `json:"next_charge_date,omitempty"`
+	// связанные данные, заполняются только по Filter.Expand
+	Catalog *CatalogService  `json:"catalog,omitempty"`
+	Audit   []*OutboxMessage `json:"audit,omitempty"`
+	// TaxInclusiveSet tax_inclusive указан явно; при обновлении без него сохраняется прежнее значение
+	TaxInclusiveSet bool `json:"-"`
+}
+
+// Summary итог по подпискам за период: TotalPrice к оплате с учетом скидок,
+// GrossPrice без скидок, DiscountTotal сумма скидок, Tax разбивка с учетом налога
+type Summary struct {
+	TotalPrice    Money        `json:"total_price" swaggertype:"string"`
+	GrossPrice    Money        `json:"gross_price" swaggertype:"string"`
+	DiscountTotal Money        `json:"discount_total" swaggertype:"string"`
+	Tax           TaxBreakdown `json:"tax"`
+}
+
+// Member участник совместной (семейной) подписки, Share - вес его доли в стоимости
+type Member struct {
+	UserID string `json:"user_id"`
+	Share  int    `json:"share"`
 }
 
 type SubscriptionInput struct {
-	UserID      *string `json:"user_id"`
-	ServiceName *string `json:"service_name"`
-	Price       *int    `json:"price"`
-	StartDate   *string `json:"start_date"`
-	EndDate     *string `json:"end_date,omitempty"`
+	UserID       *string  `json:"user_id"`
+	ServiceName  *string  `json:"service_name"`
+	Price        *Money   `json:"price" swaggertype:"string" example:"299.99"`
+	StartDate    *string  `json:"start_date"`
+	EndDate      *string  `json:"end_date,omitempty"`
+	Members      []Member `json:"members,omitempty"`
+	TaxInclusive *bool    `json:"tax_inclusive,omitempty"`
+	TaxRegion    *string  `json:"tax_region,omitempty"`
 }
 
 type Filter struct {
 	UserID       *string `json:"user_id,omitempty"`
 	ServiceName  *string `json:"service_name,omitempty"`
-	Price        *int    `json:"price,omitempty"`
+	Price        *Money  `json:"price,omitempty" swaggertype:"string" example:"299.99"`
 	StartDate    *time.Time
 	StartDateStr *string `json:"start_date,omitempty"`
 	EndDate      *time.Time
 	EndDateStr   *string `json:"end_date,omitempty"`
+	Category     *string `json:"category,omitempty"`
 	Limit        *int    `json:"limit,omitempty"`
 	Offset       *int    `json:"offset,omitempty"`
+	// Query выражение фильтра, применяется вместе с остальными условиями
+	Query Expr `json:"-"`
+
+	// Диапазон цены включительно
+	PriceMin *Money `json:"-"`
+	PriceMax *Money `json:"-"`
+	// ActiveFrom и ActiveTo подписки, действующие хотя бы месяц в периоде, как в GetSubscriptionsForPeriod
+	ActiveFrom *time.Time `json:"-"`
+	ActiveTo   *time.Time `json:"-"`
+	// Месяц начала строго после и строго до заданного
+	StartedAfter  *time.Time  `json:"-"`
+	StartedBefore *time.Time  `json:"-"`
+	Sort          []SortField `json:"-"`
+	// Q нечеткий поиск по названию сервиса, без явной сортировки результаты упорядочены по сходству
+	Q *string `json:"-"`
+	// Expand связанные данные, которые нужно подгрузить в результаты поиска (ExpandFields)
+	Expand []string `json:"-"`
+	// Cursor позиция, после которой начинается страница (SearchPage), WithTotal - посчитать Page.Total
+	Cursor    *string `json:"-"`
+	WithTotal bool    `json:"-"`
+}
+
+const (
+	ExpandPriceChanges = "price_changes"
+	ExpandCatalog      = "catalog"
+	ExpandAudit        = "audit"
+)
+
+// ExpandFields связанные данные, доступные в Filter.Expand
+var ExpandFields = map[string]bool{
+	ExpandPriceChanges: true,
+	ExpandCatalog:      true,
+	ExpandAudit:        true,
+}
+
+// AuditEventTypes события журнала, которые попадают в Subscription.Audit
+var AuditEventTypes = []string{EventCreated, EventUpdated, EventDeleted, EventExpiring}
+
+// SortField поле сортировки результатов поиска
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// SortFields белый список полей сортировки
+var SortFields = map[string]bool{
+	"id":           true,
+	"user_id":      true,
+	"service_name": true,
+	"category":     true,
+	"price":        true,
+	"start_date":   true,
+	"end_date":     true,
 }
 
 type Repository interface {
 	Search(ctx context.Context, filter *Filter) ([]*Subscription, error)
+	// SearchPage страница поиска по курсору, размер страницы - filter.Limit
+	SearchPage(ctx context.Context, filter *Filter) (*Page, error)
 	Create(ctx context.Context, sub *Subscription) error
-	Update(ctx context.Context, sub *Subscription) error
+	// Update при изменении цены записывает ее в историю с месяца month, текущего расчетного месяца
+	Update(ctx context.Context, sub *Subscription, month time.Time) error
 	Delete(ctx context.Context, filter *Filter) error
 	GetSubscriptionsForPeriod(ctx context.Context, filter *Filter) ([]*Subscription, error)
 	CloseDB()
+	CouponRepository
+	TaxRepository
+	PriceChangeRepository
+	CatalogRepository
+	BudgetRepository
+	WebhookRepository
+	OutboxRepository
+	ReminderRepository
+	AnalyticsRepository
+	AnomalyRepository
+	BatchRepository
+	ImportRepository
+	CalendarRepository
+	JobRepository
+	IdempotencyRepository
 }
 
 type SubscriptionOption func(*Subscription)
 
 func NewSubscription(opts ...SubscriptionOption) *Subscription {
-	s := &Subscription{}
+	// по умолчанию цена указана с учетом налога
+	s := &Subscription{TaxInclusive: true}
 
 	for _, opt := range opts {
 		opt(s)
@@ -73,9 +181,83 @@ func (s *SubscriptionInput) SubscriptionToOptions() []SubscriptionOption {
 	if s.EndDate != nil {
 		opts = append(opts, WithEndDate(*s.EndDate))
 	}
+	if s.Members != nil {
+		opts = append(opts, WithMembers(s.Members))
+	}
+	if s.TaxInclusive != nil {
+		opts = append(opts, WithTaxInclusive(*s.TaxInclusive))
+	}
+	if s.TaxRegion != nil {
+		opts = append(opts, WithTaxRegion(*s.TaxRegion))
+	}
 	return opts
 }
 
+// Validate проверяет данные подписки, общие правила для API и импорта
+func (s *SubscriptionInput) Validate() error {
+	if s.UserID == nil || *s.UserID == "" || len(*s.UserID) != 36 {
+		return fmt.Errorf("user_id is required correct format UUID")
+	}
+	if s.ServiceName == nil || *s.ServiceName == "" {
+		return fmt.Errorf("service_name is required")
+	}
+	if s.ServiceName != nil && len(*s.ServiceName) > 255 {
+		return fmt.Errorf("service_name must not exceed 255 characters")
+	}
+	if s.Price == nil || *s.Price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if s.StartDate == nil || *s.StartDate == "" {
+		return fmt.Errorf("start_date is required")
+	}
+	if _, err := time.Parse(dateForm, *s.StartDate); err != nil {
+		return fmt.Errorf("invalid start_date format, expected MM-YYYY")
+	}
+	if s.EndDate != nil && *s.EndDate != "" {
+		if _, err := time.Parse(dateForm, *s.EndDate); err != nil {
+			return fmt.Errorf("invalid end_date format, expected MM-YYYY")
+		}
+	}
+	if s.TaxRegion != nil && (*s.TaxRegion == "" || len(*s.TaxRegion) > 8) {
+		return fmt.Errorf("tax_region must be 1 to 8 characters")
+	}
+	seen := make(map[string]bool, len(s.Members))
+	for _, m := range s.Members {
+		if len(m.UserID) != 36 {
+			return fmt.Errorf("members.user_id is required correct format UUID")
+		}
+		if m.Share <= 0 {
+			return fmt.Errorf("members.share must be positive")
+		}
+		if seen[m.UserID] {
+			return fmt.Errorf("duplicate member %s", m.UserID)
+		}
+		seen[m.UserID] = true
+	}
+	return nil
+}
+
+// ShareOf возвращает долю пользователя в стоимости подписки в виде дроби num/den.
+// Подписка без участников целиком относится на владельца (user_id).
+func (s *Subscription) ShareOf(userID string) (num, den int) {
+	if len(s.Members) == 0 {
+		if s.UserID == userID {
+			return 1, 1
+		}
+		return 0, 1
+	}
+	for _, m := range s.Members {
+		den += m.Share
+		if m.UserID == userID {
+			num += m.Share
+		}
+	}
+	if den == 0 {
+		return 0, 1
+	}
+	return num, den
+}
+
 func WithUserID(id string) SubscriptionOption {
 	return func(s *Subscription) {
 		s.UserID = id
@@ -88,7 +270,7 @@ func WithServiceName(name string) SubscriptionOption {
 	}
 }
 
-func WithPrice(price int) SubscriptionOption {
+func WithPrice(price Money) SubscriptionOption {
 	return func(s *Subscription) {
 		s.Price = price
 	}
@@ -115,3 +297,22 @@ func WithEndDate(date string) SubscriptionOption {
 		s.EndDate = &parsedDate
 	}
 }
+
+func WithMembers(members []Member) SubscriptionOption {
+	return func(s *Subscription) {
+		s.Members = members
+	}
+}
+
+func WithTaxInclusive(inclusive bool) SubscriptionOption {
+	return func(s *Subscription) {
+		s.TaxInclusive = inclusive
+		s.TaxInclusiveSet = true
+	}
+}
+
+func WithTaxRegion(region string) SubscriptionOption {
+	return func(s *Subscription) {
+		s.TaxRegion = &region
+	}
+}
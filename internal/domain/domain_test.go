@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestSubscriptionInput_TaxInclusive(t *testing.T) {
+	exclusive := false
+	tests := []struct {
+		name      string
+		input     SubscriptionInput
+		wantValue bool
+		wantSet   bool
+	}{
+		// без tax_inclusive обновление не должно менять прежнее значение
+		{name: "omitted", input: SubscriptionInput{}, wantValue: true, wantSet: false},
+		{name: "explicit false", input: SubscriptionInput{TaxInclusive: &exclusive}, wantValue: false, wantSet: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := NewSubscription(tt.input.SubscriptionToOptions()...)
+			if sub.TaxInclusive != tt.wantValue || sub.TaxInclusiveSet != tt.wantSet {
+				t.Errorf("TaxInclusive = %v, TaxInclusiveSet = %v, want %v, %v",
+					sub.TaxInclusive, sub.TaxInclusiveSet, tt.wantValue, tt.wantSet)
+			}
+		})
+	}
+
+	// импорт без колонки tax_inclusive
+	columns, err := ParseCSVHeader([]string{"user_id", "service_name", "price", "start_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := ParseCSVRecord(columns, []string{"60601fee-2bf1-4721-ae6f-7636e79a0cba", "Netflix", "599", "01-2025"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub := NewSubscription(input.SubscriptionToOptions()...); sub.TaxInclusiveSet {
+		t.Error("CSV row without tax_inclusive column sets tax_inclusive")
+	}
+}
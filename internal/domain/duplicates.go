@@ -0,0 +1,280 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DuplicateSameService одна и та же подписка под немного разными названиями
+	DuplicateSameService = "same_service"
+	// DuplicateSameCategory разные сервисы одной категории справочника, например два музыкальных стриминга
+	DuplicateSameCategory = "same_category"
+)
+
+// planWords слова, которыми отличаются тарифы одного сервиса
+var planWords = map[string]bool{
+	"premium": true, "plus": true, "family": true, "basic": true, "standard": true,
+	"pro": true, "individual": true, "duo": true, "student": true, "subscription": true,
+	"подписка": true, "семейная": true, "премиум": true, "плюс": true,
+}
+
+// NormalizeServiceName приводит название к ключу сравнения: нижний регистр, без пунктуации,
+// пробелов и названий тарифов. "Netflix Premium" и "netflix" дают один ключ.
+func NormalizeServiceName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if !planWords[w] {
+			b.WriteString(w)
+		}
+	}
+	if b.Len() == 0 {
+		// название состоит только из слов тарифа, сравниваем как есть
+		return strings.Join(words, "")
+	}
+	return b.String()
+}
+
+// DuplicateItem подписка в группе дубликатов
+type DuplicateItem struct {
+	ServiceName   string     `json:"service_name"`
+	Category      *string    `json:"category,omitempty"`
+	MonthlyCharge Money      `json:"monthly_charge" swaggertype:"string"`
+	StartDate     time.Time  `json:"start_date"`
+	EndDate       *time.Time `json:"end_date,omitempty"`
+}
+
+// DuplicateGroup подписки пользователя, действующие одновременно в периоде
+// [OverlapStart, OverlapEnd]. MonthlySavings - экономия, если оставить только самую дорогую.
+type DuplicateGroup struct {
+	UserID         string          `json:"user_id"`
+	Reason         string          `json:"reason"`
+	Key            string          `json:"key"`
+	OverlapStart   time.Time       `json:"overlap_start"`
+	OverlapEnd     *time.Time      `json:"overlap_end,omitempty"`
+	Subscriptions  []DuplicateItem `json:"subscriptions"`
+	MonthlyTotal   Money           `json:"monthly_total" swaggertype:"string"`
+	MonthlySavings Money           `json:"monthly_savings" swaggertype:"string"`
+}
+
+type DuplicateReport struct {
+	Groups         []*DuplicateGroup `json:"groups"`
+	MonthlySavings Money             `json:"monthly_savings" swaggertype:"string"`
+}
+
+// dupCandidate подписка с ключом сравнения названия
+type dupCandidate struct {
+	sub *Subscription
+	key string
+}
+
+// FindDuplicates ищет дубликаты среди подписок. Если задан userID, все подписки относятся
+// к нему (в том числе совместные, где он участник) и суммы считаются по его доле;
+// иначе подписки группируются по владельцу. Сумма в месяц берется за текущий месяц,
+// а если пересечение уже закончилось или еще не началось - за ближайший месяц пересечения.
+func FindDuplicates(subs []*Subscription, userID *string, now time.Time) (*DuplicateReport, error) {
+	byUser := make(map[string][]dupCandidate)
+	for _, sub := range subs {
+		owner := sub.UserID
+		if userID != nil {
+			owner = *userID
+		}
+		byUser[owner] = append(byUser[owner], dupCandidate{sub: sub, key: NormalizeServiceName(sub.ServiceName)})
+	}
+
+	users := make([]string, 0, len(byUser))
+	for u := range byUser {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+
+	report := &DuplicateReport{Groups: make([]*DuplicateGroup, 0)}
+	month := MonthStart(now)
+	for _, u := range users {
+		candidates := byUser[u]
+
+		// одинаковые сервисы
+		byKey := make(map[string][]*Subscription)
+		for _, c := range candidates {
+			byKey[c.key] = append(byKey[c.key], c.sub)
+		}
+		for _, key := range sortedKeys(byKey) {
+			for _, cluster := range overlapClusters(byKey[key]) {
+				group, err := newDuplicateGroup(u, DuplicateSameService, key, cluster, userID, month)
+				if err != nil {
+					return nil, err
+				}
+				report.Groups = append(report.Groups, group)
+			}
+		}
+
+		// разные сервисы одной категории: от каждого сервиса берется самая дорогая подписка,
+		// чтобы не учитывать экономию от дубликатов сервиса дважды
+		byCategory := make(map[string][]*Subscription)
+		for _, key := range sortedKeys(byKey) {
+			rep, err := mostExpensive(byKey[key], userID, month)
+			if err != nil {
+				return nil, err
+			}
+			if rep.Category != nil {
+				byCategory[*rep.Category] = append(byCategory[*rep.Category], rep)
+			}
+		}
+		for _, category := range sortedKeys(byCategory) {
+			for _, cluster := range overlapClusters(byCategory[category]) {
+				group, err := newDuplicateGroup(u, DuplicateSameCategory, category, cluster, userID, month)
+				if err != nil {
+					return nil, err
+				}
+				report.Groups = append(report.Groups, group)
+			}
+		}
+	}
+
+	for _, g := range report.Groups {
+		var err error
+		if report.MonthlySavings, err = report.MonthlySavings.Add(g.MonthlySavings); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// overlapClusters жадно объединяет подписки, которые попарно пересекаются по датам.
+// Возвращает только группы из двух и более подписок.
+func overlapClusters(subs []*Subscription) [][]*Subscription {
+	if len(subs) < 2 {
+		return nil
+	}
+	sorted := append([]*Subscription(nil), subs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartDate.Before(sorted[j].StartDate) })
+
+	var clusters [][]*Subscription
+	for _, sub := range sorted {
+		placed := false
+		for i, cluster := range clusters {
+			if overlapsAll(sub, cluster) {
+				clusters[i] = append(cluster, sub)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			clusters = append(clusters, []*Subscription{sub})
+		}
+	}
+
+	result := make([][]*Subscription, 0, len(clusters))
+	for _, c := range clusters {
+		if len(c) > 1 {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func overlapsAll(sub *Subscription, cluster []*Subscription) bool {
+	for _, other := range cluster {
+		if !periodsOverlap(sub, other) {
+			return false
+		}
+	}
+	return true
+}
+
+func periodsOverlap(a, b *Subscription) bool {
+	aStart, bStart := MonthStart(a.StartDate), MonthStart(b.StartDate)
+	if a.EndDate != nil && MonthStart(*a.EndDate).Before(bStart) {
+		return false
+	}
+	if b.EndDate != nil && MonthStart(*b.EndDate).Before(aStart) {
+		return false
+	}
+	return true
+}
+
+func newDuplicateGroup(user, reason, key string, cluster []*Subscription, userID *string, now time.Time) (*DuplicateGroup, error) {
+	group := &DuplicateGroup{UserID: user, Reason: reason, Key: key}
+	for _, sub := range cluster {
+		if start := MonthStart(sub.StartDate); start.After(group.OverlapStart) {
+			group.OverlapStart = start
+		}
+		if sub.EndDate != nil {
+			end := MonthStart(*sub.EndDate)
+			if group.OverlapEnd == nil || end.Before(*group.OverlapEnd) {
+				group.OverlapEnd = &end
+			}
+		}
+	}
+
+	month := now
+	if month.Before(group.OverlapStart) {
+		month = group.OverlapStart
+	}
+	if group.OverlapEnd != nil && month.After(*group.OverlapEnd) {
+		month = *group.OverlapEnd
+	}
+
+	var highest Money
+	for _, sub := range cluster {
+		charge, err := monthlyCharge(sub, userID, month)
+		if err != nil {
+			return nil, err
+		}
+		group.Subscriptions = append(group.Subscriptions, DuplicateItem{
+			ServiceName:   sub.ServiceName,
+			Category:      sub.Category,
+			MonthlyCharge: charge,
+			StartDate:     sub.StartDate,
+			EndDate:       sub.EndDate,
+		})
+		if group.MonthlyTotal, err = group.MonthlyTotal.Add(charge); err != nil {
+			return nil, err
+		}
+		if charge > highest {
+			highest = charge
+		}
+	}
+	group.MonthlySavings = group.MonthlyTotal - highest
+	return group, nil
+}
+
+func mostExpensive(subs []*Subscription, userID *string, month time.Time) (*Subscription, error) {
+	var best *Subscription
+	var bestCharge Money
+	for _, sub := range subs {
+		charge, err := monthlyCharge(sub, userID, month)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || charge > bestCharge {
+			best, bestCharge = sub, charge
+		}
+	}
+	return best, nil
+}
+
+// monthlyCharge списание за месяц со скидкой, для userID - по его доле
+func monthlyCharge(sub *Subscription, userID *string, month time.Time) (Money, error) {
+	price, discount := sub.ChargeAt(month)
+	charge := price - discount
+	if userID == nil {
+		return charge, nil
+	}
+	num, den := sub.ShareOf(*userID)
+	return charge.MulDiv(int64(num), int64(den))
+}
+
+func sortedKeys(m map[string][]*Subscription) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
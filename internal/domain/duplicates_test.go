@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeServiceName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Netflix", want: "netflix"},
+		{name: "netflix premium", want: "netflix"},
+		{name: "Yandex.Plus", want: "yandex"},
+		{name: "Spotify Family", want: "spotify"},
+		{name: "Apple  TV+", want: "appletv"},
+		{name: "Premium", want: "premium"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeServiceName(tt.name); got != tt.want {
+			t.Errorf("NormalizeServiceName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	user := "123e4567-e89b-12d3-a456-426614174000"
+	music := "music"
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	subs := []*Subscription{
+		{UserID: user, ServiceName: "Netflix", Price: 99900, StartDate: jan, EndDate: &mar},
+		{UserID: user, ServiceName: "netflix premium", Price: 149900, StartDate: jan, EndDate: &mar},
+		// не пересекается с другими Netflix по датам
+		{UserID: user, ServiceName: "Netflix Basic", Price: 59900, StartDate: jun, EndDate: &jun},
+		{UserID: user, ServiceName: "Spotify", Price: 16900, StartDate: jan, Category: &music},
+		{UserID: user, ServiceName: "Yandex Music", Price: 29900, StartDate: mar, Category: &music},
+	}
+
+	report, err := FindDuplicates(subs, nil, now)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("FindDuplicates() groups = %d, want 2", len(report.Groups))
+	}
+
+	netflix := report.Groups[0]
+	if netflix.Reason != DuplicateSameService || netflix.Key != "netflix" || len(netflix.Subscriptions) != 2 ||
+		netflix.MonthlySavings != 99900 || !netflix.OverlapStart.Equal(jan) {
+		t.Errorf("netflix group = %+v", netflix)
+	}
+
+	// пересечение начинается в марте, суммы считаются за март
+	musicGroup := report.Groups[1]
+	if musicGroup.Reason != DuplicateSameCategory || musicGroup.Key != music ||
+		musicGroup.MonthlyTotal != 46800 || musicGroup.MonthlySavings != 16900 || !musicGroup.OverlapStart.Equal(mar) {
+		t.Errorf("music group = %+v", musicGroup)
+	}
+	if report.MonthlySavings != 99900+16900 {
+		t.Errorf("MonthlySavings = %s", report.MonthlySavings)
+	}
+}
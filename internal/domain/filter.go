@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Match проверяет подписку по всем условиям фильтра так же, как условия WHERE поиска в storage,
+// для репозиториев без PostgreSQL. Limit, Offset, Sort, Cursor и Expand на отбор не влияют.
+func (f *Filter) Match(sub *Subscription) bool {
+	if f.UserID != nil && sub.UserID != *f.UserID {
+		return false
+	}
+	if f.ServiceName != nil && sub.ServiceName != *f.ServiceName {
+		return false
+	}
+	if f.Price != nil && sub.Price != *f.Price {
+		return false
+	}
+	if f.StartDate != nil && !sub.StartDate.Equal(*f.StartDate) {
+		return false
+	}
+	if f.EndDate != nil && (sub.EndDate == nil || !sub.EndDate.Equal(*f.EndDate)) {
+		return false
+	}
+	if f.Category != nil && (sub.Category == nil || *sub.Category != *f.Category) {
+		return false
+	}
+	if f.PriceMin != nil && sub.Price < *f.PriceMin {
+		return false
+	}
+	if f.PriceMax != nil && sub.Price > *f.PriceMax {
+		return false
+	}
+	// пересечение с периодом: подписка началась до его конца и не закончилась до его начала
+	if f.ActiveFrom != nil && sub.EndDate != nil && sub.EndDate.Before(*f.ActiveFrom) {
+		return false
+	}
+	if f.ActiveTo != nil && sub.StartDate.After(*f.ActiveTo) {
+		return false
+	}
+	if f.StartedAfter != nil && !sub.StartDate.After(*f.StartedAfter) {
+		return false
+	}
+	if f.StartedBefore != nil && !sub.StartDate.Before(*f.StartedBefore) {
+		return false
+	}
+	if f.Q != nil {
+		if _, ok := MatchServiceName(sub.ServiceName, *f.Q); !ok {
+			return false
+		}
+	}
+	if f.Query != nil && !f.Query.Eval(sub) {
+		return false
+	}
+	return true
+}
+
+// SortSubscriptions упорядочивает подписки как ORDER BY поиска в storage: null последними
+// при возрастании и первыми при убывании, при равенстве - по id. Строки сравниваются
+// побайтно, в PostgreSQL их порядок зависит от collation базы.
+func SortSubscriptions(subs []*Subscription, sortBy []SortField) {
+	sortBy = PageSort(sortBy)
+	sort.SliceStable(subs, func(i, j int) bool {
+		return CompareSubscriptions(subs[i], subs[j], sortBy) < 0
+	})
+}
+
+// CompareSubscriptions сравнивает подписки по полям сортировки: -1 если a идет раньше b
+func CompareSubscriptions(a, b *Subscription, sortBy []SortField) int {
+	for _, f := range sortBy {
+		av, aok := subscriptionField(a, f.Field)
+		bv, bok := subscriptionField(b, f.Field)
+		var c int
+		switch {
+		case !aok && !bok:
+			c = 0
+		case !aok:
+			c = 1
+		case !bok:
+			c = -1
+		default:
+			c = compareValues(av, bv)
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch v := a.(type) {
+	case int:
+		return compareInt64(int64(v), int64(b.(int)))
+	case string:
+		return strings.Compare(v, b.(string))
+	case Money:
+		return compareInt64(int64(v), int64(b.(Money)))
+	case time.Time:
+		return v.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
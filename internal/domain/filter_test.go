@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilter_Match(t *testing.T) {
+	video := "video"
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{ID: 1, UserID: "u1", ServiceName: "Yandex Plus", Price: 29900,
+		StartDate: mar, EndDate: &jun, Category: &video}
+	open := &Subscription{ID: 2, UserID: "u1", ServiceName: "Netflix", Price: 99900, StartDate: jan}
+
+	str := func(s string) *string { return &s }
+	money := func(m Money) *Money { return &m }
+	month := func(t time.Time) *time.Time { return &t }
+	price, _ := NewComparison("price", OpGt, "100", false)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		sub    *Subscription
+		want   bool
+	}{
+		{name: "empty", sub: sub, want: true},
+		{name: "user", filter: Filter{UserID: str("u2")}, sub: sub, want: false},
+		{name: "service", filter: Filter{ServiceName: str("Yandex Plus")}, sub: sub, want: true},
+		{name: "price", filter: Filter{Price: money(29900)}, sub: sub, want: true},
+		{name: "start date", filter: Filter{StartDate: month(jan)}, sub: sub, want: false},
+		{name: "end date", filter: Filter{EndDate: month(jun)}, sub: sub, want: true},
+		{name: "end date of open subscription", filter: Filter{EndDate: month(jun)}, sub: open, want: false},
+		{name: "category", filter: Filter{Category: str("video")}, sub: sub, want: true},
+		{name: "category without catalog", filter: Filter{Category: str("video")}, sub: open, want: false},
+		// границы диапазона цены включительно
+		{name: "price min bound", filter: Filter{PriceMin: money(29900)}, sub: sub, want: true},
+		{name: "price max", filter: Filter{PriceMax: money(29899)}, sub: sub, want: false},
+		{name: "active from after end", filter: Filter{ActiveFrom: month(jun.AddDate(0, 1, 0))}, sub: sub, want: false},
+		{name: "active from open subscription", filter: Filter{ActiveFrom: month(jun.AddDate(1, 0, 0))}, sub: open, want: true},
+		{name: "active to before start", filter: Filter{ActiveTo: month(jan)}, sub: sub, want: false},
+		{name: "active to start month", filter: Filter{ActiveTo: month(mar)}, sub: sub, want: true},
+		// StartedAfter и StartedBefore строгие
+		{name: "started after same month", filter: Filter{StartedAfter: month(mar)}, sub: sub, want: false},
+		{name: "started before", filter: Filter{StartedBefore: month(jun)}, sub: sub, want: true},
+		{name: "q", filter: Filter{Q: str("yandx")}, sub: sub, want: true},
+		{name: "q no match", filter: Filter{Q: str("spotify")}, sub: sub, want: false},
+		{name: "query with range", filter: Filter{Query: price, PriceMax: money(50000)}, sub: open, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.sub); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortSubscriptions(t *testing.T) {
+	music := "music"
+	video := "video"
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*Subscription{
+		{ID: 4, ServiceName: "b", Price: 100, StartDate: jan},
+		{ID: 1, ServiceName: "a", Price: 300, StartDate: jan, Category: &video},
+		{ID: 3, ServiceName: "a", Price: 100, StartDate: jan, Category: &music},
+		{ID: 2, ServiceName: "c", Price: 100, StartDate: jan, EndDate: &jan},
+	}
+	ids := func(subs []*Subscription) []int {
+		res := make([]int, len(subs))
+		for i, s := range subs {
+			res[i] = s.ID
+		}
+		return res
+	}
+
+	tests := []struct {
+		name string
+		sort []SortField
+		want []int
+	}{
+		{name: "default by id", want: []int{1, 2, 3, 4}},
+		{name: "price with id tiebreak", sort: []SortField{{Field: "price"}}, want: []int{2, 3, 4, 1}},
+		{name: "desc then asc", sort: []SortField{{Field: "price", Desc: true}, {Field: "service_name"}}, want: []int{1, 3, 4, 2}},
+		// null последними при возрастании и первыми при убывании, как в PostgreSQL
+		{name: "nulls last asc", sort: []SortField{{Field: "category"}}, want: []int{3, 1, 2, 4}},
+		{name: "nulls first desc", sort: []SortField{{Field: "end_date", Desc: true}}, want: []int{1, 3, 4, 2}},
+		{name: "explicit id desc", sort: []SortField{{Field: "id", Desc: true}}, want: []int{4, 3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]*Subscription(nil), subs...)
+			SortSubscriptions(got, tt.sort)
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("SortSubscriptions() = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const (
+	GroupByService = "service"
+	GroupByUser    = "user"
+)
+
+// PriceChange с месяца EffectiveDate подписка стоит Price. Изменения в будущем - запланированные
+type PriceChange struct {
+	EffectiveDate time.Time `json:"effective_date"`
+	Price         Money     `json:"price" swaggertype:"string" example:"349.99"`
+}
+
+type PriceChangeInput struct {
+	UserID        *string `json:"user_id"`
+	ServiceName   *string `json:"service_name"`
+	Price         *Money  `json:"price" swaggertype:"string" example:"349.99"`
+	EffectiveDate *string `json:"effective_date"`
+}
+
+// ForecastMonth прогноз расходов на месяц с разбивкой по сервисам или пользователям
+type ForecastMonth struct {
+	Month time.Time      `json:"month"`
+	Total Money          `json:"total" swaggertype:"string"`
+	Items []ForecastItem `json:"items"`
+}
+
+type ForecastItem struct {
+	Key    string `json:"key"`
+	Amount Money  `json:"amount" swaggertype:"string"`
+}
+
+type PriceChangeRepository interface {
+	// SetPriceChange создает или заменяет изменение цены на месяц change.EffectiveDate
+	SetPriceChange(ctx context.Context, userID, serviceName string, change *PriceChange) error
+	ListPriceChanges(ctx context.Context, userID, serviceName string) ([]PriceChange, error)
+	// DeletePriceChange удаляет изменение и пересчитывает цену подписки на месяц month
+	DeletePriceChange(ctx context.Context, userID, serviceName string, effective, month time.Time) error
+	// ApplyDuePriceChanges записывает в цену подписок последнее изменение, вступившее в силу
+	// не позже month, и возвращает число обновленных подписок
+	ApplyDuePriceChanges(ctx context.Context, month time.Time) (int, error)
+}
+
+// PriceAt цена подписки в месяце month: последнее изменение цены, вступившее в силу
+// не позже month, иначе базовая цена Price
+func (s *Subscription) PriceAt(month time.Time) Money {
+	price := s.Price
+	for _, c := range s.PriceChanges {
+		if c.EffectiveDate.After(month) {
+			break
+		}
+		price = c.Price
+	}
+	return price
+}
+
+// SortPriceChanges упорядочивает изменения цены по дате вступления в силу
+func SortPriceChanges(changes []PriceChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].EffectiveDate.Before(changes[j].EffectiveDate)
+	})
+}
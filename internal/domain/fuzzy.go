@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FuzzyThreshold порог сходства для параметра q, как pg_trgm.word_similarity_threshold по умолчанию
+const FuzzyThreshold = 0.6
+
+type trigram [3]rune
+
+// wordTrigrams триграммы слов строки в порядке следования, как в pg_trgm:
+// нижний регистр, слова из букв и цифр, перед словом два пробела, после - один
+func wordTrigrams(s string) []trigram {
+	var res []trigram
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		padded := append([]rune("  "+w), ' ')
+		for i := 0; i+3 <= len(padded); i++ {
+			res = append(res, trigram{padded[i], padded[i+1], padded[i+2]})
+		}
+	}
+	return res
+}
+
+// WordSimilarity аналог word_similarity(q, s) из pg_trgm: наибольшее сходство триграмм q
+// с непрерывным участком триграмм s. 1 - q целиком входит в s как слово.
+func WordSimilarity(q, s string) float64 {
+	query := make(map[trigram]bool)
+	for _, t := range wordTrigrams(q) {
+		query[t] = true
+	}
+	if len(query) == 0 {
+		return 0
+	}
+
+	target := wordTrigrams(s)
+	best := 0.0
+	for i := range target {
+		seen := make(map[trigram]bool)
+		shared, size := 0, 0
+		for _, t := range target[i:] {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			size++
+			if query[t] {
+				shared++
+			}
+			if sim := float64(shared) / float64(len(query)+size-shared); sim > best {
+				best = sim
+			}
+		}
+	}
+	return best
+}
+
+// MatchServiceName совпадение названия сервиса с запросом q: подстрока без учета регистра
+// или сходство не ниже FuzzyThreshold. score используется для ранжирования.
+func MatchServiceName(name, q string) (score float64, ok bool) {
+	score = WordSimilarity(q, name)
+	if strings.Contains(strings.ToLower(name), strings.ToLower(q)) {
+		return score, true
+	}
+	return score, score >= FuzzyThreshold
+}
+
+// FuzzySearch поиск по q для репозиториев без PostgreSQL: оставляет подходящие подписки
+// и упорядочивает их по убыванию сходства, при равенстве - по ID
+func FuzzySearch(subs []*Subscription, q string) []*Subscription {
+	type scored struct {
+		sub   *Subscription
+		score float64
+	}
+	matched := make([]scored, 0, len(subs))
+	for _, sub := range subs {
+		if score, ok := MatchServiceName(sub.ServiceName, q); ok {
+			matched = append(matched, scored{sub: sub, score: score})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].score != matched[j].score {
+			return matched[i].score > matched[j].score
+		}
+		return matched[i].sub.ID < matched[j].sub.ID
+	})
+	res := make([]*Subscription, len(matched))
+	for i, m := range matched {
+		res[i] = m.sub
+	}
+	return res
+}
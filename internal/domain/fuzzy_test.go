@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWordSimilarity(t *testing.T) {
+	tests := []struct {
+		q, s string
+		want float64
+	}{
+		// пример из документации pg_trgm
+		{q: "word", s: "two words", want: 0.8},
+		{q: "netflix", s: "Netflix Premium", want: 1},
+		{q: "", s: "Netflix", want: 0},
+	}
+	for _, tt := range tests {
+		if got := WordSimilarity(tt.q, tt.s); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("WordSimilarity(%q, %q) = %v, want %v", tt.q, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	subs := []*Subscription{
+		{ID: 1, ServiceName: "Spotify"},
+		{ID: 2, ServiceName: "Netflix Premium"},
+		{ID: 3, ServiceName: "Yandex Plus"},
+		{ID: 4, ServiceName: "Netflix"},
+		{ID: 5, ServiceName: "Apple TV"},
+		{ID: 6, ServiceName: "Yandex Music"},
+	}
+
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		// опечатка
+		{q: "netflx", want: []int{2, 4}},
+		// одинаковое сходство упорядочено по ID
+		{q: "Yandx", want: []int{3, 6}},
+		// часть названия
+		{q: "tv", want: []int{5}},
+		{q: "hbo", want: nil},
+	}
+	for _, tt := range tests {
+		got := FuzzySearch(subs, tt.q)
+		ids := make([]int, 0, len(got))
+		for _, sub := range got {
+			ids = append(ids, sub.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("FuzzySearch(%q) = %v, want %v", tt.q, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("FuzzySearch(%q) = %v, want %v", tt.q, ids, tt.want)
+				break
+			}
+		}
+	}
+}
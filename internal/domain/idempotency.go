@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MaxIdempotencyKeyLength наибольшая длина заголовка Idempotency-Key
+const MaxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyKeyReused ключ уже использован для запроса с другим телом или адресом
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with different request")
+	// ErrIdempotencyInProgress запрос с этим ключом еще выполняется
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// IdempotentResponse сохраненный ответ на запрос с ключом идемпотентности
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyRecord ключ идемпотентности. Response пустой, пока первый запрос выполняется.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Response    *IdempotentResponse
+	ExpiresAt   time.Time
+}
+
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey занимает ключ на ttl. Ключ свободен, если его нет, срок хранения истек
+	// или запрос с ним не завершился за lockTimeout - тогда возвращается nil. Иначе возвращается
+	// существующая запись.
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (*IdempotencyRecord, error)
+	SaveIdempotentResponse(ctx context.Context, key string, resp *IdempotentResponse) error
+	// DeleteIdempotencyKey освобождает ключ, например после ошибки сервера, чтобы запрос можно было повторить
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Виды фоновых задач
+const (
+	JobExport  = "export"  // выгрузка подписок в CSV, параметры как у GET /api/subscriptions/export
+	JobSummary = "summary" // отчет за период, параметры как у GET /api/reports
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+var (
+	// ErrInvalidJob неизвестный вид задачи или неверные параметры
+	ErrInvalidJob  = errors.New("invalid job")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobNotReady = errors.New("job result is not ready")
+)
+
+// Job фоновая задача. Params - параметры запроса в виде строк, разбираются при выполнении.
+// Progress - число обработанных подписок.
+type Job struct {
+	ID          string            `json:"id"`
+	Kind        string            `json:"kind"`
+	Params      map[string]string `json:"params"`
+	Status      string            `json:"status"`
+	Progress    int               `json:"progress"`
+	Attempts    int               `json:"attempts"`
+	Error       *string           `json:"error,omitempty"`
+	ContentType *string           `json:"content_type,omitempty"`
+	ResultSize  *int              `json:"result_size,omitempty"`
+	ResultURL   string            `json:"result_url,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+}
+
+// JobResult файл с результатом выполненной задачи
+type JobResult struct {
+	ContentType string
+	FileName    string
+	Data        []byte
+}
+
+type JobRepository interface {
+	// CreateJob ставит задачу в очередь, заполняет ID, Status и CreatedAt
+	CreateJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id string) (*Job, error)
+	GetJobResult(ctx context.Context, id string) (*JobResult, error)
+	// ClaimJob забирает самую старую задачу из очереди или задачу, воркер которой не обновлял
+	// heartbeat дольше stale, и меньше maxAttempts попыток. Пустая очередь - nil без ошибки.
+	ClaimJob(ctx context.Context, stale time.Duration, maxAttempts int) (*Job, error)
+	// UpdateJobProgress сохраняет прогресс и обновляет heartbeat выполняемой задачи
+	UpdateJobProgress(ctx context.Context, id string, progress int) error
+	CompleteJob(ctx context.Context, id string, progress int, result *JobResult) error
+	FailJob(ctx context.Context, id string, message string) error
+	// ReleaseJob возвращает задачу в очередь без учета попытки, например при остановке сервера
+	ReleaseJob(ctx context.Context, id string) error
+	// FailStaleJobs завершает с ошибкой зависшие задачи, у которых не осталось попыток
+	FailStaleJobs(ctx context.Context, stale time.Duration, maxAttempts int) (int64, error)
+	// DeleteFinishedJobs удаляет завершенные до before задачи вместе с результатами
+	DeleteFinishedJobs(ctx context.Context, before time.Time) (int64, error)
+}
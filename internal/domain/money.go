@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale число знаков после запятой: суммы хранятся в копейках
+const MoneyScale = 2
+
+const moneyUnit = 100
+
+var ErrMoneyOverflow = errors.New("money amount overflow")
+
+// Money денежная сумма в минимальных единицах валюты (копейках).
+// В JSON кодируется десятичной строкой "299.99", на вход принимает и строку, и число.
+type Money int64
+
+// ParseMoney разбирает десятичную запись суммы, допускается не более MoneyScale знаков после точки
+func ParseMoney(s string) (Money, error) {
+	str := strings.TrimSpace(s)
+	neg := strings.HasPrefix(str, "-")
+	str = strings.TrimPrefix(str, "-")
+
+	intPart, fracPart, hasDot := strings.Cut(str, ".")
+	if intPart == "" || (hasDot && fracPart == "") || len(fracPart) > MoneyScale {
+		return 0, fmt.Errorf("invalid amount %q, expected decimal with up to %d fraction digits", s, MoneyScale)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("invalid amount %q, expected decimal with up to %d fraction digits", s, MoneyScale)
+			}
+		}
+	}
+	fracPart += strings.Repeat("0", MoneyScale-len(fracPart))
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, ErrMoneyOverflow
+	}
+	minor, _ := strconv.ParseInt(fracPart, 10, 64)
+	if units > (math.MaxInt64-minor)/moneyUnit {
+		return 0, ErrMoneyOverflow
+	}
+	m := Money(units*moneyUnit + minor)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := uint64(m)
+	if m < 0 {
+		sign = "-"
+		v = uint64(-(m + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyUnit, v%moneyUnit)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.String() + `"`), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		str = unquoted
+	}
+	parsed, err := ParseMoney(str)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Add складывает суммы с проверкой переполнения
+func (m Money) Add(o Money) (Money, error) {
+	if (o > 0 && m > math.MaxInt64-o) || (o < 0 && m < math.MinInt64-o) {
+		return 0, ErrMoneyOverflow
+	}
+	return m + o, nil
+}
+
+// MulDiv возвращает m*num/den с округлением до ближайшей копейки, промежуточный результат не переполняется
+func (m Money) MulDiv(num, den int64) (Money, error) {
+	if den == 0 {
+		return 0, errors.New("division by zero")
+	}
+	r := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num))
+	r.Mul(r, big.NewInt(2))
+	r.Add(r, big.NewInt(den))
+	r.Div(r, new(big.Int).Mul(big.NewInt(den), big.NewInt(2)))
+	if !r.IsInt64() {
+		return 0, ErrMoneyOverflow
+	}
+	return Money(r.Int64()), nil
+}
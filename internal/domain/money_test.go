@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{name: "integer", input: "300", want: 30000},
+		{name: "two digits", input: "299.99", want: 29999},
+		{name: "one digit", input: "0.5", want: 50},
+		{name: "negative", input: "-1.25", want: -125},
+		{name: "too many digits", input: "1.999", wantErr: true},
+		{name: "empty fraction", input: "1.", wantErr: true},
+		{name: "exponent", input: "1e3", wantErr: true},
+		{name: "garbage", input: "abc", wantErr: true},
+		{name: "overflow", input: "92233720368547758.08", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var input struct {
+		Str Money `json:"str"`
+		Num Money `json:"num"`
+		Int Money `json:"int"`
+	}
+	if err := json.Unmarshal([]byte(`{"str":"299.99","num":299.99,"int":300}`), &input); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if input.Str != 29999 || input.Num != 29999 || input.Int != 30000 {
+		t.Errorf("Unmarshal() got = %+v", input)
+	}
+
+	out, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(out) != `{"str":"299.99","num":"299.99","int":"300.00"}` {
+		t.Errorf("Marshal() got = %s", out)
+	}
+}
+
+func TestMoney_Overflow(t *testing.T) {
+	if _, err := Money(math.MaxInt64).Add(1); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("Add() error = %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := Money(math.MaxInt64).MulDiv(3, 2); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("MulDiv() error = %v, want ErrMoneyOverflow", err)
+	}
+	got, err := Money(math.MaxInt64).MulDiv(1, 3)
+	if err != nil || got != Money(math.MaxInt64/3) {
+		t.Errorf("MulDiv() got = %v, error = %v", got, err)
+	}
+	if Money(math.MinInt64).String() != "-92233720368547758.08" {
+		t.Errorf("String() got = %s", Money(math.MinInt64).String())
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage событие, записанное в outbox вместе с изменением данных.
+// Seq номер публикации, его присваивает relay; поток событий упорядочен по нему, а не по id.
+type OutboxMessage struct {
+	ID          int64           `json:"id"`
+	Seq         int64           `json:"seq,omitempty"`
+	EventType   string          `json:"event_type"`
+	UserID      *string         `json:"user_id,omitempty"`
+	ServiceName *string         `json:"service_name,omitempty"`
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// EventSink получатель событий из outbox. Доставка не реже одного раза:
+// одно и то же сообщение может быть передано повторно, получатель должен это учитывать.
+type EventSink interface {
+	Publish(ctx context.Context, msg *OutboxMessage) error
+}
+
+type OutboxFilter struct {
+	UserID     *string
+	AfterSeq   int64
+	EventTypes []string
+	Limit      int
+}
+
+type OutboxRepository interface {
+	// ListOutbox возвращает опубликованные сообщения журнала событий с номером публикации
+	// больше AfterSeq по возрастанию номера
+	ListOutbox(ctx context.Context, filter *OutboxFilter) ([]*OutboxMessage, error)
+	// LastOutboxSeq возвращает наибольший номер публикации события пользователя, 0 если событий нет
+	LastOutboxSeq(ctx context.Context, userID string) (int64, error)
+	// RelayOutbox блокирует до limit неопубликованных сообщений, присваивает им номера публикации,
+	// по порядку передает их publish и отмечает опубликованными. На первой ошибке обработка прекращается, оставшиеся
+	// сообщения будут переданы при следующем вызове. Возвращает число опубликованных.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, msg *OutboxMessage) error) (int, error)
+}
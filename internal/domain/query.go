@@ -0,0 +1,217 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Операторы сравнения в выражении фильтра
+const (
+	OpEq       = "="
+	OpNe       = "!="
+	OpLt       = "<"
+	OpLe       = "<="
+	OpGt       = ">"
+	OpGe       = ">="
+	OpContains = "~" // подстрока без учета регистра
+)
+
+const (
+	FieldString = iota
+	FieldMoney
+	FieldMonth
+)
+
+// QueryField поле подписки, доступное в выражении фильтра.
+// Nullable поле можно сравнивать с null.
+type QueryField struct {
+	Type     int
+	Nullable bool
+}
+
+// QueryFields белый список полей выражения фильтра, другие поля запрещены
+var QueryFields = map[string]QueryField{
+	"user_id":      {Type: FieldString},
+	"service_name": {Type: FieldString},
+	"category":     {Type: FieldString, Nullable: true},
+	"price":        {Type: FieldMoney},
+	"start_date":   {Type: FieldMonth},
+	"end_date":     {Type: FieldMonth, Nullable: true},
+}
+
+// queryMonthForms форматы месяца в выражении: 2025-01 и принятый в API 01-2025
+var queryMonthForms = []string{"2006-01", dateForm}
+
+// Expr выражение фильтра подписок. Eval вычисляет выражение для подписки в памяти
+// и дает тот же результат, что и SQL, в который выражение компилирует storage.
+type Expr interface {
+	Eval(sub *Subscription) bool
+}
+
+type AndExpr struct {
+	Left, Right Expr
+}
+
+type OrExpr struct {
+	Left, Right Expr
+}
+
+type NotExpr struct {
+	Expr Expr
+}
+
+// Comparison сравнение поля со значением. Value имеет тип поля (string, Money,
+// time.Time начала месяца) или nil для сравнения с null.
+type Comparison struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// NewComparison проверяет поле и оператор по белому списку и приводит literal к типу поля.
+// quoted - значение было в кавычках, такое значение не может быть null.
+func NewComparison(field, op, literal string, quoted bool) (*Comparison, error) {
+	spec, ok := QueryFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	cmp := &Comparison{Field: field, Op: op}
+
+	if !quoted && literal == "null" {
+		if !spec.Nullable {
+			return nil, fmt.Errorf("field %q cannot be null", field)
+		}
+		if op != OpEq && op != OpNe {
+			return nil, fmt.Errorf("null can only be compared with = or !=")
+		}
+		return cmp, nil
+	}
+
+	switch spec.Type {
+	case FieldString:
+		if op != OpEq && op != OpNe && op != OpContains {
+			return nil, fmt.Errorf("operator %s is not supported for field %q", op, field)
+		}
+		if len(literal) > 255 {
+			return nil, fmt.Errorf("value for field %q must not exceed 255 characters", field)
+		}
+		cmp.Value = literal
+	case FieldMoney:
+		if op == OpContains {
+			return nil, fmt.Errorf("operator %s is not supported for field %q", op, field)
+		}
+		price, err := ParseMoney(literal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %q: %v", field, err)
+		}
+		cmp.Value = price
+	case FieldMonth:
+		if op == OpContains {
+			return nil, fmt.Errorf("operator %s is not supported for field %q", op, field)
+		}
+		month, err := parseQueryMonth(literal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %q, expected YYYY-MM", field)
+		}
+		cmp.Value = month
+	}
+	return cmp, nil
+}
+
+func parseQueryMonth(s string) (time.Time, error) {
+	var err error
+	for _, form := range queryMonthForms {
+		var t time.Time
+		if t, err = time.Parse(form, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func (e *AndExpr) Eval(sub *Subscription) bool {
+	return e.Left.Eval(sub) && e.Right.Eval(sub)
+}
+
+func (e *OrExpr) Eval(sub *Subscription) bool {
+	return e.Left.Eval(sub) || e.Right.Eval(sub)
+}
+
+func (e *NotExpr) Eval(sub *Subscription) bool {
+	return !e.Expr.Eval(sub)
+}
+
+// Eval поле со значением null не удовлетворяет ни одному сравнению, кроме "= null"
+func (c *Comparison) Eval(sub *Subscription) bool {
+	value, ok := subscriptionField(sub, c.Field)
+	if c.Value == nil {
+		return ok == (c.Op == OpNe)
+	}
+	if !ok {
+		return false
+	}
+
+	switch v := value.(type) {
+	case string:
+		want := c.Value.(string)
+		switch c.Op {
+		case OpEq:
+			return v == want
+		case OpNe:
+			return v != want
+		case OpContains:
+			return strings.Contains(strings.ToLower(v), strings.ToLower(want))
+		}
+	case Money:
+		return compareOrdered(c.Op, int64(v), int64(c.Value.(Money)))
+	case time.Time:
+		return compareOrdered(c.Op, MonthStart(v).Unix(), c.Value.(time.Time).Unix())
+	}
+	return false
+}
+
+// subscriptionField значение поля подписки из белого списка, ok=false если поле равно null
+func subscriptionField(sub *Subscription, field string) (interface{}, bool) {
+	switch field {
+	case "id":
+		return sub.ID, true
+	case "user_id":
+		return sub.UserID, true
+	case "service_name":
+		return sub.ServiceName, true
+	case "category":
+		if sub.Category == nil {
+			return nil, false
+		}
+		return *sub.Category, true
+	case "price":
+		return sub.Price, true
+	case "start_date":
+		return sub.StartDate, true
+	case "end_date":
+		if sub.EndDate == nil {
+			return nil, false
+		}
+		return *sub.EndDate, true
+	}
+	return nil, false
+}
+
+func compareOrdered(op string, a, b int64) bool {
+	switch op {
+	case OpEq:
+		return a == b
+	case OpNe:
+		return a != b
+	case OpLt:
+		return a < b
+	case OpLe:
+		return a <= b
+	case OpGt:
+		return a > b
+	case OpGe:
+		return a >= b
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComparison_Eval(t *testing.T) {
+	music := "music"
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	spotify := &Subscription{UserID: "u1", ServiceName: "Yandex Music", Price: 29900, StartDate: jan,
+		EndDate: &jun, Category: &music}
+	netflix := &Subscription{UserID: "u1", ServiceName: "Netflix", Price: 99900, StartDate: jun}
+
+	cmp := func(field, op, literal string) Expr {
+		c, err := NewComparison(field, op, literal, false)
+		if err != nil {
+			t.Fatalf("NewComparison(%s %s %s): %v", field, op, literal, err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name string
+		expr Expr
+		sub  *Subscription
+		want bool
+	}{
+		{name: "price ge", expr: cmp("price", OpGe, "299"), sub: spotify, want: true},
+		{name: "price lt", expr: cmp("price", OpLt, "299"), sub: spotify, want: false},
+		{name: "month lt", expr: cmp("start_date", OpLt, "2024-02"), sub: spotify, want: true},
+		{name: "month api form", expr: cmp("start_date", OpEq, "06-2024"), sub: netflix, want: true},
+		{name: "contains ignores case", expr: cmp("service_name", OpContains, "yandex"), sub: spotify, want: true},
+		{name: "is null", expr: cmp("end_date", OpEq, "null"), sub: netflix, want: true},
+		{name: "is not null", expr: cmp("end_date", OpNe, "null"), sub: netflix, want: false},
+		// null не равен и не не-равен значению, как в SQL
+		{name: "null ne value", expr: cmp("category", OpNe, "music"), sub: netflix, want: false},
+		{name: "not of null comparison", expr: &NotExpr{Expr: cmp("end_date", OpLt, "2025-01")}, sub: netflix, want: true},
+		{name: "and", expr: &AndExpr{Left: cmp("price", OpGe, "300"), Right: cmp("start_date", OpLt, "2025-01")},
+			sub: netflix, want: true},
+		{name: "or", expr: &OrExpr{Left: cmp("price", OpGe, "1000"), Right: cmp("category", OpEq, "music")},
+			sub: spotify, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expr.Eval(tt.sub); got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewComparison_Whitelist(t *testing.T) {
+	tests := []struct {
+		field, op, literal string
+	}{
+		{field: "id", op: OpEq, literal: "1"},
+		{field: "user_id", op: OpLt, literal: "a"},
+		{field: "price", op: OpContains, literal: "1"},
+		{field: "price", op: OpEq, literal: "abc"},
+		{field: "price", op: OpEq, literal: "null"},
+		{field: "end_date", op: OpLt, literal: "null"},
+		{field: "start_date", op: OpGt, literal: "2025-13"},
+	}
+	for _, tt := range tests {
+		if _, err := NewComparison(tt.field, tt.op, tt.literal, false); err == nil {
+			t.Errorf("NewComparison(%s %s %s) expected error", tt.field, tt.op, tt.literal)
+		}
+	}
+	// строка в кавычках не считается null
+	if c, err := NewComparison("category", OpEq, "null", true); err != nil || c.Value != "null" {
+		t.Errorf("quoted null = %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// ReminderRenewal ближайшее списание по подписке
+	ReminderRenewal = "renewal"
+	// ReminderEnding последнее списание перед окончанием подписки
+	ReminderEnding = "ending"
+)
+
+const (
+	ChannelLog     = "log"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// NotificationPreferences настройки напоминаний пользователя. DaysBefore - за сколько дней
+// до списания напоминать, без него используется значение из конфигурации.
+type NotificationPreferences struct {
+	UserID     string  `json:"user_id"`
+	Channel    string  `json:"channel"`
+	Email      *string `json:"email,omitempty"`
+	WebhookURL *string `json:"webhook_url,omitempty"`
+	DaysBefore *int    `json:"days_before,omitempty"`
+	Renewal    bool    `json:"renewal"`
+	Ending     bool    `json:"ending"`
+}
+
+// Reminder напоминание о предстоящем списании
+type Reminder struct {
+	SubscriptionID int    `json:"subscription_id"`
+	Kind           string `json:"kind"`
+	Charge
+}
+
+// Notifier канал доставки напоминаний
+type Notifier interface {
+	Notify(ctx context.Context, prefs *NotificationPreferences, reminder *Reminder) error
+}
+
+type ReminderRepository interface {
+	SetNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error
+	GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error)
+	ListNotificationPreferences(ctx context.Context) ([]*NotificationPreferences, error)
+	DeleteNotificationPreferences(ctx context.Context, userID string) error
+	// ClaimReminder отмечает напоминание отправленным, claimed=false если оно уже было отправлено
+	ClaimReminder(ctx context.Context, reminder *Reminder) (claimed bool, err error)
+	// ReleaseReminder снимает отметку, если напоминание не удалось доставить
+	ReleaseReminder(ctx context.Context, reminder *Reminder) error
+}
+
+// DueReminder напоминание о ближайшем списании, если оно наступит не позже now+window.
+// Если списание последнее, вместо renewal создается ending. nil - напоминать не о чем
+// или пользователь отключил напоминания этого вида.
+func (s *Subscription) DueReminder(now time.Time, window time.Duration, prefs *NotificationPreferences) *Reminder {
+	next := s.NextCharge(now)
+	if next == nil || next.After(now.Add(window)) {
+		return nil
+	}
+	kind := ReminderRenewal
+	if s.EndDate != nil && next.Equal(MonthStart(*s.EndDate)) {
+		kind = ReminderEnding
+	}
+	if (kind == ReminderRenewal && !prefs.Renewal) || (kind == ReminderEnding && !prefs.Ending) {
+		return nil
+	}
+	price, discount := s.ChargeAt(*next)
+	return &Reminder{
+		SubscriptionID: s.ID,
+		Kind:           kind,
+		Charge: Charge{
+			UserID:      s.UserID,
+			ServiceName: s.ServiceName,
+			ChargeDate:  *next,
+			Amount:      price - discount,
+			Discount:    discount,
+		},
+	}
+}
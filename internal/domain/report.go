@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Форматы отчета
+const (
+	ReportJSON = "json"
+	ReportXLSX = "xlsx"
+	ReportPDF  = "pdf"
+)
+
+// ReportContentTypes MIME типы форматов отчета
+var ReportContentTypes = map[string]string{
+	ReportJSON: "application/json",
+	ReportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ReportPDF:  "application/pdf",
+}
+
+// ReportLine вклад подписки в сводку за период: Months - оплачиваемые месяцы в периоде,
+// суммы с учетом доли пользователя, если отчет построен по user_id
+type ReportLine struct {
+	SubscriptionID int          `json:"subscription_id"`
+	UserID         string       `json:"user_id"`
+	ServiceName    string       `json:"service_name"`
+	Category       *string      `json:"category,omitempty"`
+	Months         int          `json:"months"`
+	Gross          Money        `json:"gross" swaggertype:"string"`
+	Discount       Money        `json:"discount" swaggertype:"string"`
+	Total          Money        `json:"total" swaggertype:"string"`
+	Tax            TaxBreakdown `json:"tax"`
+}
+
+// Report сводка по подпискам за период с разбивкой по подпискам. Summary совпадает
+// с ответом POST /api/subscriptions/summary для того же фильтра.
+type Report struct {
+	StartDate   time.Time    `json:"start_date"`
+	EndDate     time.Time    `json:"end_date"`
+	UserID      *string      `json:"user_id,omitempty"`
+	ServiceName *string      `json:"service_name,omitempty"`
+	Category    *string      `json:"category,omitempty"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Summary     Summary      `json:"summary"`
+	Lines       []ReportLine `json:"lines"`
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "context"
+
+// TaxRate ставка налога региона в базисных пунктах: 2000 = 20%
+type TaxRate struct {
+	Region string `json:"region"`
+	RateBP int    `json:"rate_bp"`
+}
+
+// TaxBreakdown суммы без налога, налог и суммы с налогом
+type TaxBreakdown struct {
+	Net   Money `json:"net" swaggertype:"string"`
+	Tax   Money `json:"tax" swaggertype:"string"`
+	Gross Money `json:"gross" swaggertype:"string"`
+}
+
+type TaxRepository interface {
+	SetTaxRate(ctx context.Context, rate *TaxRate) error
+	ListTaxRates(ctx context.Context) ([]*TaxRate, error)
+	DeleteTaxRate(ctx context.Context, region string) error
+}
+
+// SplitTax раскладывает сумму на net/tax/gross. Для цен с налогом налог выделяется
+// из суммы, для цен без налога начисляется сверху. Округление до ближайшей копейки.
+func SplitTax(amount Money, rateBP int, inclusive bool) (TaxBreakdown, error) {
+	if inclusive {
+		net, err := amount.MulDiv(10000, int64(10000+rateBP))
+		if err != nil {
+			return TaxBreakdown{}, err
+		}
+		return TaxBreakdown{Net: net, Tax: amount - net, Gross: amount}, nil
+	}
+	tax, err := amount.MulDiv(int64(rateBP), 10000)
+	if err != nil {
+		return TaxBreakdown{}, err
+	}
+	gross, err := amount.Add(tax)
+	if err != nil {
+		return TaxBreakdown{}, err
+	}
+	return TaxBreakdown{Net: amount, Tax: tax, Gross: gross}, nil
+}
+
+// Add складывает разбивки с проверкой переполнения
+func (b TaxBreakdown) Add(o TaxBreakdown) (TaxBreakdown, error) {
+	var err error
+	if b.Net, err = b.Net.Add(o.Net); err != nil {
+		return TaxBreakdown{}, err
+	}
+	if b.Tax, err = b.Tax.Add(o.Tax); err != nil {
+		return TaxBreakdown{}, err
+	}
+	if b.Gross, err = b.Gross.Add(o.Gross); err != nil {
+		return TaxBreakdown{}, err
+	}
+	return b, nil
+}
+
+// RateBP ставка налога подписки, 0 если регион не задан или для него нет ставки
+func (s *Subscription) RateBP() int {
+	if s.TaxRate == nil {
+		return 0
+	}
+	return s.TaxRate.RateBP
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventCreated     = "subscription.created"
+	EventUpdated     = "subscription.updated"
+	EventDeleted     = "subscription.deleted"
+	EventExpiring    = "subscription.expiring"
+	EventBudgetAlert = "budget.alert"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Event доменное событие, отправляется во внешние системы
+type Event struct {
+	Type        string      `json:"type"`
+	UserID      string      `json:"user_id,omitempty"`
+	ServiceName string      `json:"service_name,omitempty"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+	Data        interface{} `json:"data,omitempty"`
+}
+
+// WebhookEndpoint получатель событий. Пустой Events - подписка на все события.
+// Secret используется для HMAC-подписи и возвращается только при создании.
+type WebhookEndpoint struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery попытки доставки события одному получателю: зарегистрированному (EndpointID)
+// или адресу webhook_url бюджета (BudgetID)
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	EndpointID    *int            `json:"endpoint_id,omitempty"`
+	BudgetID      *int            `json:"budget_id,omitempty"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  *int            `json:"response_code,omitempty"`
+	LastError     *string         `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+
+	// адрес и секрет получателя, заполняются при выборке на отправку; у доставок бюджета секрета нет
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type WebhookDeliveryFilter struct {
+	EndpointID *int
+	Status     *string
+	Limit      *int
+}
+
+type WebhookRepository interface {
+	CreateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error
+	ListWebhookEndpoints(ctx context.Context) ([]*WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id int) error
+	// EnqueueWebhookDeliveries создает доставки события всем активным получателям, подписанным на eventType
+	EnqueueWebhookDeliveries(ctx context.Context, eventType string, payload []byte) error
+	// ClaimDueDeliveries забирает доставки, время попытки которых наступило, и откладывает их на lease,
+	// чтобы они не были отправлены повторно, если процесс упадет до сохранения результата
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	SaveDeliveryAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, filter *WebhookDeliveryFilter) ([]*WebhookDelivery, error)
+	// ReplayWebhookDelivery ставит доставку в очередь на повторную отправку
+	ReplayWebhookDelivery(ctx context.Context, id int64) error
+	// PublishExpiringSubscriptions отмечает подписки с end_date в [from, to], о скором окончании
+	// которых еще не сообщалось, и в той же транзакции записывает для них в outbox
+	// subscription.expiring. Возвращает число отмеченных подписок.
+	PublishExpiringSubscriptions(ctx context.Context, from, to time.Time) (int, error)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"math"
+)
+
+func (s *SubServiceImpl) ActiveByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ActiveMonth, error) {
+	res, err := s.repo.ActiveByMonth(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to get active subscriptions", "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *SubServiceImpl) ARPUByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ARPUMonth, error) {
+	res, err := s.repo.ARPUByMonth(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to get ARPU", "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *SubServiceImpl) ChurnByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ChurnMonth, error) {
+	res, err := s.repo.ChurnByMonth(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to get churn", "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// Cohorts доля удержания округляется до 4 знаков
+func (s *SubServiceImpl) Cohorts(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error) {
+	cohorts, err := s.repo.Cohorts(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to get cohorts", "error", err)
+		return nil, err
+	}
+	for _, c := range cohorts {
+		c.Retention = make([]float64, len(c.Retained))
+		if c.Size == 0 {
+			continue
+		}
+		for i, n := range c.Retained {
+			c.Retention[i] = math.Round(float64(n)/float64(c.Size)*10000) / 10000
+		}
+	}
+	return cohorts, nil
+}
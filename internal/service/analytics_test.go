@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cohortRepo struct {
+	mockRepo
+	cohortsFunc func(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error)
+}
+
+func (m *cohortRepo) Cohorts(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error) {
+	if m.cohortsFunc != nil {
+		return m.cohortsFunc(ctx, filter)
+	}
+	return nil, nil
+}
+
+func TestSubServiceImpl_Cohorts(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &cohortRepo{
+		cohortsFunc: func(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error) {
+			return []*domain.Cohort{{Cohort: jan, Size: 3, Retained: []int{3, 2, 1}}}, nil
+		},
+	}
+	service := NewService(repo)
+
+	got, err := service.Cohorts(context.Background(), &domain.AnalyticsFilter{StartDate: jan, EndDate: jan.AddDate(0, 2, 0)})
+	if err != nil {
+		t.Fatalf("Cohorts() error = %v", err)
+	}
+	want := []float64{1, 0.6667, 0.3333}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Retention, want) {
+		t.Errorf("Cohorts() retention = %v, want %v", got[0].Retention, want)
+	}
+}
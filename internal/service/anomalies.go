@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultAnomalyOutlierPercent = 30
+	defaultAnomalyJumpPercent    = 20
+)
+
+func (s *SubServiceImpl) ListPriceAnomalies(ctx context.Context, filter *domain.PriceAnomalyFilter) ([]*domain.PriceAnomaly, error) {
+	anomalies, err := s.repo.ListPriceAnomalies(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to list price anomalies", "error", err)
+		return nil, err
+	}
+	return anomalies, nil
+}
+
+// RunAnomalyDetector ищет аномалии цен сразу при запуске и затем каждые interval до отмены ctx
+func (s *SubServiceImpl) RunAnomalyDetector(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.DetectPriceAnomalies(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Price anomaly detection failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetectPriceAnomalies сравнивает цены подписок текущего месяца с рекомендованными ценами
+// справочника, медианой цен других пользователей и собственной историей цен.
+// Новые аномалии сохраняются вместе с событием price.anomaly, уже известные пропускаются.
+func (s *SubServiceImpl) DetectPriceAnomalies(ctx context.Context) error {
+	now := s.now()
+	month := domain.MonthStart(now)
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &domain.Filter{StartDate: &month, EndDate: &month})
+	if err != nil {
+		return err
+	}
+	catalog, err := s.repo.ListCatalog(ctx)
+	if err != nil {
+		return err
+	}
+	listPrices := make(map[string]domain.Money)
+	for _, svc := range catalog {
+		if svc.ListPrice != nil {
+			listPrices[svc.ServiceName] = *svc.ListPrice
+		}
+	}
+
+	anomalies, err := domain.DetectPriceAnomalies(subs, listPrices, now, s.anomalyOutlierPercent, s.anomalyJumpPercent)
+	if err != nil {
+		return err
+	}
+	for _, anomaly := range anomalies {
+		created, err := s.repo.SavePriceAnomaly(ctx, anomaly)
+		if err != nil {
+			return err
+		}
+		if created {
+			slog.Info("Price anomaly detected", "subscription_id", anomaly.SubscriptionID, "kind", anomaly.Kind,
+				"price", anomaly.Price, "reference_price", anomaly.ReferencePrice, "reference", anomaly.Reference)
+		}
+	}
+	return nil
+}
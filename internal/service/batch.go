@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+// ApplyBatch выполняет пакет операций с подписками. В режиме atomic при ошибке операции
+// возвращается ответ с результатами и ошибка domain.ErrBatchAborted.
+func (s *SubServiceImpl) ApplyBatch(ctx context.Context, mode string, ops []domain.BatchOperation) (*domain.BatchResponse, error) {
+	results, err := s.repo.ApplyBatch(ctx, ops, mode == domain.BatchAtomic, domain.MonthStart(s.now()))
+	if err != nil && !errors.Is(err, domain.ErrBatchAborted) {
+		slog.Error("Failed to apply batch", "error", err)
+		return nil, err
+	}
+
+	resp := &domain.BatchResponse{Mode: mode, Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case domain.BatchOK:
+			resp.Succeeded++
+		case domain.BatchFailed:
+			resp.Failed++
+		}
+	}
+	if err != nil {
+		slog.Warn("Batch aborted", "failed", resp.Failed)
+	}
+	return resp, err
+}
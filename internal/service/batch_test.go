@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"testing"
+	"time"
+)
+
+type batchRepo struct {
+	mockRepo
+	applyBatchFunc func(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchResult, error)
+}
+
+func (m *batchRepo) ApplyBatch(ctx context.Context, ops []domain.BatchOperation, atomic bool, month time.Time) ([]domain.BatchResult, error) {
+	if m.applyBatchFunc != nil {
+		return m.applyBatchFunc(ctx, ops, atomic)
+	}
+	return nil, nil
+}
+
+func TestSubServiceImpl_ApplyBatch(t *testing.T) {
+	ops := []domain.BatchOperation{
+		{Op: domain.BatchCreate, Subscription: &domain.Subscription{UserID: "u1", ServiceName: "Netflix"}},
+		{Op: domain.BatchDelete, UserID: "u1", ServiceName: "Spotify"},
+		{Op: domain.BatchDelete, UserID: "u1", ServiceName: "Kinopoisk"},
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		repoErr       error
+		wantErr       error
+		wantSucceeded int
+		wantFailed    int
+	}{
+		{name: "partial", mode: domain.BatchPartial, wantSucceeded: 2, wantFailed: 1},
+		{name: "atomic aborted", mode: domain.BatchAtomic, repoErr: domain.ErrBatchAborted, wantErr: domain.ErrBatchAborted,
+			wantSucceeded: 0, wantFailed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &batchRepo{
+				applyBatchFunc: func(ctx context.Context, got []domain.BatchOperation, atomic bool) ([]domain.BatchResult, error) {
+					if atomic != (tt.mode == domain.BatchAtomic) {
+						t.Errorf("atomic = %v for mode %s", atomic, tt.mode)
+					}
+					if tt.repoErr != nil {
+						return []domain.BatchResult{
+							{Index: 0, Op: domain.BatchCreate, Status: domain.BatchRolledBack},
+							{Index: 1, Op: domain.BatchDelete, Status: domain.BatchFailed, Error: "subscription not found"},
+							{Index: 2, Op: domain.BatchDelete, Status: domain.BatchSkipped},
+						}, tt.repoErr
+					}
+					return []domain.BatchResult{
+						{Index: 0, Op: domain.BatchCreate, Status: domain.BatchOK, ID: 7},
+						{Index: 1, Op: domain.BatchDelete, Status: domain.BatchFailed, Error: "subscription not found"},
+						{Index: 2, Op: domain.BatchDelete, Status: domain.BatchOK},
+					}, nil
+				},
+			}
+			resp, err := NewService(repo).ApplyBatch(context.Background(), tt.mode, ops)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if resp.Succeeded != tt.wantSucceeded || resp.Failed != tt.wantFailed {
+				t.Errorf("succeeded/failed = %d/%d, want %d/%d", resp.Succeeded, resp.Failed, tt.wantSucceeded, tt.wantFailed)
+			}
+		})
+	}
+}
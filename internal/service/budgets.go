@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+func (s *SubServiceImpl) CreateBudget(ctx context.Context, budget *domain.Budget) error {
+	if err := s.repo.CreateBudget(ctx, budget); err != nil {
+		slog.Error("Failed to create budget", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) UpdateBudget(ctx context.Context, budget *domain.Budget) error {
+	if err := s.repo.UpdateBudget(ctx, budget); err != nil {
+		slog.Error("Failed to update budget", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListBudgets(ctx context.Context, userID *string) ([]*domain.Budget, error) {
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to list budgets", "error", err)
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (s *SubServiceImpl) DeleteBudget(ctx context.Context, id int) error {
+	if err := s.repo.DeleteBudget(ctx, id); err != nil {
+		slog.Error("Failed to delete budget", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListBudgetAlerts(ctx context.Context, filter *domain.BudgetAlertFilter) ([]*domain.BudgetAlert, error) {
+	alerts, err := s.repo.ListBudgetAlerts(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to list budget alerts", "error", err)
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// RunBudgetEvaluator проверяет бюджеты сразу при запуске и затем каждые interval до отмены ctx
+func (s *SubServiceImpl) RunBudgetEvaluator(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.EvaluateBudgets(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Budget evaluation failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateBudgets сравнивает расходы текущего месяца (actual) и прогноз на следующий месяц
+// (forecast) с порогами бюджетов. Расходы считаются тем же расчетом, что и сводка
+// GetSubscriptionsSummary. Оповещение о каждом пороге создается один раз за месяц.
+// Ошибка проверки одного бюджета не останавливает проверку остальных.
+func (s *SubServiceImpl) EvaluateBudgets(ctx context.Context) error {
+	budgets, err := s.repo.ListBudgets(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	current := domain.MonthStart(s.now())
+	checks := []struct {
+		kind  string
+		month time.Time
+	}{
+		{kind: domain.AlertActual, month: current},
+		{kind: domain.AlertForecast, month: current.AddDate(0, 1, 0)},
+	}
+
+	failed := 0
+	for _, budget := range budgets {
+		for _, check := range checks {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			month := check.month
+			summary, err := s.GetSubscriptionsSummary(ctx, &domain.Filter{
+				UserID:    budget.UserID,
+				Category:  budget.Category,
+				StartDate: &month,
+				EndDate:   &month,
+			})
+			if err == nil {
+				err = s.checkThresholds(ctx, budget, check.kind, month, summary.TotalPrice)
+			}
+			if err != nil {
+				failed++
+				slog.Error("Budget check failed", "budget_id", budget.ID, "kind", check.kind, "error", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d budget checks failed", failed, len(budgets)*len(checks))
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) checkThresholds(ctx context.Context, budget *domain.Budget, kind string, month time.Time, spend domain.Money) error {
+	for _, threshold := range budget.Thresholds {
+		limit, err := budget.Amount.MulDiv(int64(threshold), 100)
+		if err != nil {
+			return err
+		}
+		if spend < limit {
+			continue
+		}
+		alert := &domain.BudgetAlert{
+			BudgetID:     budget.ID,
+			UserID:       budget.UserID,
+			Category:     budget.Category,
+			Month:        month,
+			Kind:         kind,
+			Threshold:    threshold,
+			Spend:        spend,
+			BudgetAmount: budget.Amount,
+		}
+		created, err := s.repo.SaveBudgetAlert(ctx, alert)
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+		slog.Info("Budget threshold crossed", "budget_id", budget.ID, "kind", kind, "threshold", threshold, "spend", spend)
+	}
+	return nil
+}
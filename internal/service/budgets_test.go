@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type budgetRepo struct {
+	mockRepo
+	listBudgetsFunc     func(ctx context.Context, userID *string) ([]*domain.Budget, error)
+	saveBudgetAlertFunc func(ctx context.Context, alert *domain.BudgetAlert) (bool, error)
+}
+
+func (m *budgetRepo) ListBudgets(ctx context.Context, userID *string) ([]*domain.Budget, error) {
+	if m.listBudgetsFunc != nil {
+		return m.listBudgetsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *budgetRepo) SaveBudgetAlert(ctx context.Context, alert *domain.BudgetAlert) (bool, error) {
+	if m.saveBudgetAlertFunc != nil {
+		return m.saveBudgetAlertFunc(ctx, alert)
+	}
+	return true, nil
+}
+
+func TestSubServiceImpl_EvaluateBudgets(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	brokenUUID := "223e4567-e89b-12d3-a456-426614174000"
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	// бюджет, расходы по которому не считаются, идет первым и не мешает проверке остальных
+	broken := &domain.Budget{ID: 1, UserID: &brokenUUID, Amount: 100000, Thresholds: []int{80}}
+	budget := &domain.Budget{ID: 2, UserID: &validUUID, Amount: 100000, Thresholds: []int{80, 100}}
+	var created []*domain.BudgetAlert
+	saved := make(map[string]bool)
+	repo := &budgetRepo{
+		mockRepo: mockRepo{
+			getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+				if *filter.UserID == brokenUUID {
+					return nil, errors.New("db error")
+				}
+				// в январе 900 из 1000, с февраля цена растет до 1100
+				return []*domain.Subscription{{
+					UserID:       validUUID,
+					ServiceName:  "Netflix",
+					Price:        90000,
+					StartDate:    jan,
+					PriceChanges: []domain.PriceChange{{EffectiveDate: feb, Price: 110000}},
+				}}, nil
+			},
+		},
+		listBudgetsFunc: func(ctx context.Context, userID *string) ([]*domain.Budget, error) {
+			return []*domain.Budget{broken, budget}, nil
+		},
+		saveBudgetAlertFunc: func(ctx context.Context, alert *domain.BudgetAlert) (bool, error) {
+			key := fmt.Sprintf("%d-%s-%s-%d", alert.BudgetID, alert.Kind, alert.Month.Format(time.DateOnly), alert.Threshold)
+			if saved[key] {
+				return false, nil
+			}
+			saved[key] = true
+			created = append(created, alert)
+			return true, nil
+		},
+	}
+	service := NewService(repo)
+	service.now = func() time.Time { return now }
+
+	// повторная проверка не должна дублировать оповещения
+	for i := 0; i < 2; i++ {
+		err := service.EvaluateBudgets(context.Background())
+		if err == nil || err.Error() != "2 of 4 budget checks failed" {
+			t.Fatalf("EvaluateBudgets() error = %v, want 2 of 4 budget checks failed", err)
+		}
+	}
+
+	type key struct {
+		budgetID  int
+		kind      string
+		month     time.Time
+		threshold int
+	}
+	got := make(map[key]domain.Money)
+	for _, a := range created {
+		got[key{a.BudgetID, a.Kind, a.Month, a.Threshold}] = a.Spend
+	}
+	want := map[key]domain.Money{
+		{2, domain.AlertActual, jan, 80}:    90000,
+		{2, domain.AlertForecast, feb, 80}:  110000,
+		{2, domain.AlertForecast, feb, 100}: 110000,
+	}
+	if !reflect.DeepEqual(got, want) || len(created) != len(want) {
+		t.Errorf("EvaluateBudgets() created = %+v", created)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+// calendarPastMonths за сколько месяцев назад в календарь попадают закончившиеся подписки
+const calendarPastMonths = 12
+
+// CreateCalendarToken выдает пользователю новый токен ссылки на календарь, прежний перестает действовать.
+// Токен возвращается один раз, в БД хранится только его хэш.
+func (s *SubServiceImpl) CreateCalendarToken(ctx context.Context, userID string) (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err = s.repo.SetCalendarToken(ctx, userID, calendarTokenHash(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *SubServiceImpl) DeleteCalendarToken(ctx context.Context, userID string) error {
+	return s.repo.DeleteCalendarToken(ctx, userID)
+}
+
+// Calendar календарь списаний пользователя в формате iCalendar, доступный по токену.
+// Неверный токен - domain.ErrCalendarToken.
+func (s *SubServiceImpl) Calendar(ctx context.Context, userID, token string) (string, error) {
+	ok, err := s.repo.CheckCalendarToken(ctx, userID, calendarTokenHash(token))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", domain.ErrCalendarToken
+	}
+
+	// подписки пользователя, в том числе совместные, действующие сейчас, будущие
+	// и закончившиеся за последний год
+	now := s.now()
+	from := domain.MonthStart(now).AddDate(0, -calendarPastMonths, 0)
+	to := time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &domain.Filter{UserID: &userID, StartDate: &from, EndDate: &to})
+	if err != nil {
+		return "", err
+	}
+	calendar, err := domain.BuildCalendar(subs, userID, now)
+	if err != nil {
+		slog.Error("Failed to build calendar", "user_id", userID, "error", err)
+		return "", err
+	}
+	return calendar, nil
+}
+
+func calendarTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
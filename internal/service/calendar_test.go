@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"strings"
+	"testing"
+)
+
+type calendarRepo struct {
+	mockRepo
+	checkCalendarTokenFunc func(ctx context.Context, userID, tokenHash string) (bool, error)
+}
+
+func (m *calendarRepo) CheckCalendarToken(ctx context.Context, userID, tokenHash string) (bool, error) {
+	if m.checkCalendarTokenFunc != nil {
+		return m.checkCalendarTokenFunc(ctx, userID, tokenHash)
+	}
+	return false, nil
+}
+
+func TestSubServiceImpl_Calendar(t *testing.T) {
+	const token = "secret"
+	repo := &calendarRepo{
+		checkCalendarTokenFunc: func(ctx context.Context, userID, tokenHash string) (bool, error) {
+			return tokenHash == calendarTokenHash(token), nil
+		},
+	}
+	service := NewService(repo)
+	if _, err := service.Calendar(context.Background(), "u1", "wrong"); !errors.Is(err, domain.ErrCalendarToken) {
+		t.Errorf("wrong token: err = %v, want ErrCalendarToken", err)
+	}
+	calendar, err := service.Calendar(context.Background(), "u1", token)
+	if err != nil {
+		t.Fatalf("Calendar() error = %v", err)
+	}
+	if !strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\n") {
+		t.Errorf("calendar = %q", calendar)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+func (s *SubServiceImpl) SetCatalogService(ctx context.Context, svc *domain.CatalogService) error {
+	if err := s.repo.SetCatalogService(ctx, svc); err != nil {
+		slog.Error("Failed to save catalog service", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListCatalog(ctx context.Context) ([]*domain.CatalogService, error) {
+	catalog, err := s.repo.ListCatalog(ctx)
+	if err != nil {
+		slog.Error("Failed to list catalog", "error", err)
+		return nil, err
+	}
+	return catalog, nil
+}
+
+func (s *SubServiceImpl) DeleteCatalogService(ctx context.Context, serviceName string) error {
+	if err := s.repo.DeleteCatalogService(ctx, serviceName); err != nil {
+		slog.Error("Failed to delete catalog service", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"sort"
+)
+
+// UpcomingCharges списания по подпискам в ближайшие days дней. При фильтре по пользователю
+// сумма списания пересчитывается на его долю в совместной подписке.
+func (s *SubServiceImpl) UpcomingCharges(ctx context.Context, filter *domain.Filter, days int) ([]*domain.Charge, error) {
+	now := s.now()
+	windowEnd := now.AddDate(0, 0, days)
+
+	periodStart := domain.MonthStart(now)
+	periodEnd := domain.MonthStart(windowEnd)
+	periodFilter := *filter
+	periodFilter.StartDate = &periodStart
+	periodFilter.EndDate = &periodEnd
+
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &periodFilter)
+	if err != nil {
+		slog.Error("Failed to get subscriptions for upcoming charges", "error", err)
+		return nil, err
+	}
+
+	charges := make([]*domain.Charge, 0)
+	for _, sub := range subs {
+		for date := sub.NextCharge(now); date != nil && !date.After(windowEnd); date = sub.NextCharge(date.AddDate(0, 0, 1)) {
+			price, discount := sub.ChargeAt(*date)
+			amount := price - discount
+			if filter.UserID != nil {
+				num, den := sub.ShareOf(*filter.UserID)
+				if amount, err = amount.MulDiv(int64(num), int64(den)); err != nil {
+					return nil, err
+				}
+				if discount, err = discount.MulDiv(int64(num), int64(den)); err != nil {
+					return nil, err
+				}
+			}
+			if amount == 0 && discount == 0 {
+				continue
+			}
+			charges = append(charges, &domain.Charge{
+				UserID:      sub.UserID,
+				ServiceName: sub.ServiceName,
+				ChargeDate:  *date,
+				Amount:      amount,
+				Discount:    discount,
+			})
+		}
+	}
+
+	sort.SliceStable(charges, func(i, j int) bool {
+		if !charges[i].ChargeDate.Equal(charges[j].ChargeDate) {
+			return charges[i].ChargeDate.Before(charges[j].ChargeDate)
+		}
+		return charges[i].ServiceName < charges[j].ServiceName
+	})
+	return charges, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubServiceImpl_UpcomingCharges(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	endFeb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	couponStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	subs := []*domain.Subscription{
+		{
+			UserID:      validUUID,
+			ServiceName: "Netflix",
+			Price:       10000,
+			StartDate:   time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:     &endFeb,
+		},
+		{
+			UserID:      validUUID,
+			ServiceName: "Spotify",
+			Price:       20000,
+			StartDate:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			Coupon:      &domain.Coupon{Code: "FIX", Kind: domain.CouponFixed, Amount: 5000, DurationMonths: 1},
+			CouponStart: &couponStart,
+		},
+	}
+
+	var gotFilter *domain.Filter
+	repo := &mockRepo{
+		getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+			gotFilter = filter
+			return subs, nil
+		},
+	}
+	service := NewService(repo)
+	service.now = func() time.Time { return now }
+
+	got, err := service.UpcomingCharges(context.Background(), &domain.Filter{}, 90)
+	if err != nil {
+		t.Fatalf("UpcomingCharges() error = %v", err)
+	}
+	if !gotFilter.StartDate.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		!gotFilter.EndDate.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("UpcomingCharges() period = %v..%v", gotFilter.StartDate, gotFilter.EndDate)
+	}
+
+	want := []*domain.Charge{
+		{UserID: validUUID, ServiceName: "Netflix", ChargeDate: endFeb, Amount: 10000},
+		{UserID: validUUID, ServiceName: "Spotify", ChargeDate: couponStart, Amount: 15000, Discount: 5000},
+		{UserID: validUUID, ServiceName: "Spotify", ChargeDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Amount: 20000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, c := range got {
+			t.Logf("got %+v", *c)
+		}
+		t.Errorf("UpcomingCharges() mismatch")
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+func (s *SubServiceImpl) CreateCoupon(ctx context.Context, coupon *domain.Coupon) error {
+	if err := s.repo.CreateCoupon(ctx, coupon); err != nil {
+		slog.Error("Failed to create coupon", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListCoupons(ctx context.Context) ([]*domain.Coupon, error) {
+	coupons, err := s.repo.ListCoupons(ctx)
+	if err != nil {
+		slog.Error("Failed to list coupons", "error", err)
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (s *SubServiceImpl) DeleteCoupon(ctx context.Context, code string) error {
+	if err := s.repo.DeleteCoupon(ctx, code); err != nil {
+		slog.Error("Failed to delete coupon", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) AttachCoupon(ctx context.Context, userID, serviceName string, code *string, start *time.Time) error {
+	if err := s.repo.AttachCoupon(ctx, userID, serviceName, code, start); err != nil {
+		slog.Error("Failed to attach coupon", "error", err)
+		return err
+	}
+	return nil
+}
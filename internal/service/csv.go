@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"log/slog"
+	"sort"
+)
+
+// csvFlushRows через сколько строк выгрузка сбрасывается в w
+const csvFlushRows = 100
+
+// ExportCSV пишет в w подписки по фильтру в формате CSV с заголовком domain.CSVColumns.
+// Подписки читаются из БД построчно и сразу записываются, выгрузка не собирается в памяти.
+func (s *SubServiceImpl) ExportCSV(ctx context.Context, w io.Writer, filter *domain.Filter) error {
+	return s.exportCSV(ctx, w, filter, nil)
+}
+
+// exportCSV выгрузка с вызовом progress после каждой записанной подписки
+func (s *SubServiceImpl) exportCSV(ctx context.Context, w io.Writer, filter *domain.Filter, progress func(rows int)) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(domain.CSVColumns); err != nil {
+		return err
+	}
+	rows := 0
+	err := s.repo.StreamSubscriptions(ctx, filter, func(sub *domain.Subscription) error {
+		if err := cw.Write(domain.CSVRecord(sub)); err != nil {
+			return err
+		}
+		rows++
+		if progress != nil {
+			progress(rows)
+		}
+		if rows%csvFlushRows == 0 {
+			cw.Flush()
+			return cw.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to export subscriptions", "rows", rows, "error", err)
+		return err
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return err
+	}
+	slog.Info("Subscriptions exported", "rows", rows)
+	return nil
+}
+
+// ImportCSV создает или обновляет подписки из CSV с заголовком. Строки с ошибками пропускаются
+// и попадают в отчет, остальные записываются. При dryRun ничего не сохраняется.
+// Ошибка err - файл не удалось разобрать или сбой БД.
+func (s *SubServiceImpl) ImportCSV(ctx context.Context, r io.Reader, dryRun bool) (*domain.ImportReport, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: header is required", domain.ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidCSV, err)
+	}
+	columns, err := domain.ParseCSVHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidCSV, err)
+	}
+	cr.FieldsPerRecord = len(header)
+
+	report := &domain.ImportReport{DryRun: dryRun, Errors: []domain.ImportRowError{}}
+	var subs []*domain.Subscription
+	var lines []int
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Rows++
+		if report.Rows > domain.MaxImportRows {
+			return nil, domain.ErrImportTooLarge
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			// строка с другим числом полей не мешает читать следующие, остальные ошибки разбора - мешают
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || !errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				return nil, fmt.Errorf("%w: %w", domain.ErrInvalidCSV, err)
+			}
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: parseErr.Line, Error: "wrong number of fields"})
+			continue
+		}
+
+		input, err := domain.ParseCSVRecord(columns, record)
+		if err == nil {
+			err = input.Validate()
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		subs = append(subs, domain.NewSubscription(input.SubscriptionToOptions()...))
+		lines = append(lines, line)
+	}
+
+	if len(subs) > 0 {
+		results, err := s.repo.ImportSubscriptions(ctx, subs, dryRun, domain.MonthStart(s.now()))
+		if err != nil {
+			slog.Error("Failed to import subscriptions", "error", err)
+			return nil, err
+		}
+		for i, res := range results {
+			switch res.Status {
+			case domain.ImportCreated:
+				report.Created++
+			case domain.ImportUpdated:
+				report.Updated++
+			case domain.ImportFailed:
+				report.Errors = append(report.Errors, domain.ImportRowError{Row: lines[i], Error: res.Error})
+			}
+		}
+	}
+	report.Failed = len(report.Errors)
+	// ошибки проверки и записи идут в порядке строк файла
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+
+	slog.Info("Subscriptions import finished", "rows", report.Rows, "created", report.Created,
+		"updated", report.Updated, "failed", report.Failed, "dry_run", dryRun)
+	return report, nil
+}
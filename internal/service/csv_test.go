@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvRepo struct {
+	mockRepo
+	streamSubscriptionsFunc func(ctx context.Context, filter *domain.Filter, fn func(*domain.Subscription) error) error
+	importSubscriptionsFunc func(ctx context.Context, subs []*domain.Subscription, dryRun bool) ([]domain.ImportResult, error)
+}
+
+func (m *csvRepo) StreamSubscriptions(ctx context.Context, filter *domain.Filter, fn func(*domain.Subscription) error) error {
+	if m.streamSubscriptionsFunc != nil {
+		return m.streamSubscriptionsFunc(ctx, filter, fn)
+	}
+	return nil
+}
+
+func (m *csvRepo) ImportSubscriptions(ctx context.Context, subs []*domain.Subscription, dryRun bool, month time.Time) ([]domain.ImportResult, error) {
+	if m.importSubscriptionsFunc != nil {
+		return m.importSubscriptionsFunc(ctx, subs, dryRun)
+	}
+	return nil, nil
+}
+
+func TestSubServiceImpl_ImportCSV(t *testing.T) {
+	const uid = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	input := "user_id,service_name,price,start_date,end_date\n" +
+		uid + ",Netflix,599.00,01-2025,\n" +
+		uid + ",Spotify,abc,01-2025,\n" +
+		uid + ",Yandex Plus,299,02-2025,12-2025\n" +
+		uid + ",Kinopoisk\n" +
+		uid + ",Okko,199,13-2025,\n"
+
+	var gotDryRun bool
+	repo := &csvRepo{
+		importSubscriptionsFunc: func(ctx context.Context, subs []*domain.Subscription, dryRun bool) ([]domain.ImportResult, error) {
+			gotDryRun = dryRun
+			if len(subs) != 2 || subs[0].ServiceName != "Netflix" || subs[1].EndDate == nil {
+				t.Fatalf("unexpected subscriptions %+v", subs)
+			}
+			return []domain.ImportResult{
+				{Status: domain.ImportCreated, ID: 1},
+				{Status: domain.ImportFailed, Error: "constraint violation: subscriptions_price_check"},
+			}, nil
+		},
+	}
+
+	report, err := NewService(repo).ImportCSV(context.Background(), strings.NewReader(input), true)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if !gotDryRun || !report.DryRun {
+		t.Error("dry run was not passed to repository")
+	}
+	if report.Rows != 5 || report.Created != 1 || report.Updated != 0 || report.Failed != 4 {
+		t.Errorf("report = %+v", report)
+	}
+	wantRows := []int{3, 4, 5, 6}
+	for i, e := range report.Errors {
+		if e.Row != wantRows[i] {
+			t.Errorf("errors[%d].row = %d, want %d (%s)", i, e.Row, wantRows[i], e.Error)
+		}
+	}
+
+	_, err = NewService(repo).ImportCSV(context.Background(), strings.NewReader("user_id,price\n"), false)
+	if !errors.Is(err, domain.ErrInvalidCSV) {
+		t.Errorf("missing column: err = %v, want ErrInvalidCSV", err)
+	}
+}
+
+func TestSubServiceImpl_ExportCSV(t *testing.T) {
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &csvRepo{
+		streamSubscriptionsFunc: func(ctx context.Context, filter *domain.Filter, fn func(*domain.Subscription) error) error {
+			for _, sub := range []*domain.Subscription{
+				{UserID: "u1", ServiceName: "Netflix, HD", Price: 59900, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), TaxInclusive: true},
+				{UserID: "u2", ServiceName: "Spotify", Price: 29900, StartDate: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), EndDate: &end},
+			} {
+				if err := fn(sub); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	var b strings.Builder
+	if err := NewService(repo).ExportCSV(context.Background(), &b, &domain.Filter{}); err != nil {
+		t.Fatalf("ExportCSV() error = %v", err)
+	}
+	want := "user_id,service_name,price,start_date,end_date,tax_inclusive,tax_region,category\n" +
+		"u1,\"Netflix, HD\",599.00,01-2025,,true,,\n" +
+		"u2,Spotify,299.00,02-2025,06-2025,false,,\n"
+	if b.String() != want {
+		t.Errorf("ExportCSV() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
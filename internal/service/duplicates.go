@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+// FindDuplicates ищет дубликаты среди подписок, действующих в периоде фильтра.
+// Без дат проверяется текущий месяц, без даты окончания - один месяц начала.
+func (s *SubServiceImpl) FindDuplicates(ctx context.Context, filter *domain.Filter) (*domain.DuplicateReport, error) {
+	now := s.now()
+	period := *filter
+	if period.StartDate == nil {
+		start := domain.MonthStart(now)
+		period.StartDate = &start
+	}
+	if period.EndDate == nil {
+		period.EndDate = period.StartDate
+	}
+
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &period)
+	if err != nil {
+		slog.Error("Failed to get subscriptions for duplicates", "error", err)
+		return nil, err
+	}
+	report, err := domain.FindDuplicates(subs, filter.UserID, now)
+	if err != nil {
+		slog.Error("Failed to find duplicates", "error", err)
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+const (
+	eventsPageSize           = 500
+	defaultEventPollInterval = time.Second
+)
+
+// streamEventTypes события, которые получают клиенты потока изменений подписок
+var streamEventTypes = []string{domain.EventCreated, domain.EventUpdated, domain.EventDeleted}
+
+// WithEventPollInterval как часто поток изменений подписок проверяет журнал outbox на новые события
+func WithEventPollInterval(interval time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.eventPollInterval = interval
+	}
+}
+
+// CloseEventStreams закрывает все потоки событий, вызывается при остановке сервера
+func (s *SubServiceImpl) CloseEventStreams() {
+	s.closeStreams.Do(func() { close(s.streamsDone) })
+}
+
+// StreamEvents возвращает поток изменений подписок пользователя в порядке публикации (Seq).
+// Поток читает зафиксированный журнал outbox, поэтому события видны независимо от того,
+// какой процесс их опубликовал. Если lastEventID больше нуля, сначала отдаются пропущенные
+// события с номером публикации больше lastEventID, иначе поток начинается с текущего конца журнала.
+// Канал закрывается при отмене ctx, ошибке чтения журнала или CloseEventStreams.
+func (s *SubServiceImpl) StreamEvents(ctx context.Context, userID string, lastEventID int64) (<-chan *domain.OutboxMessage, error) {
+	last := lastEventID
+	if last <= 0 {
+		var err error
+		if last, err = s.repo.LastOutboxSeq(ctx, userID); err != nil {
+			slog.Error("Failed to load event log position", "user_id", userID, "error", err)
+			return nil, err
+		}
+	}
+
+	out := make(chan *domain.OutboxMessage)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(s.eventPollInterval)
+		defer ticker.Stop()
+		for {
+			// relay фиксирует номера публикации по возрастанию, поэтому после last
+			// не может появиться событие с меньшим номером
+			page, err := s.listEvents(ctx, userID, last)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to load event log", "user_id", userID, "error", err)
+				}
+				return
+			}
+			for _, m := range page {
+				select {
+				case out <- m:
+					last = m.Seq
+				case <-ctx.Done():
+					return
+				case <-s.streamsDone:
+					return
+				}
+			}
+			// полная страница - журнал дочитывается без ожидания
+			if len(page) == eventsPageSize {
+				continue
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-s.streamsDone:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *SubServiceImpl) listEvents(ctx context.Context, userID string, afterSeq int64) ([]*domain.OutboxMessage, error) {
+	return s.repo.ListOutbox(ctx, &domain.OutboxFilter{
+		UserID:     &userID,
+		AfterSeq:   afterSeq,
+		EventTypes: streamEventTypes,
+		Limit:      eventsPageSize,
+	})
+}
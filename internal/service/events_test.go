@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type eventRepo struct {
+	mockRepo
+	listOutboxFunc    func(ctx context.Context, filter *domain.OutboxFilter) ([]*domain.OutboxMessage, error)
+	lastOutboxSeqFunc func(ctx context.Context, userID string) (int64, error)
+}
+
+func (m *eventRepo) ListOutbox(ctx context.Context, filter *domain.OutboxFilter) ([]*domain.OutboxMessage, error) {
+	if m.listOutboxFunc != nil {
+		return m.listOutboxFunc(ctx, filter)
+	}
+	return nil, nil
+}
+
+func (m *eventRepo) LastOutboxSeq(ctx context.Context, userID string) (int64, error) {
+	if m.lastOutboxSeqFunc != nil {
+		return m.lastOutboxSeqFunc(ctx, userID)
+	}
+	return 0, nil
+}
+
+func TestSubServiceImpl_StreamEvents(t *testing.T) {
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	message := func(id, seq int64, eventType string) *domain.OutboxMessage {
+		return &domain.OutboxMessage{ID: id, Seq: seq, UserID: &userID, EventType: eventType}
+	}
+
+	// журнал в памяти: события 5 и 6 уже зафиксированы, транзакция с id 3 опубликована позже с номером 9
+	var mu sync.Mutex
+	log := []*domain.OutboxMessage{message(5, 5, domain.EventCreated), message(6, 6, domain.EventUpdated)}
+	var afterSeqs []int64
+	repo := &eventRepo{
+		listOutboxFunc: func(ctx context.Context, filter *domain.OutboxFilter) ([]*domain.OutboxMessage, error) {
+			if *filter.UserID != userID || !reflect.DeepEqual(filter.EventTypes, streamEventTypes) {
+				t.Errorf("unexpected filter %+v", filter)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			afterSeqs = append(afterSeqs, filter.AfterSeq)
+			var res []*domain.OutboxMessage
+			for _, m := range log {
+				if m.Seq > filter.AfterSeq {
+					res = append(res, m)
+				}
+			}
+			return res, nil
+		},
+		lastOutboxSeqFunc: func(ctx context.Context, id string) (int64, error) {
+			return 6, nil
+		},
+	}
+	service := NewService(repo, WithEventPollInterval(10*time.Millisecond))
+
+	receive := func(events <-chan *domain.OutboxMessage, n int) []int64 {
+		var got []int64
+		for len(got) < n {
+			select {
+			case m := <-events:
+				got = append(got, m.ID)
+			case <-time.After(time.Second):
+				t.Fatalf("timeout, got %v", got)
+			}
+		}
+		return got
+	}
+
+	// переподключение с Last-Event-ID получает пропущенное событие 6, затем новое из журнала
+	resumed, err := service.StreamEvents(context.Background(), userID, 5)
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	// без Last-Event-ID поток начинается с конца журнала
+	fresh, err := service.StreamEvents(context.Background(), userID, 0)
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	if got := receive(resumed, 1); !reflect.DeepEqual(got, []int64{6}) {
+		t.Errorf("resumed stream got %v, want [6]", got)
+	}
+
+	mu.Lock()
+	log = append(log, message(3, 9, domain.EventDeleted))
+	mu.Unlock()
+	if got := receive(resumed, 1); !reflect.DeepEqual(got, []int64{3}) {
+		t.Errorf("resumed stream got %v, want [3]", got)
+	}
+	if got := receive(fresh, 1); !reflect.DeepEqual(got, []int64{3}) {
+		t.Errorf("fresh stream got %v, want [3]", got)
+	}
+	mu.Lock()
+	for _, seq := range afterSeqs {
+		if seq < 5 {
+			t.Errorf("journal read after seq %d, want >= 5", seq)
+		}
+	}
+	mu.Unlock()
+
+	// остановка сервера закрывает потоки
+	service.CloseEventStreams()
+	for _, events := range []<-chan *domain.OutboxMessage{resumed, fresh} {
+		select {
+		case _, ok := <-events:
+			if ok {
+				t.Error("expected closed stream after CloseEventStreams")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("stream not closed after CloseEventStreams")
+		}
+	}
+}
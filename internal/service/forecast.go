@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+func (s *SubServiceImpl) SetPriceChange(ctx context.Context, userID, serviceName string, change *domain.PriceChange) error {
+	if err := s.repo.SetPriceChange(ctx, userID, serviceName, change); err != nil {
+		slog.Error("Failed to save price change", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListPriceChanges(ctx context.Context, userID, serviceName string) ([]domain.PriceChange, error) {
+	changes, err := s.repo.ListPriceChanges(ctx, userID, serviceName)
+	if err != nil {
+		slog.Error("Failed to list price changes", "error", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (s *SubServiceImpl) DeletePriceChange(ctx context.Context, userID, serviceName string, effective time.Time) error {
+	if err := s.repo.DeletePriceChange(ctx, userID, serviceName, effective, domain.MonthStart(s.now())); err != nil {
+		slog.Error("Failed to delete price change", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RunPriceChangeApplier раз в interval переносит вступившие в силу изменения цены в цену подписок,
+// чтобы поиск и выгрузки видели текущую цену
+func (s *SubServiceImpl) RunPriceChangeApplier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.repo.ApplyDuePriceChanges(ctx, domain.MonthStart(s.now()))
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Applying price changes failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Price changes applied", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Forecast прогноз расходов на months месяцев начиная со следующего. Бессрочные подписки
+// продлеваются на весь горизонт, учитываются даты окончания, запланированные изменения цены
+// и скидки. При группировке по пользователям стоимость совместной подписки делится между участниками.
+func (s *SubServiceImpl) Forecast(ctx context.Context, filter *domain.Filter, months int, groupBy string) ([]*domain.ForecastMonth, error) {
+	first := domain.MonthStart(s.now()).AddDate(0, 1, 0)
+	last := first.AddDate(0, months-1, 0)
+	periodFilter := *filter
+	periodFilter.StartDate = &first
+	periodFilter.EndDate = &last
+
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &periodFilter)
+	if err != nil {
+		slog.Error("Failed to get subscriptions for forecast", "error", err)
+		return nil, err
+	}
+
+	forecast := make([]*domain.ForecastMonth, 0, months)
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		amounts := make(map[string]domain.Money)
+		for _, sub := range subs {
+			if month.Before(domain.MonthStart(sub.StartDate)) || (sub.EndDate != nil && month.After(domain.MonthStart(*sub.EndDate))) {
+				continue
+			}
+			price, discount := sub.ChargeAt(month)
+			for key, amount := range forecastShares(sub, price-discount, filter.UserID, groupBy) {
+				if amount == 0 {
+					continue
+				}
+				if amounts[key], err = amounts[key].Add(amount); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		fm := &domain.ForecastMonth{Month: month, Items: make([]domain.ForecastItem, 0, len(amounts))}
+		for key, amount := range amounts {
+			if fm.Total, err = fm.Total.Add(amount); err != nil {
+				return nil, err
+			}
+			fm.Items = append(fm.Items, domain.ForecastItem{Key: key, Amount: amount})
+		}
+		sort.Slice(fm.Items, func(i, j int) bool { return fm.Items[i].Key < fm.Items[j].Key })
+		forecast = append(forecast, fm)
+	}
+	return forecast, nil
+}
+
+// forecastShares раскладывает месячную сумму по ключам группировки.
+// Доля не превышает сумму, поэтому ошибки переполнения MulDiv здесь невозможны.
+func forecastShares(sub *domain.Subscription, amount domain.Money, userID *string, groupBy string) map[string]domain.Money {
+	shares := make(map[string]domain.Money)
+	if userID != nil {
+		num, den := sub.ShareOf(*userID)
+		share, _ := amount.MulDiv(int64(num), int64(den))
+		if groupBy == domain.GroupByUser {
+			shares[*userID] = share
+		} else {
+			shares[sub.ServiceName] = share
+		}
+		return shares
+	}
+	if groupBy != domain.GroupByUser {
+		shares[sub.ServiceName] = amount
+		return shares
+	}
+	if len(sub.Members) == 0 {
+		shares[sub.UserID] = amount
+		return shares
+	}
+	for _, m := range sub.Members {
+		num, den := sub.ShareOf(m.UserID)
+		shares[m.UserID], _ = amount.MulDiv(int64(num), int64(den))
+	}
+	return shares
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// priceChangeRepo запоминает расчетные месяцы, переданные сервисом в репозиторий
+type priceChangeRepo struct {
+	mockRepo
+	months                   []time.Time
+	applyDuePriceChangesFunc func(ctx context.Context, month time.Time) (int, error)
+}
+
+func (m *priceChangeRepo) Update(ctx context.Context, input *domain.Subscription, month time.Time) error {
+	m.months = append(m.months, month)
+	return nil
+}
+
+func (m *priceChangeRepo) DeletePriceChange(ctx context.Context, userID, serviceName string, effective, month time.Time) error {
+	m.months = append(m.months, month)
+	return nil
+}
+
+func (m *priceChangeRepo) ApplyDuePriceChanges(ctx context.Context, month time.Time) (int, error) {
+	if m.applyDuePriceChangesFunc != nil {
+		return m.applyDuePriceChangesFunc(ctx, month)
+	}
+	return 0, nil
+}
+
+func TestSubServiceImpl_Forecast(t *testing.T) {
+	owner := "123e4567-e89b-12d3-a456-426614174000"
+	member := "223e4567-e89b-12d3-a456-426614174000"
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	subs := []*domain.Subscription{
+		{
+			// бессрочная семейная подписка, с апреля цена растет
+			UserID:       owner,
+			ServiceName:  "Spotify",
+			Price:        40000,
+			StartDate:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Members:      []domain.Member{{UserID: owner, Share: 1}, {UserID: member, Share: 1}},
+			PriceChanges: []domain.PriceChange{{EffectiveDate: apr, Price: 60000}},
+		},
+		{
+			// заканчивается в марте
+			UserID:      owner,
+			ServiceName: "Netflix",
+			Price:       10000,
+			StartDate:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:     &mar,
+		},
+	}
+	repo := &mockRepo{
+		getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+			return subs, nil
+		},
+	}
+	service := NewService(repo)
+	service.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		filter  *domain.Filter
+		groupBy string
+		want    []*domain.ForecastMonth
+	}{
+		{
+			name:    "by service",
+			filter:  &domain.Filter{},
+			groupBy: domain.GroupByService,
+			want: []*domain.ForecastMonth{
+				{Month: feb, Total: 50000, Items: []domain.ForecastItem{{Key: "Netflix", Amount: 10000}, {Key: "Spotify", Amount: 40000}}},
+				{Month: mar, Total: 50000, Items: []domain.ForecastItem{{Key: "Netflix", Amount: 10000}, {Key: "Spotify", Amount: 40000}}},
+				{Month: apr, Total: 60000, Items: []domain.ForecastItem{{Key: "Spotify", Amount: 60000}}},
+			},
+		},
+		{
+			name:    "by user",
+			filter:  &domain.Filter{},
+			groupBy: domain.GroupByUser,
+			want: []*domain.ForecastMonth{
+				{Month: feb, Total: 50000, Items: []domain.ForecastItem{{Key: owner, Amount: 30000}, {Key: member, Amount: 20000}}},
+				{Month: mar, Total: 50000, Items: []domain.ForecastItem{{Key: owner, Amount: 30000}, {Key: member, Amount: 20000}}},
+				{Month: apr, Total: 60000, Items: []domain.ForecastItem{{Key: owner, Amount: 30000}, {Key: member, Amount: 30000}}},
+			},
+		},
+		{
+			name:    "member share",
+			filter:  &domain.Filter{UserID: &member},
+			groupBy: domain.GroupByService,
+			want: []*domain.ForecastMonth{
+				{Month: feb, Total: 20000, Items: []domain.ForecastItem{{Key: "Spotify", Amount: 20000}}},
+				{Month: mar, Total: 20000, Items: []domain.ForecastItem{{Key: "Spotify", Amount: 20000}}},
+				{Month: apr, Total: 30000, Items: []domain.ForecastItem{{Key: "Spotify", Amount: 30000}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Forecast(context.Background(), tt.filter, 3, tt.groupBy)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				for _, m := range got {
+					t.Logf("got %+v", *m)
+				}
+				t.Errorf("Forecast() mismatch")
+			}
+		})
+	}
+}
+
+func TestSubServiceImpl_RunPriceChangeApplier(t *testing.T) {
+	now := time.Date(2024, 3, 20, 15, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := &priceChangeRepo{}
+	repo.applyDuePriceChangesFunc = func(ctx context.Context, month time.Time) (int, error) {
+		repo.months = append(repo.months, month)
+		cancel()
+		return 1, nil
+	}
+	service := NewService(repo)
+	service.now = func() time.Time { return now }
+
+	done := make(chan struct{})
+	go func() {
+		service.RunPriceChangeApplier(ctx, time.Hour)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPriceChangeApplier did not stop after cancel")
+	}
+	// изменения применяются сразу при запуске, по началу текущего месяца
+	if want := []time.Time{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}; !reflect.DeepEqual(repo.months, want) {
+		t.Errorf("ApplyDuePriceChanges months = %v, want %v", repo.months, want)
+	}
+}
+
+func TestSubServiceImpl_PriceChangeMonth(t *testing.T) {
+	// последний час марта: расчетный месяц берется из часов сервиса, а не из часов базы
+	now := time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &priceChangeRepo{}
+	service := NewService(repo)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	sub := &domain.Subscription{UserID: "123e4567-e89b-12d3-a456-426614174000", ServiceName: "Netflix", Price: 59900}
+	if err := service.UpdateSubscription(ctx, sub); err != nil {
+		t.Fatalf("UpdateSubscription() error = %v", err)
+	}
+	if err := service.DeletePriceChange(ctx, sub.UserID, sub.ServiceName, mar); err != nil {
+		t.Fatalf("DeletePriceChange() error = %v", err)
+	}
+	if want := []time.Time{mar, mar}; !reflect.DeepEqual(repo.months, want) {
+		t.Errorf("repository months = %v, want %v", repo.months, want)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyLockTimeout через сколько незавершенный запрос с ключом считается прерванным
+	// и ключ можно занять снова; больше таймаутов изменяющих запросов
+	idempotencyLockTimeout = time.Minute
+)
+
+// WithIdempotencyTTL сколько хранятся ключи идемпотентности и ответы для повтора
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.idempotencyTTL = ttl
+	}
+}
+
+// BeginIdempotentRequest занимает ключ для запроса с хэшем requestHash. Пустой ответ без ошибки -
+// запрос нужно выполнить и завершить FinishIdempotentRequest или AbortIdempotentRequest.
+// Иначе возвращается сохраненный ответ первого запроса. Ключ с другим запросом -
+// domain.ErrIdempotencyKeyReused, первый запрос еще выполняется - domain.ErrIdempotencyInProgress.
+func (s *SubServiceImpl) BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotentResponse, error) {
+	rec, err := s.repo.ReserveIdempotencyKey(ctx, key, requestHash, s.idempotencyTTL, idempotencyLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, nil
+	}
+	if rec.RequestHash != requestHash {
+		slog.Warn("Idempotency key reused with different request", "key", key)
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if rec.Response == nil {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+	slog.Info("Replaying idempotent response", "key", key, "status", rec.Response.StatusCode)
+	return rec.Response, nil
+}
+
+// FinishIdempotentRequest сохраняет ответ для повторов запроса с ключом
+func (s *SubServiceImpl) FinishIdempotentRequest(ctx context.Context, key string, resp *domain.IdempotentResponse) error {
+	return s.repo.SaveIdempotentResponse(ctx, key, resp)
+}
+
+// AbortIdempotentRequest освобождает ключ, повтор запроса выполнится заново
+func (s *SubServiceImpl) AbortIdempotentRequest(ctx context.Context, key string) error {
+	return s.repo.DeleteIdempotencyKey(ctx, key)
+}
+
+// RunIdempotencyCleanup удаляет просроченные ключи идемпотентности раз в interval
+func (s *SubServiceImpl) RunIdempotencyCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.repo.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Idempotency keys cleanup failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Expired idempotency keys deleted", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
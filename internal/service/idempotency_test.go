@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"testing"
+	"time"
+)
+
+type idempotencyRepo struct {
+	mockRepo
+	reserveIdempotencyKeyFunc func(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (*domain.IdempotencyRecord, error)
+}
+
+func (m *idempotencyRepo) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (*domain.IdempotencyRecord, error) {
+	if m.reserveIdempotencyKeyFunc != nil {
+		return m.reserveIdempotencyKeyFunc(ctx, key, requestHash, ttl, lockTimeout)
+	}
+	return nil, nil
+}
+
+func TestSubServiceImpl_BeginIdempotentRequest(t *testing.T) {
+	saved := &domain.IdempotentResponse{StatusCode: 201, ContentType: "application/json"}
+	records := map[string]*domain.IdempotencyRecord{
+		"done":    {Key: "done", RequestHash: "h1", Response: saved},
+		"running": {Key: "running", RequestHash: "h1"},
+	}
+	var gotTTL time.Duration
+	repo := &idempotencyRepo{
+		reserveIdempotencyKeyFunc: func(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (*domain.IdempotencyRecord, error) {
+			gotTTL = ttl
+			return records[key], nil
+		},
+	}
+	service := NewService(repo, WithIdempotencyTTL(time.Hour))
+
+	tests := []struct {
+		name    string
+		key     string
+		hash    string
+		want    *domain.IdempotentResponse
+		wantErr error
+	}{
+		{name: "new key", key: "new", hash: "h1"},
+		{name: "replay", key: "done", hash: "h1", want: saved},
+		{name: "different request", key: "done", hash: "h2", wantErr: domain.ErrIdempotencyKeyReused},
+		{name: "in progress", key: "running", hash: "h1", wantErr: domain.ErrIdempotencyInProgress},
+		{name: "in progress with different request", key: "running", hash: "h2", wantErr: domain.ErrIdempotencyKeyReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.BeginIdempotentRequest(context.Background(), tt.key, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if gotTTL != time.Hour {
+		t.Errorf("ttl = %s, want 1h", gotTTL)
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultJobResultTTL = 24 * time.Hour
+	// jobHeartbeatInterval как часто выполняемая задача сохраняет прогресс; задачу без heartbeat
+	// дольше jobStaleAfter забирает другой воркер
+	jobHeartbeatInterval = 10 * time.Second
+	jobStaleAfter        = time.Minute
+	jobMaxAttempts       = 3
+	jobTimeout           = 30 * time.Minute
+	jobCleanupInterval   = time.Minute
+	jobReleaseTimeout    = 5 * time.Second
+)
+
+// JobFilterParser разбирает параметры задачи вида kind в фильтр подписок.
+// Параметры те же, что у соответствующего запроса API.
+type JobFilterParser func(kind string, params map[string]string) (*domain.Filter, error)
+
+// WithJobFilterParser разбор параметров фоновых задач, без него задачи не принимаются
+func WithJobFilterParser(parser JobFilterParser) Option {
+	return func(s *SubServiceImpl) {
+		s.jobFilter = parser
+	}
+}
+
+// WithJobResultTTL сколько хранятся завершенные задачи и их результаты
+func WithJobResultTTL(ttl time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.jobResultTTL = ttl
+	}
+}
+
+// EnqueueJob проверяет параметры и ставит задачу в очередь. Неверные параметры - domain.ErrInvalidJob.
+func (s *SubServiceImpl) EnqueueJob(ctx context.Context, kind string, params map[string]string) (*domain.Job, error) {
+	if params == nil {
+		params = map[string]string{}
+	}
+	if _, _, err := s.parseJob(kind, params); err != nil {
+		return nil, err
+	}
+	job := &domain.Job{Kind: kind, Params: params}
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *SubServiceImpl) GetJob(ctx context.Context, id string) (*domain.Job, error) {
+	return s.repo.GetJob(ctx, id)
+}
+
+// JobResult файл с результатом задачи. Задача еще выполняется или завершилась с ошибкой - domain.ErrJobNotReady.
+func (s *SubServiceImpl) JobResult(ctx context.Context, id string) (*domain.JobResult, error) {
+	job, err := s.repo.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case domain.JobDone:
+		return s.repo.GetJobResult(ctx, id)
+	case domain.JobFailed:
+		msg := ""
+		if job.Error != nil {
+			msg = *job.Error
+		}
+		return nil, fmt.Errorf("%w: job failed: %s", domain.ErrJobNotReady, msg)
+	}
+	return nil, fmt.Errorf("%w: job is %s", domain.ErrJobNotReady, job.Status)
+}
+
+// parseJob фильтр и формат результата задачи
+func (s *SubServiceImpl) parseJob(kind string, params map[string]string) (*domain.Filter, string, error) {
+	if s.jobFilter == nil {
+		return nil, "", errors.New("job filter parser is not configured")
+	}
+	var format string
+	switch kind {
+	case domain.JobExport:
+		format = "csv"
+		if f := params["format"]; f != "" && f != format {
+			return nil, "", fmt.Errorf("%w: format must be csv", domain.ErrInvalidJob)
+		}
+	case domain.JobSummary:
+		format = domain.ReportJSON
+		if f := params["format"]; f != "" {
+			if _, ok := domain.ReportContentTypes[f]; !ok {
+				return nil, "", fmt.Errorf("%w: format must be json, xlsx or pdf", domain.ErrInvalidJob)
+			}
+			format = f
+		}
+	default:
+		return nil, "", fmt.Errorf("%w: kind must be export or summary", domain.ErrInvalidJob)
+	}
+	filter, err := s.jobFilter(kind, params)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", domain.ErrInvalidJob, err)
+	}
+	return filter, format, nil
+}
+
+// RunJobWorkers запускает workers воркеров очереди задач, каждый проверяет очередь раз в interval,
+// и удаляет задачи старше срока хранения. Возвращается после отмены ctx и завершения воркеров.
+func (s *SubServiceImpl) RunJobWorkers(ctx context.Context, workers int, interval time.Duration) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runJobWorker(ctx, interval)
+		}()
+	}
+
+	ticker := time.NewTicker(jobCleanupInterval)
+	defer ticker.Stop()
+	for {
+		s.cleanupJobs(ctx)
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SubServiceImpl) runJobWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// задачи выполняются подряд, пока очередь не опустеет
+		for ctx.Err() == nil && s.runNextJob(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNextJob забирает и выполняет одну задачу, false - очередь пуста
+func (s *SubServiceImpl) runNextJob(ctx context.Context) bool {
+	job, err := s.repo.ClaimJob(ctx, jobStaleAfter, jobMaxAttempts)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to claim job", "error", err)
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+	slog.Info("Job started", "id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	var processed atomic.Int64
+	runCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	heartbeatDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatDone:
+				return
+			case <-ticker.C:
+				_ = s.repo.UpdateJobProgress(runCtx, job.ID, int(processed.Load()))
+			}
+		}
+	}()
+	result, err := s.executeJob(runCtx, job, func(n int) { processed.Store(int64(n)) })
+	close(heartbeatDone)
+
+	switch {
+	case ctx.Err() != nil:
+		// сервер останавливается: задача вернется в очередь и выполнится заново
+		releaseCtx, cancelRelease := context.WithTimeout(context.Background(), jobReleaseTimeout)
+		defer cancelRelease()
+		_ = s.repo.ReleaseJob(releaseCtx, job.ID)
+	case err != nil:
+		msg := err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			msg = "job timed out"
+		}
+		_ = s.repo.FailJob(ctx, job.ID, msg)
+	default:
+		_ = s.repo.CompleteJob(ctx, job.ID, int(processed.Load()), result)
+	}
+	return true
+}
+
+// executeJob выполняет задачу, progress получает число обработанных подписок
+func (s *SubServiceImpl) executeJob(ctx context.Context, job *domain.Job, progress func(n int)) (*domain.JobResult, error) {
+	filter, format, err := s.parseJob(job.Kind, job.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if job.Kind == domain.JobExport {
+		if err = s.exportCSV(ctx, &buf, filter, progress); err != nil {
+			return nil, err
+		}
+		return &domain.JobResult{ContentType: "text/csv; charset=utf-8", FileName: "subscriptions.csv", Data: buf.Bytes()}, nil
+	}
+
+	report, err := s.BuildReport(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	progress(len(report.Lines))
+	if err = s.RenderReport(&buf, report, format); err != nil {
+		return nil, err
+	}
+	name := "subscriptions-" + report.StartDate.Format(reportDateForm) + "-" + report.EndDate.Format(reportDateForm) + "." + format
+	return &domain.JobResult{ContentType: domain.ReportContentTypes[format], FileName: name, Data: buf.Bytes()}, nil
+}
+
+// cleanupJobs завершает зависшие задачи без оставшихся попыток и удаляет задачи старше срока хранения
+func (s *SubServiceImpl) cleanupJobs(ctx context.Context) {
+	if n, err := s.repo.FailStaleJobs(ctx, jobStaleAfter, jobMaxAttempts); err == nil && n > 0 {
+		slog.Warn("Stale jobs failed", "count", n)
+	}
+	if n, err := s.repo.DeleteFinishedJobs(ctx, s.now().Add(-s.jobResultTTL)); err == nil && n > 0 {
+		slog.Info("Finished jobs deleted", "count", n)
+	}
+}
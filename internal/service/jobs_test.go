@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jobRepo struct {
+	csvRepo
+	createJobFunc   func(ctx context.Context, job *domain.Job) error
+	getJobFunc      func(ctx context.Context, id string) (*domain.Job, error)
+	claimJobFunc    func(ctx context.Context, stale time.Duration, maxAttempts int) (*domain.Job, error)
+	completeJobFunc func(ctx context.Context, id string, progress int, result *domain.JobResult) error
+	failJobFunc     func(ctx context.Context, id string, message string) error
+}
+
+func (m *jobRepo) CreateJob(ctx context.Context, job *domain.Job) error {
+	if m.createJobFunc != nil {
+		return m.createJobFunc(ctx, job)
+	}
+	return nil
+}
+
+func (m *jobRepo) GetJob(ctx context.Context, id string) (*domain.Job, error) {
+	if m.getJobFunc != nil {
+		return m.getJobFunc(ctx, id)
+	}
+	return nil, domain.ErrJobNotFound
+}
+
+func (m *jobRepo) ClaimJob(ctx context.Context, stale time.Duration, maxAttempts int) (*domain.Job, error) {
+	if m.claimJobFunc != nil {
+		return m.claimJobFunc(ctx, stale, maxAttempts)
+	}
+	return nil, nil
+}
+
+func (m *jobRepo) CompleteJob(ctx context.Context, id string, progress int, result *domain.JobResult) error {
+	if m.completeJobFunc != nil {
+		return m.completeJobFunc(ctx, id, progress, result)
+	}
+	return nil
+}
+
+func (m *jobRepo) FailJob(ctx context.Context, id string, message string) error {
+	if m.failJobFunc != nil {
+		return m.failJobFunc(ctx, id, message)
+	}
+	return nil
+}
+
+func TestSubServiceImpl_Jobs(t *testing.T) {
+	parser := func(kind string, params map[string]string) (*domain.Filter, error) {
+		if kind == domain.JobSummary && params["start_date"] == "" {
+			return nil, errors.New("start_date and end_date are required")
+		}
+		return &domain.Filter{}, nil
+	}
+	var created *domain.Job
+	var completed *domain.JobResult
+	var progress int
+	claimed := false
+	repo := &jobRepo{
+		csvRepo: csvRepo{
+			streamSubscriptionsFunc: func(ctx context.Context, filter *domain.Filter, fn func(*domain.Subscription) error) error {
+				for _, name := range []string{"Netflix", "Spotify"} {
+					sub := domain.NewSubscription(domain.WithUserID("u1"), domain.WithServiceName(name),
+						domain.WithPrice(49900), domain.WithStartDate("01-2025"))
+					if err := fn(sub); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		createJobFunc: func(ctx context.Context, job *domain.Job) error {
+			job.ID, job.Status = "job-1", domain.JobQueued
+			created = job
+			return nil
+		},
+		claimJobFunc: func(ctx context.Context, stale time.Duration, maxAttempts int) (*domain.Job, error) {
+			if claimed || created == nil {
+				return nil, nil
+			}
+			claimed = true
+			return created, nil
+		},
+		completeJobFunc: func(ctx context.Context, id string, n int, result *domain.JobResult) error {
+			completed, progress = result, n
+			return nil
+		},
+		failJobFunc: func(ctx context.Context, id string, message string) error {
+			t.Errorf("job failed: %s", message)
+			return nil
+		},
+		getJobFunc: func(ctx context.Context, id string) (*domain.Job, error) {
+			return &domain.Job{ID: id, Status: domain.JobRunning}, nil
+		},
+	}
+	service := NewService(repo, WithJobFilterParser(parser))
+	ctx := context.Background()
+
+	invalid := []struct {
+		kind   string
+		params map[string]string
+	}{
+		{"backup", nil},
+		{domain.JobExport, map[string]string{"format": "xlsx"}},
+		{domain.JobSummary, map[string]string{"start_date": "01-2025", "end_date": "12-2025", "format": "doc"}},
+		{domain.JobSummary, nil},
+	}
+	for _, tt := range invalid {
+		if _, err := service.EnqueueJob(ctx, tt.kind, tt.params); !errors.Is(err, domain.ErrInvalidJob) {
+			t.Errorf("EnqueueJob(%s, %v) error = %v, want ErrInvalidJob", tt.kind, tt.params, err)
+		}
+	}
+
+	job, err := service.EnqueueJob(ctx, domain.JobExport, nil)
+	if err != nil {
+		t.Fatalf("EnqueueJob() error = %v", err)
+	}
+	if job.ID != "job-1" || job.Status != domain.JobQueued {
+		t.Errorf("job = %+v", job)
+	}
+	if !service.runNextJob(ctx) {
+		t.Fatal("runNextJob() = false, want job to run")
+	}
+	if service.runNextJob(ctx) {
+		t.Error("runNextJob() on empty queue = true")
+	}
+	if completed == nil {
+		t.Fatal("job was not completed")
+	}
+	lines := strings.Split(strings.TrimSpace(string(completed.Data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "user_id,service_name") || progress != 2 {
+		t.Errorf("result = %q, progress = %d", completed.Data, progress)
+	}
+	if completed.FileName != "subscriptions.csv" {
+		t.Errorf("file name = %q", completed.FileName)
+	}
+
+	if _, err := service.JobResult(ctx, "job-1"); !errors.Is(err, domain.ErrJobNotReady) {
+		t.Errorf("JobResult() of running job error = %v, want ErrJobNotReady", err)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// LogNotifier пишет напоминание в лог, используется по умолчанию
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, prefs *domain.NotificationPreferences, reminder *domain.Reminder) error {
+	slog.Info("Reminder", "user_id", reminder.UserID, "service_name", reminder.ServiceName,
+		"kind", reminder.Kind, "charge_date", reminder.ChargeDate, "amount", reminder.Amount)
+	return nil
+}
+
+// WebhookNotifier отправляет напоминание в JSON на webhook_url пользователя
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{client: client}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, prefs *domain.NotificationPreferences, reminder *domain.Reminder) error {
+	if prefs.WebhookURL == nil {
+		return fmt.Errorf("webhook_url is not set")
+	}
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *prefs.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier отправляет напоминание письмом. Без username авторизация не используется.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPNotifier(host, port, username, password, from string) *SMTPNotifier {
+	n := &SMTPNotifier{addr: host + ":" + port, from: from}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, prefs *domain.NotificationPreferences, reminder *domain.Reminder) error {
+	if prefs.Email == nil {
+		return fmt.Errorf("email is not set")
+	}
+	subject, text := reminderText(reminder)
+
+	var msg strings.Builder
+	msg.WriteString("From: " + n.from + "\r\n")
+	msg.WriteString("To: " + *prefs.Email + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{*prefs.Email}, []byte(msg.String()))
+}
+
+func reminderText(r *domain.Reminder) (subject, text string) {
+	date := r.ChargeDate.Format("02.01.2006")
+	if r.Kind == domain.ReminderEnding {
+		subject = fmt.Sprintf("Подписка %s заканчивается", r.ServiceName)
+		text = fmt.Sprintf("%s будет последнее списание по подписке %s: %s.\n", date, r.ServiceName, r.Amount)
+	} else {
+		subject = fmt.Sprintf("Скоро продление подписки %s", r.ServiceName)
+		text = fmt.Sprintf("%s подписка %s будет продлена, сумма списания: %s.\n", date, r.ServiceName, r.Amount)
+	}
+	return subject, text
+}
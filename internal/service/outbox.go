@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+const outboxBatchSize = 100
+
+// Relay переносит события из outbox в sink. Сообщение отмечается опубликованным только
+// после успешной передачи в sink, поэтому при сбое оно будет передано повторно.
+type Relay struct {
+	repo domain.OutboxRepository
+	sink domain.EventSink
+}
+
+func NewRelay(repo domain.OutboxRepository, sink domain.EventSink) *Relay {
+	return &Relay{repo: repo, sink: sink}
+}
+
+// Run публикует накопившиеся события каждые interval до отмены ctx
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Outbox relay failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain публикует события пачками, пока outbox не опустеет или не произойдет ошибка
+func (r *Relay) Drain(ctx context.Context) error {
+	for {
+		n, err := r.repo.RelayOutbox(ctx, outboxBatchSize, r.sink.Publish)
+		if n > 0 {
+			slog.Info("Outbox events published", "count", n)
+		}
+		if err != nil {
+			return err
+		}
+		if n < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// LogSink пишет события в лог, удобен для отладки
+type LogSink struct{}
+
+func (LogSink) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	slog.Info("Event", "id", msg.ID, "type", msg.EventType, "payload", string(msg.Payload))
+	return nil
+}
+
+// WebhookSink ставит событие в очередь доставки вебхуков подписанным получателям
+type WebhookSink struct {
+	repo domain.WebhookRepository
+}
+
+func NewWebhookSink(repo domain.WebhookRepository) *WebhookSink {
+	return &WebhookSink{repo: repo}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	return s.repo.EnqueueWebhookDeliveries(ctx, msg.EventType, msg.Payload)
+}
+
+// MultiSink передает событие всем sink по очереди. Если один из них вернул ошибку,
+// событие будет передано повторно всем, включая уже получившие его.
+type MultiSink []domain.EventSink
+
+func (m MultiSink) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Publish(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
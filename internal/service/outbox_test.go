@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"testing"
+)
+
+// memOutbox outbox в памяти с той же семантикой, что и RelayOutbox в storage
+type memOutbox struct {
+	messages  []*domain.OutboxMessage
+	published map[int64]bool
+}
+
+func (o *memOutbox) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, msg *domain.OutboxMessage) error) (int, error) {
+	n := 0
+	for _, m := range o.messages {
+		if o.published[m.ID] {
+			continue
+		}
+		if n == limit {
+			break
+		}
+		if err := publish(ctx, m); err != nil {
+			return n, err
+		}
+		o.published[m.ID] = true
+		n++
+	}
+	return n, nil
+}
+
+func (o *memOutbox) ListOutbox(ctx context.Context, filter *domain.OutboxFilter) ([]*domain.OutboxMessage, error) {
+	return nil, nil
+}
+
+func (o *memOutbox) LastOutboxSeq(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+type recordingSink struct {
+	got    []int64
+	failID int64
+}
+
+func (s *recordingSink) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	s.got = append(s.got, msg.ID)
+	if msg.ID == s.failID {
+		s.failID = 0
+		return errors.New("sink unavailable")
+	}
+	return nil
+}
+
+func TestRelay_Drain(t *testing.T) {
+	outbox := &memOutbox{published: make(map[int64]bool)}
+	for id := int64(1); id <= outboxBatchSize+5; id++ {
+		outbox.messages = append(outbox.messages, &domain.OutboxMessage{ID: id, EventType: domain.EventCreated})
+	}
+	first := &recordingSink{failID: 3}
+	second := &recordingSink{}
+	relay := NewRelay(outbox, MultiSink{first, second})
+
+	// ошибка sink останавливает публикацию, сообщение остается неопубликованным
+	if err := relay.Drain(context.Background()); err == nil {
+		t.Fatal("Drain() expected error")
+	}
+	if len(outbox.published) != 2 {
+		t.Fatalf("published %d messages, want 2", len(outbox.published))
+	}
+
+	if err := relay.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if len(outbox.published) != len(outbox.messages) {
+		t.Fatalf("published %d messages, want %d", len(outbox.published), len(outbox.messages))
+	}
+	// сообщение 3 передано повторно обоим sink, порядок сохранен
+	if first.got[2] != 3 || first.got[3] != 3 || second.got[2] != 3 || second.got[3] != 3 {
+		t.Errorf("unexpected redelivery order: %v, %v", first.got[:5], second.got[:5])
+	}
+	if len(second.got) != len(outbox.messages)+1 {
+		t.Errorf("second sink got %d messages, want %d", len(second.got), len(outbox.messages)+1)
+	}
+}
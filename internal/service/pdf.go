@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Страница A4 альбомной ориентации, размеры в пунктах. Моноширинный Courier позволяет
+// выравнивать колонки пробелами: ширина символа 0.6 размера шрифта.
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 40
+	pdfFontSize   = 8
+	pdfLineHeight = 12
+)
+
+// pdfLine строка текста страницы, пустой Text - пустая строка
+type pdfLine struct {
+	Text string
+	Bold bool
+}
+
+// pdfTranslit латиница для кириллицы: стандартные шрифты PDF знают только WinAnsiEncoding
+var pdfTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// pdfTransliterate заменяет кириллицу латиницей
+func pdfTransliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		lower := unicode.ToLower(r)
+		t, ok := pdfTranslit[lower]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if lower != r && t != "" {
+			t = strings.ToUpper(t[:1]) + t[1:]
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// pdfEncode кодирует строку в WinAnsiEncoding для строкового литерала PDF: кириллица
+// транслитерируется, прочие символы вне Latin-1 заменяются на "?"
+func pdfEncode(s string) string {
+	var b strings.Builder
+	for _, r := range pdfTransliterate(s) {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfCell текст колонки шириной width символов: длинный обрезается, короткий дополняется пробелами
+func pdfCell(s string, width int, alignRight bool) string {
+	text := []rune(pdfTransliterate(s))
+	if len(text) > width {
+		return string(text[:width-1]) + "~"
+	}
+	pad := strings.Repeat(" ", width-len(text))
+	if alignRight {
+		return pad + string(text)
+	}
+	return string(text) + pad
+}
+
+// writePDF пишет документ PDF 1.4 из строк текста, разбивая их на страницы
+func writePDF(w io.Writer, lines []pdfLine) error {
+	perPage := (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	var pages [][]pdfLine
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	pages = append(pages, lines)
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// 1 - каталог, 2 - дерево страниц, 3 и 4 - шрифты, далее пары страница + содержимое
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+
+		var content strings.Builder
+		y := pdfPageHeight - pdfMargin
+		for _, line := range page {
+			y -= pdfLineHeight
+			if line.Text == "" {
+				continue
+			}
+			font := "F1"
+			if line.Bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, pdfFontSize, pdfMargin, y, pdfEncode(line.Text))
+		}
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+const defaultReminderWindow = 3 * 24 * time.Hour
+
+func (s *SubServiceImpl) SetNotificationPreferences(ctx context.Context, prefs *domain.NotificationPreferences) error {
+	if err := s.repo.SetNotificationPreferences(ctx, prefs); err != nil {
+		slog.Error("Failed to save notification preferences", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) GetNotificationPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	prefs, err := s.repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to get notification preferences", "error", err)
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (s *SubServiceImpl) DeleteNotificationPreferences(ctx context.Context, userID string) error {
+	if err := s.repo.DeleteNotificationPreferences(ctx, userID); err != nil {
+		slog.Error("Failed to delete notification preferences", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RunReminderScheduler отправляет напоминания сразу при запуске и затем каждые interval до отмены ctx
+func (s *SubServiceImpl) RunReminderScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.SendReminders(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Sending reminders failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders напоминает о списаниях, которые наступят в пределах окна пользователя
+// (days_before или reminderWindow). Пользователи без настроек получают напоминания в лог.
+// О каждом списании напоминание отправляется один раз; если доставка не удалась,
+// отметка снимается и попытка повторится при следующем запуске.
+func (s *SubServiceImpl) SendReminders(ctx context.Context) error {
+	list, err := s.repo.ListNotificationPreferences(ctx)
+	if err != nil {
+		return err
+	}
+	maxWindow := s.reminderWindow
+	byUser := make(map[string]*domain.NotificationPreferences, len(list))
+	for _, prefs := range list {
+		byUser[prefs.UserID] = prefs
+		if w := s.windowFor(prefs); w > maxWindow {
+			maxWindow = w
+		}
+	}
+
+	now := s.now()
+	periodStart := domain.MonthStart(now)
+	periodEnd := domain.MonthStart(now.Add(maxWindow))
+	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, &domain.Filter{StartDate: &periodStart, EndDate: &periodEnd})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		prefs, ok := byUser[sub.UserID]
+		if !ok {
+			prefs = &domain.NotificationPreferences{UserID: sub.UserID, Channel: domain.ChannelLog, Renewal: true, Ending: true}
+		}
+		reminder := sub.DueReminder(now, s.windowFor(prefs), prefs)
+		if reminder == nil {
+			continue
+		}
+		claimed, err := s.repo.ClaimReminder(ctx, reminder)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.notifierFor(prefs.Channel).Notify(ctx, prefs, reminder); err != nil {
+			slog.Error("Failed to send reminder", "user_id", sub.UserID, "service_name", sub.ServiceName,
+				"channel", prefs.Channel, "error", err)
+			if err := s.repo.ReleaseReminder(ctx, reminder); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) windowFor(prefs *domain.NotificationPreferences) time.Duration {
+	if prefs.DaysBefore != nil {
+		return time.Duration(*prefs.DaysBefore) * 24 * time.Hour
+	}
+	return s.reminderWindow
+}
+
+// notifierFor если канал не настроен (например, нет SMTP), напоминание уходит в лог
+func (s *SubServiceImpl) notifierFor(channel string) domain.Notifier {
+	if n, ok := s.notifiers[channel]; ok {
+		return n
+	}
+	slog.Warn("Notifier is not configured, falling back to log", "channel", channel)
+	return LogNotifier{}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type reminderRepo struct {
+	mockRepo
+	listPreferencesFunc func(ctx context.Context) ([]*domain.NotificationPreferences, error)
+	claimReminderFunc   func(ctx context.Context, reminder *domain.Reminder) (bool, error)
+	releaseReminderFunc func(ctx context.Context, reminder *domain.Reminder) error
+}
+
+func (m *reminderRepo) ListNotificationPreferences(ctx context.Context) ([]*domain.NotificationPreferences, error) {
+	if m.listPreferencesFunc != nil {
+		return m.listPreferencesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *reminderRepo) ClaimReminder(ctx context.Context, reminder *domain.Reminder) (bool, error) {
+	if m.claimReminderFunc != nil {
+		return m.claimReminderFunc(ctx, reminder)
+	}
+	return true, nil
+}
+
+func (m *reminderRepo) ReleaseReminder(ctx context.Context, reminder *domain.Reminder) error {
+	if m.releaseReminderFunc != nil {
+		return m.releaseReminderFunc(ctx, reminder)
+	}
+	return nil
+}
+
+// smtpStandIn минимальный SMTP сервер для тестов, сохраняет тела принятых писем
+type smtpStandIn struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []string
+	// fail первые fail писем отклоняются на DATA
+	fail int
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &smtpStandIn{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return srv
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprint(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"):
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			s.mu.Lock()
+			if s.fail > 0 {
+				s.fail--
+				s.mu.Unlock()
+				reply("451 Try again later")
+				continue
+			}
+			s.messages = append(s.messages, body.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSubServiceImpl_SendReminders(t *testing.T) {
+	emailUser := "123e4567-e89b-12d3-a456-426614174000"
+	logUser := "223e4567-e89b-12d3-a456-426614174000"
+	email := "user@example.com"
+	now := time.Date(2024, 1, 30, 9, 0, 0, 0, time.UTC)
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	smtpSrv := newSMTPStandIn(t)
+	smtpSrv.fail = 1
+	host, port, _ := net.SplitHostPort(smtpSrv.listener.Addr().String())
+
+	sent := make(map[string]bool)
+	var released int
+	repo := &reminderRepo{
+		mockRepo: mockRepo{
+			getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+				return []*domain.Subscription{
+					{ID: 1, UserID: emailUser, ServiceName: "Netflix", Price: 29900, StartDate: start},
+					{ID: 2, UserID: logUser, ServiceName: "Spotify", Price: 16900, StartDate: start},
+				}, nil
+			},
+		},
+		listPreferencesFunc: func(ctx context.Context) ([]*domain.NotificationPreferences, error) {
+			return []*domain.NotificationPreferences{
+				{UserID: emailUser, Channel: domain.ChannelEmail, Email: &email, Renewal: true, Ending: true},
+			}, nil
+		},
+		claimReminderFunc: func(ctx context.Context, r *domain.Reminder) (bool, error) {
+			key := fmt.Sprintf("%d-%s-%s", r.SubscriptionID, r.Kind, r.ChargeDate.Format(time.DateOnly))
+			if sent[key] {
+				return false, nil
+			}
+			sent[key] = true
+			return true, nil
+		},
+		releaseReminderFunc: func(ctx context.Context, r *domain.Reminder) error {
+			released++
+			delete(sent, fmt.Sprintf("%d-%s-%s", r.SubscriptionID, r.Kind, r.ChargeDate.Format(time.DateOnly)))
+			return nil
+		},
+	}
+	service := NewService(repo,
+		WithReminderWindow(3*24*time.Hour),
+		WithNotifier(domain.ChannelEmail, NewSMTPNotifier(host, port, "", "", "noreply@example.com")))
+	service.now = func() time.Time { return now }
+
+	// первая попытка отклонена SMTP сервером, напоминание повторяется при следующем запуске и больше не дублируется
+	for i := 0; i < 3; i++ {
+		if err := service.SendReminders(context.Background()); err != nil {
+			t.Fatalf("SendReminders() error = %v", err)
+		}
+	}
+
+	if released != 1 {
+		t.Errorf("released %d reminders, want 1", released)
+	}
+	if len(smtpSrv.messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(smtpSrv.messages))
+	}
+	msg := smtpSrv.messages[0]
+	if !strings.Contains(msg, "To: "+email) || !strings.Contains(msg, "01.02.2024") || !strings.Contains(msg, "299.00") {
+		t.Errorf("unexpected email:\n%s", msg)
+	}
+	if !sent["2-renewal-2024-02-01"] {
+		t.Errorf("reminder for user without preferences not sent: %v", sent)
+	}
+}
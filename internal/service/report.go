@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const reportDateForm = "01-2006"
+
+type reportSummaryRow struct {
+	Label string
+	Value domain.Money
+}
+
+// reportSummaryRows строки сводки, одинаковые для XLSX и PDF
+func reportSummaryRows(report *domain.Report) []reportSummaryRow {
+	s := report.Summary
+	return []reportSummaryRow{
+		{"Total", s.TotalPrice},
+		{"Gross (before discounts)", s.GrossPrice},
+		{"Discounts", s.DiscountTotal},
+		{"Net (without tax)", s.Tax.Net},
+		{"Tax", s.Tax.Tax},
+		{"Gross (with tax)", s.Tax.Gross},
+	}
+}
+
+// reportHeader заголовок отчета: период, фильтры и время построения
+func reportHeader(report *domain.Report) []string {
+	lines := []string{
+		"Period: " + report.StartDate.Format(reportDateForm) + " - " + report.EndDate.Format(reportDateForm),
+	}
+	if report.UserID != nil {
+		lines = append(lines, "User: "+*report.UserID)
+	}
+	if report.ServiceName != nil {
+		lines = append(lines, "Service: "+*report.ServiceName)
+	}
+	if report.Category != nil {
+		lines = append(lines, "Category: "+*report.Category)
+	}
+	return append(lines, "Generated: "+report.GeneratedAt.Format("2006-01-02 15:04 MST"))
+}
+
+var reportLineColumns = []string{"Service", "User", "Category", "Months", "Gross", "Discount", "Total", "Net", "Tax"}
+
+// RenderReport пишет отчет в формате format: domain.ReportJSON, ReportXLSX или ReportPDF
+func (s *SubServiceImpl) RenderReport(w io.Writer, report *domain.Report, format string) error {
+	switch format {
+	case domain.ReportJSON:
+		return json.NewEncoder(w).Encode(report)
+	case domain.ReportXLSX:
+		return writeXLSX(w, reportSheets(report))
+	case domain.ReportPDF:
+		return writePDF(w, reportPDFLines(report))
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+func reportSheets(report *domain.Report) []xlsxSheet {
+	summary := xlsxSheet{Name: "Summary", Rows: [][]xlsxCell{{xlsxBold("Subscriptions report")}}}
+	for _, line := range reportHeader(report) {
+		summary.Rows = append(summary.Rows, []xlsxCell{xlsxText(line)})
+	}
+	summary.Rows = append(summary.Rows, nil)
+	for _, row := range reportSummaryRows(report) {
+		summary.Rows = append(summary.Rows, []xlsxCell{xlsxText(row.Label), xlsxMoney(row.Value)})
+	}
+
+	header := make([]xlsxCell, len(reportLineColumns))
+	for i, c := range reportLineColumns {
+		header[i] = xlsxBold(c)
+	}
+	lines := xlsxSheet{Name: "Subscriptions", Rows: [][]xlsxCell{header}}
+	for _, l := range report.Lines {
+		category := ""
+		if l.Category != nil {
+			category = *l.Category
+		}
+		lines.Rows = append(lines.Rows, []xlsxCell{
+			xlsxText(l.ServiceName), xlsxText(l.UserID), xlsxText(category), xlsxNumber(l.Months),
+			xlsxMoney(l.Gross), xlsxMoney(l.Discount), xlsxMoney(l.Total), xlsxMoney(l.Tax.Net), xlsxMoney(l.Tax.Tax),
+		})
+	}
+	return []xlsxSheet{summary, lines}
+}
+
+// Ширина колонок разбивки в PDF в символах, суммы выравниваются вправо
+var reportPDFWidths = []int{26, 36, 14, 6, 12, 12, 12, 12, 12}
+
+func reportPDFLines(report *domain.Report) []pdfLine {
+	lines := []pdfLine{{Text: "Subscriptions report", Bold: true}}
+	for _, line := range reportHeader(report) {
+		lines = append(lines, pdfLine{Text: line})
+	}
+	lines = append(lines, pdfLine{})
+	for _, row := range reportSummaryRows(report) {
+		lines = append(lines, pdfLine{Text: pdfCell(row.Label, 26, false) + pdfCell(row.Value.String(), 16, true)})
+	}
+	lines = append(lines, pdfLine{})
+
+	lines = append(lines, pdfLine{Text: reportPDFRow(reportLineColumns), Bold: true})
+	for _, l := range report.Lines {
+		category := ""
+		if l.Category != nil {
+			category = *l.Category
+		}
+		lines = append(lines, pdfLine{Text: reportPDFRow([]string{
+			l.ServiceName, l.UserID, category, strconv.Itoa(l.Months),
+			l.Gross.String(), l.Discount.String(), l.Total.String(), l.Tax.Net.String(), l.Tax.Tax.String(),
+		})})
+	}
+	return lines
+}
+
+func reportPDFRow(values []string) string {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		// первые три колонки текстовые, остальные - числа
+		cells[i] = pdfCell(v, reportPDFWidths[i], i >= 3)
+	}
+	return strings.Join(cells, "  ")
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubServiceImpl_Report(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	category := "Кино"
+	repo := &mockRepo{
+		getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+			return []*domain.Subscription{
+				{ID: 2, UserID: "u2", ServiceName: "Кинопоиск", Price: 29900, StartDate: start, TaxInclusive: true, Category: &category},
+				{ID: 1, UserID: "u1", ServiceName: "Apple (One)", Price: 10000, StartDate: end, TaxInclusive: true},
+			}, nil
+		},
+	}
+	service := NewService(repo)
+	report, err := service.BuildReport(context.Background(), &domain.Filter{StartDate: &start, EndDate: &end})
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if len(report.Lines) != 2 || report.Lines[0].SubscriptionID != 1 || report.Lines[1].Months != 3 {
+		t.Fatalf("lines = %+v", report.Lines)
+	}
+	if report.Summary.TotalPrice != 29900*3+10000 {
+		t.Errorf("total = %v", report.Summary.TotalPrice)
+	}
+
+	var xlsx bytes.Buffer
+	if err = service.RenderReport(&xlsx, report, domain.ReportXLSX); err != nil {
+		t.Fatalf("RenderReport(xlsx) error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(xlsx.Bytes()), int64(xlsx.Len()))
+	if err != nil {
+		t.Fatalf("xlsx is not a zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("xlsx has no %s", name)
+		}
+	}
+	if sheet := files["xl/worksheets/sheet2.xml"]; !strings.Contains(sheet, "<v>897.00</v>") || !strings.Contains(sheet, "Кинопоиск") {
+		t.Errorf("subscriptions sheet misses values:\n%s", sheet)
+	}
+
+	var pdf bytes.Buffer
+	if err = service.RenderReport(&pdf, report, domain.ReportPDF); err != nil {
+		t.Fatalf("RenderReport(pdf) error = %v", err)
+	}
+	out := pdf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Error("pdf has no header or trailer")
+	}
+	for _, want := range []string{"Kinopoisk", `Apple \(One\)`, "997.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("pdf does not contain %q", want)
+		}
+	}
+}
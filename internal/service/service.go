@@ -4,15 +4,105 @@ import (
 	"context"
 	"github.com/agidelle/effectivemobile/internal/domain"
 	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 )
 
+const httpTimeout = 5 * time.Second
+
 type SubServiceImpl struct {
-	repo domain.Repository
+	repo       domain.Repository
+	now        func() time.Time
+	httpClient *http.Client
+
+	webhookMaxAttempts int
+	webhookBaseDelay   time.Duration
+	expiringWindow     time.Duration
+
+	notifiers      map[string]domain.Notifier
+	reminderWindow time.Duration
+
+	anomalyOutlierPercent int
+	anomalyJumpPercent    int
+
+	jobFilter    JobFilterParser
+	jobResultTTL time.Duration
+
+	idempotencyTTL time.Duration
+
+	eventPollInterval time.Duration
+	streamsDone       chan struct{}
+	closeStreams      sync.Once
+}
+
+type Option func(*SubServiceImpl)
+
+// WithWebhookRetry число попыток доставки вебхука и задержка перед первым повтором,
+// каждая следующая задержка вдвое больше предыдущей
+func WithWebhookRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.webhookMaxAttempts = maxAttempts
+		s.webhookBaseDelay = baseDelay
+	}
+}
+
+// WithNotifier канал доставки напоминаний, заменяет канал с тем же именем
+func WithNotifier(channel string, notifier domain.Notifier) Option {
+	return func(s *SubServiceImpl) {
+		s.notifiers[channel] = notifier
+	}
+}
+
+// WithReminderWindow за какое время до списания напоминать пользователям без days_before
+func WithReminderWindow(window time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.reminderWindow = window
+	}
+}
+
+// WithExpiringWindow за какое время до последнего списания отправляется subscription.expiring
+func WithExpiringWindow(window time.Duration) Option {
+	return func(s *SubServiceImpl) {
+		s.expiringWindow = window
+	}
 }
 
-func NewService(repo domain.Repository) *SubServiceImpl {
-	return &SubServiceImpl{repo: repo}
+// WithAnomalyThresholds пороги аномалий цены в процентах: отклонение от эталонной цены
+// и повышение относительно предыдущей цены подписки
+func WithAnomalyThresholds(outlierPercent, jumpPercent int) Option {
+	return func(s *SubServiceImpl) {
+		s.anomalyOutlierPercent = outlierPercent
+		s.anomalyJumpPercent = jumpPercent
+	}
+}
+
+func NewService(repo domain.Repository, opts ...Option) *SubServiceImpl {
+	client := &http.Client{Timeout: httpTimeout}
+	s := &SubServiceImpl{
+		repo:               repo,
+		now:                time.Now,
+		httpClient:         client,
+		webhookMaxAttempts: defaultWebhookMaxAttempts,
+		webhookBaseDelay:   defaultWebhookBaseDelay,
+		expiringWindow:     defaultExpiringWindow,
+		notifiers: map[string]domain.Notifier{
+			domain.ChannelLog:     LogNotifier{},
+			domain.ChannelWebhook: NewWebhookNotifier(client),
+		},
+		reminderWindow:        defaultReminderWindow,
+		anomalyOutlierPercent: defaultAnomalyOutlierPercent,
+		anomalyJumpPercent:    defaultAnomalyJumpPercent,
+		jobResultTTL:          defaultJobResultTTL,
+		idempotencyTTL:        defaultIdempotencyTTL,
+		eventPollInterval:     defaultEventPollInterval,
+		streamsDone:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *SubServiceImpl) CloseDB() {
@@ -29,10 +119,27 @@ func (s *SubServiceImpl) Search(ctx context.Context, filter *domain.Filter) ([]*
 		slog.Info("No subscription found", "filter", filter)
 		return nil, nil
 	}
+	now := s.now()
+	for _, sub := range res {
+		sub.NextChargeDate = sub.NextCharge(now)
+	}
 	slog.Info("Subscription found", "subscription", res)
 	return res, nil
 }
 
+func (s *SubServiceImpl) SearchPage(ctx context.Context, filter *domain.Filter) (*domain.Page, error) {
+	page, err := s.repo.SearchPage(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to search subscriptions page", "error", err)
+		return nil, err
+	}
+	now := s.now()
+	for _, sub := range page.Items {
+		sub.NextChargeDate = sub.NextCharge(now)
+	}
+	return page, nil
+}
+
 func (s *SubServiceImpl) CreateSubscription(ctx context.Context, input *domain.Subscription) error {
 	err := s.repo.Create(ctx, input)
 	if err != nil {
@@ -44,7 +151,7 @@ func (s *SubServiceImpl) CreateSubscription(ctx context.Context, input *domain.S
 }
 
 func (s *SubServiceImpl) UpdateSubscription(ctx context.Context, input *domain.Subscription) error {
-	err := s.repo.Update(ctx, input)
+	err := s.repo.Update(ctx, input, domain.MonthStart(s.now()))
 	if err != nil {
 		slog.Error("Failed to update subscription", "error", err)
 		return err
@@ -63,16 +170,35 @@ func (s *SubServiceImpl) DeleteSubscription(ctx context.Context, filter *domain.
 	return nil
 }
 
-func (s *SubServiceImpl) GetSubscriptionsSummary(ctx context.Context, filter *domain.Filter) (int, error) {
+func (s *SubServiceImpl) GetSubscriptionsSummary(ctx context.Context, filter *domain.Filter) (*domain.Summary, error) {
+	report, err := s.BuildReport(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &report.Summary, nil
+}
+
+// BuildReport сводка по подпискам за период filter.StartDate..filter.EndDate
+// вместе с вкладом каждой подписки
+func (s *SubServiceImpl) BuildReport(ctx context.Context, filter *domain.Filter) (*domain.Report, error) {
 	subs, err := s.repo.GetSubscriptionsForPeriod(ctx, filter)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	filterStart := *filter.StartDate
 	filterEnd := *filter.EndDate
 
-	totalPrice := 0
+	report := &domain.Report{
+		StartDate:   filterStart,
+		EndDate:     filterEnd,
+		UserID:      filter.UserID,
+		ServiceName: filter.ServiceName,
+		Category:    filter.Category,
+		GeneratedAt: s.now().UTC(),
+		Lines:       []domain.ReportLine{},
+	}
+	summary := &report.Summary
 	for _, sub := range subs {
 		subStart := sub.StartDate
 		var subEnd time.Time
@@ -86,11 +212,64 @@ func (s *SubServiceImpl) GetSubscriptionsSummary(ctx context.Context, filter *do
 		actualEnd := minTime(filterEnd, subEnd)
 
 		monthsInPeriod := calculateMonthsInPeriodTime(actualStart, actualEnd)
-		if monthsInPeriod > 0 {
-			totalPrice += sub.Price * monthsInPeriod
+		if monthsInPeriod <= 0 {
+			continue
+		}
+		// Скидка по промокоду действует ограниченное число месяцев, поэтому считаем помесячно
+		var gross, discount domain.Money
+		for month := actualStart; !month.After(actualEnd); month = month.AddDate(0, 1, 0) {
+			price, monthDiscount := sub.ChargeAt(month)
+			if gross, err = gross.Add(price); err != nil {
+				return nil, err
+			}
+			// скидка не превышает цену месяца, поэтому сумма скидок не больше gross
+			discount += monthDiscount
+		}
+		// При фильтре по пользователю учитываем только его долю в совместной подписке
+		if filter.UserID != nil {
+			num, den := sub.ShareOf(*filter.UserID)
+			if gross, err = gross.MulDiv(int64(num), int64(den)); err != nil {
+				return nil, err
+			}
+			if discount, err = discount.MulDiv(int64(num), int64(den)); err != nil {
+				return nil, err
+			}
+		}
+		if summary.GrossPrice, err = summary.GrossPrice.Add(gross); err != nil {
+			return nil, err
 		}
+		if summary.DiscountTotal, err = summary.DiscountTotal.Add(discount); err != nil {
+			return nil, err
+		}
+
+		tax, err := domain.SplitTax(gross-discount, sub.RateBP(), sub.TaxInclusive)
+		if err != nil {
+			return nil, err
+		}
+		if summary.Tax, err = summary.Tax.Add(tax); err != nil {
+			return nil, err
+		}
+		report.Lines = append(report.Lines, domain.ReportLine{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID,
+			ServiceName:    sub.ServiceName,
+			Category:       sub.Category,
+			Months:         monthsInPeriod,
+			Gross:          gross,
+			Discount:       discount,
+			Total:          gross - discount,
+			Tax:            tax,
+		})
 	}
-	return totalPrice, nil
+	summary.TotalPrice = summary.GrossPrice - summary.DiscountTotal
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.UserID < b.UserID
+	})
+	return report, nil
 }
 
 func calculateMonthsInPeriodTime(start, end time.Time) int {
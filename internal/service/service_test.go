@@ -9,7 +9,10 @@ import (
 	"time"
 )
 
+// mockRepo реализует методы подписок; вызов остальных методов domain.Repository паникует,
+// тесты других разделов встраивают его в свои фейки
 type mockRepo struct {
+	domain.Repository
 	searchFunc                    func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error)
 	createFunc                    func(ctx context.Context, input *domain.Subscription) error
 	updateFunc                    func(ctx context.Context, input *domain.Subscription) error
@@ -24,30 +27,35 @@ func (m *mockRepo) Search(ctx context.Context, filter *domain.Filter) ([]*domain
 	}
 	return nil, nil
 }
+
 func (m *mockRepo) Create(ctx context.Context, input *domain.Subscription) error {
 	if m.createFunc != nil {
 		return m.createFunc(ctx, input)
 	}
 	return nil
 }
-func (m *mockRepo) Update(ctx context.Context, input *domain.Subscription) error {
+
+func (m *mockRepo) Update(ctx context.Context, input *domain.Subscription, month time.Time) error {
 	if m.updateFunc != nil {
 		return m.updateFunc(ctx, input)
 	}
 	return nil
 }
+
 func (m *mockRepo) Delete(ctx context.Context, filter *domain.Filter) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, filter)
 	}
 	return nil
 }
+
 func (m *mockRepo) GetSubscriptionsForPeriod(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
 	if m.getSubscriptionsForPeriodFunc != nil {
 		return m.getSubscriptionsForPeriodFunc(ctx, filter)
 	}
 	return nil, nil
 }
+
 func (m *mockRepo) CloseDB() {
 	if m.closeDBFunc != nil {
 		m.closeDBFunc()
@@ -56,6 +64,8 @@ func (m *mockRepo) CloseDB() {
 
 func TestSubServiceImpl_Search(t *testing.T) {
 	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	nextCharge := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
 
 	tests := []struct {
 		name       string
@@ -73,7 +83,7 @@ func TestSubServiceImpl_Search(t *testing.T) {
 			}(),
 			mockResult: []*domain.Subscription{{UserID: validUUID, ServiceName: "Netflix"}},
 			mockErr:    nil,
-			want:       []*domain.Subscription{{UserID: validUUID, ServiceName: "Netflix"}},
+			want:       []*domain.Subscription{{UserID: validUUID, ServiceName: "Netflix", NextChargeDate: &nextCharge}},
 			wantErr:    false,
 		},
 		{
@@ -108,6 +118,7 @@ func TestSubServiceImpl_Search(t *testing.T) {
 				},
 			}
 			service := NewService(repo)
+			service.now = func() time.Time { return now }
 			got, err := service.Search(context.Background(), tt.filter)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Search() error = %v, wantErr %v", err, tt.wantErr)
@@ -122,7 +133,7 @@ func TestSubServiceImpl_Search(t *testing.T) {
 func TestSubServiceImpl_CreateSubscription(t *testing.T) {
 	validUUID := "123e4567-e89b-12d3-a456-426614174000"
 	validService := "Netflix"
-	validPrice := 100
+	validPrice := domain.Money(10000)
 
 	tests := []struct {
 		name    string
@@ -173,7 +184,7 @@ func TestSubServiceImpl_CreateSubscription(t *testing.T) {
 func TestSubServiceImpl_UpdateSubscription(t *testing.T) {
 	validUUID := "123e4567-e89b-12d3-a456-426614174000"
 	validService := "Netflix"
-	validPrice := 150
+	validPrice := domain.Money(15000)
 
 	tests := []struct {
 		name    string
@@ -342,3 +353,86 @@ func TestSubServiceImpl_GetSubscriptionsForPeriod(t *testing.T) {
 		})
 	}
 }
+
+func TestSubServiceImpl_GetSubscriptionsSummary(t *testing.T) {
+	owner := "123e4567-e89b-12d3-a456-426614174000"
+	member := "223e4567-e89b-12d3-a456-426614174000"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	family := &domain.Subscription{
+		UserID:      owner,
+		ServiceName: "Spotify",
+		Price:       400,
+		StartDate:   start,
+		Members:     []domain.Member{{UserID: owner, Share: 1}, {UserID: member, Share: 3}},
+		// цена с НДС 20%
+		TaxInclusive: true,
+		TaxRate:      &domain.TaxRate{Region: "RU", RateBP: 2000},
+	}
+	couponStart := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	personal := &domain.Subscription{
+		UserID:      owner,
+		ServiceName: "Netflix",
+		Price:       100,
+		StartDate:   couponStart,
+		// скидка 50% только на февраль
+		Coupon:      &domain.Coupon{Code: "HALF", Kind: domain.CouponPercent, Percent: 50, DurationMonths: 1},
+		CouponStart: &couponStart,
+		// цена без налога, налог 10% начисляется сверху
+		TaxRate: &domain.TaxRate{Region: "KZ", RateBP: 1000},
+	}
+
+	tests := []struct {
+		name   string
+		userID *string
+		want   domain.Summary
+	}{
+		{
+			name:   "all users",
+			userID: nil,
+			want: domain.Summary{
+				TotalPrice: 400*3 + 100*2 - 50, GrossPrice: 400*3 + 100*2, DiscountTotal: 50,
+				Tax: domain.TaxBreakdown{Net: 1000 + 150, Tax: 200 + 15, Gross: 1200 + 165},
+			},
+		},
+		{
+			name:   "owner share",
+			userID: &owner,
+			want: domain.Summary{
+				TotalPrice: 100*3 + 100*2 - 50, GrossPrice: 100*3 + 100*2, DiscountTotal: 50,
+				Tax: domain.TaxBreakdown{Net: 250 + 150, Tax: 50 + 15, Gross: 300 + 165},
+			},
+		},
+		{
+			name:   "member share",
+			userID: &member,
+			want: domain.Summary{
+				TotalPrice: 300 * 3, GrossPrice: 300 * 3,
+				Tax: domain.TaxBreakdown{Net: 750, Tax: 150, Gross: 900},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{
+				getSubscriptionsForPeriodFunc: func(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+					return []*domain.Subscription{family, personal}, nil
+				},
+			}
+			service := NewService(repo)
+			got, err := service.GetSubscriptionsSummary(context.Background(), &domain.Filter{
+				UserID:    tt.userID,
+				StartDate: &start,
+				EndDate:   &end,
+			})
+			if err != nil {
+				t.Fatalf("GetSubscriptionsSummary() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("GetSubscriptionsSummary() got = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
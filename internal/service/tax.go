@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+func (s *SubServiceImpl) SetTaxRate(ctx context.Context, rate *domain.TaxRate) error {
+	if err := s.repo.SetTaxRate(ctx, rate); err != nil {
+		slog.Error("Failed to save tax rate", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListTaxRates(ctx context.Context) ([]*domain.TaxRate, error) {
+	rates, err := s.repo.ListTaxRates(ctx)
+	if err != nil {
+		slog.Error("Failed to list tax rates", "error", err)
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s *SubServiceImpl) DeleteTaxRate(ctx context.Context, region string) error {
+	if err := s.repo.DeleteTaxRate(ctx, region); err != nil {
+		slog.Error("Failed to delete tax rate", "error", err)
+		return err
+	}
+	return nil
+}
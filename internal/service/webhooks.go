@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookBaseDelay   = 30 * time.Second
+	defaultExpiringWindow     = 7 * 24 * time.Hour
+	maxWebhookDelay           = time.Hour
+	webhookBatchSize          = 50
+	// webhookLease время, на которое выбранная доставка скрывается от других диспетчеров
+	webhookLease = time.Minute
+)
+
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+func (s *SubServiceImpl) CreateWebhookEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	if endpoint.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			slog.Error("Failed to generate webhook secret", "error", err)
+			return err
+		}
+		endpoint.Secret = secret
+	}
+	if err := s.repo.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		slog.Error("Failed to create webhook endpoint", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListWebhookEndpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	endpoints, err := s.repo.ListWebhookEndpoints(ctx)
+	if err != nil {
+		slog.Error("Failed to list webhook endpoints", "error", err)
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (s *SubServiceImpl) DeleteWebhookEndpoint(ctx context.Context, id int) error {
+	if err := s.repo.DeleteWebhookEndpoint(ctx, id); err != nil {
+		slog.Error("Failed to delete webhook endpoint", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *SubServiceImpl) ListWebhookDeliveries(ctx context.Context, filter *domain.WebhookDeliveryFilter) ([]*domain.WebhookDelivery, error) {
+	deliveries, err := s.repo.ListWebhookDeliveries(ctx, filter)
+	if err != nil {
+		slog.Error("Failed to list webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *SubServiceImpl) ReplayWebhookDelivery(ctx context.Context, id int64) error {
+	if err := s.repo.ReplayWebhookDelivery(ctx, id); err != nil {
+		slog.Error("Failed to replay webhook delivery", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RunWebhookDispatcher каждые interval отправляет накопившиеся доставки и
+// публикует события о скором окончании подписок, до отмены ctx
+func (s *SubServiceImpl) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// события subscription.expiring пишет в outbox сам репозиторий, в транзакции отметки
+		now := s.now()
+		n, err := s.repo.PublishExpiringSubscriptions(ctx, now, now.Add(s.expiringWindow))
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Expiring subscriptions check failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Expiring subscriptions published", "count", n)
+		}
+		if err := s.DeliverWebhooks(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Webhook delivery failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverWebhooks отправляет доставки, время попытки которых наступило
+func (s *SubServiceImpl) DeliverWebhooks(ctx context.Context) error {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		s.attemptDelivery(ctx, delivery)
+		if err := s.repo.SaveDeliveryAttempt(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attemptDelivery отправляет доставку и обновляет ее состояние. После неудачи следующая
+// попытка откладывается на webhookBaseDelay * 2^(attempts-1), но не больше maxWebhookDelay;
+// после webhookMaxAttempts неудач доставка помечается failed и повторяется только через replay.
+func (s *SubServiceImpl) attemptDelivery(ctx context.Context, d *domain.WebhookDelivery) {
+	d.Attempts++
+	code, err := s.postWebhook(ctx, d)
+	if code != 0 {
+		d.ResponseCode = &code
+	}
+	now := s.now()
+	if err == nil {
+		d.Status = domain.DeliveryDelivered
+		d.LastError = nil
+		d.DeliveredAt = &now
+		slog.Info("Webhook delivered", "id", d.ID, "event", d.EventType, "attempts", d.Attempts)
+		return
+	}
+
+	msg := err.Error()
+	d.LastError = &msg
+	if d.Attempts >= s.webhookMaxAttempts {
+		d.Status = domain.DeliveryFailed
+		slog.Warn("Webhook delivery failed permanently", "id", d.ID, "event", d.EventType, "error", err)
+		return
+	}
+	d.Status = domain.DeliveryPending
+	d.NextAttemptAt = now.Add(s.webhookBackoff(d.Attempts))
+	slog.Warn("Webhook delivery failed, will retry", "id", d.ID, "attempts", d.Attempts,
+		"next_attempt_at", d.NextAttemptAt, "error", err)
+}
+
+func (s *SubServiceImpl) webhookBackoff(attempts int) time.Duration {
+	delay := s.webhookBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxWebhookDelay {
+			return maxWebhookDelay
+		}
+	}
+	return delay
+}
+
+func (s *SubServiceImpl) postWebhook(ctx context.Context, d *domain.WebhookDelivery) (int, error) {
+	timestamp := s.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, d.EventType)
+	req.Header.Set(HeaderWebhookDelivery, strconv.FormatInt(d.ID, 10))
+	// у доставок на webhook_url бюджета нет секрета, они не подписываются
+	if d.Secret != "" {
+		req.Header.Set(HeaderWebhookSignature, SignWebhook(d.Secret, timestamp, d.Payload))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook формирует заголовок подписи "t=<unix>,v1=<hex>", где v1 - HMAC-SHA256
+// от "<unix>.<тело запроса>". Получатель проверяет подпись и отбрасывает старые метки времени.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type deliveryRepo struct {
+	mockRepo
+	claimDueDeliveriesFunc  func(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error)
+	saveDeliveryAttemptFunc func(ctx context.Context, delivery *domain.WebhookDelivery) error
+}
+
+func (m *deliveryRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	if m.claimDueDeliveriesFunc != nil {
+		return m.claimDueDeliveriesFunc(ctx, limit, lease)
+	}
+	return nil, nil
+}
+
+func (m *deliveryRepo) SaveDeliveryAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if m.saveDeliveryAttemptFunc != nil {
+		return m.saveDeliveryAttemptFunc(ctx, delivery)
+	}
+	return nil
+}
+
+func TestSubServiceImpl_DeliverWebhooks(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	secret := "0123456789abcdef"
+	payload := []byte(`{"type":"subscription.created"}`)
+
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if want := SignWebhook(secret, now.Unix(), body); r.Header.Get(HeaderWebhookSignature) != want {
+			t.Errorf("signature = %q, want %q", r.Header.Get(HeaderWebhookSignature), want)
+		}
+		if r.Header.Get(HeaderWebhookEvent) != domain.EventCreated || r.Header.Get(HeaderWebhookDelivery) != "7" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		// первая попытка завершается ошибкой получателя
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	delivery := &domain.WebhookDelivery{
+		ID:        7,
+		EventType: domain.EventCreated,
+		Payload:   payload,
+		Status:    domain.DeliveryPending,
+		URL:       receiver.URL,
+		Secret:    secret,
+	}
+	var saved []domain.WebhookDelivery
+	repo := &deliveryRepo{
+		claimDueDeliveriesFunc: func(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+			return []*domain.WebhookDelivery{delivery}, nil
+		},
+		saveDeliveryAttemptFunc: func(ctx context.Context, d *domain.WebhookDelivery) error {
+			saved = append(saved, *d)
+			return nil
+		},
+	}
+	service := NewService(repo, WithWebhookRetry(3, time.Minute))
+	service.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := service.DeliverWebhooks(context.Background()); err != nil {
+			t.Fatalf("DeliverWebhooks() error = %v", err)
+		}
+	}
+
+	if len(saved) != 2 {
+		t.Fatalf("saved %d attempts, want 2", len(saved))
+	}
+	first, second := saved[0], saved[1]
+	if first.Status != domain.DeliveryPending || first.Attempts != 1 || first.LastError == nil ||
+		*first.ResponseCode != http.StatusServiceUnavailable || !first.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("first attempt = %+v", first)
+	}
+	if second.Status != domain.DeliveryDelivered || second.Attempts != 2 || second.LastError != nil ||
+		*second.ResponseCode != http.StatusOK || second.DeliveredAt == nil {
+		t.Errorf("second attempt = %+v", second)
+	}
+}
+
+func TestSubServiceImpl_DeliverBudgetWebhook(t *testing.T) {
+	budgetID := 3
+	var got *http.Request
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer receiver.Close()
+
+	// доставка на webhook_url бюджета идет через ту же очередь, но без секрета и подписи
+	delivery := &domain.WebhookDelivery{
+		ID:        8,
+		BudgetID:  &budgetID,
+		EventType: domain.EventBudgetAlert,
+		Payload:   []byte(`{"budget_id":3}`),
+		Status:    domain.DeliveryPending,
+		URL:       receiver.URL,
+	}
+	repo := &deliveryRepo{
+		claimDueDeliveriesFunc: func(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+			return []*domain.WebhookDelivery{delivery}, nil
+		},
+		saveDeliveryAttemptFunc: func(ctx context.Context, d *domain.WebhookDelivery) error { return nil },
+	}
+	if err := NewService(repo).DeliverWebhooks(context.Background()); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+	if got == nil {
+		t.Fatal("budget webhook was not delivered")
+	}
+	if got.Header.Get(HeaderWebhookSignature) != "" || got.Header.Get(HeaderWebhookEvent) != domain.EventBudgetAlert {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if delivery.Status != domain.DeliveryDelivered {
+		t.Errorf("delivery status = %s, want delivered", delivery.Status)
+	}
+}
+
+func TestSubServiceImpl_WebhookBackoff(t *testing.T) {
+	service := NewService(&mockRepo{}, WithWebhookRetry(10, 30*time.Second))
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, w := range want {
+		if got := service.webhookBackoff(i + 1); got != w {
+			t.Errorf("webhookBackoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+	if got := service.webhookBackoff(20); got != maxWebhookDelay {
+		t.Errorf("webhookBackoff(20) = %s, want %s", got, maxWebhookDelay)
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+// Общие части запросов аналитики: месяцы периода и подписки, отобранные по сервису и категории.
+// $1, $2 - границы периода, $3 - service_name, $4 - категория.
+const (
+	analyticsMonths = `months AS (
+			SELECT generate_series($1::date, $2::date, interval '1 month')::date AS month
+		)`
+	analyticsSubscriptions = `filtered AS (
+			SELECT s.id, s.user_id, s.service_name, s.price,
+				date_trunc('month', s.start_date)::date AS start_month,
+				date_trunc('month', s.end_date)::date AS end_month,
+				s.coupon_start, c.kind AS coupon_kind, c.percent AS coupon_percent,
+				c.amount AS coupon_amount, c.duration_months AS coupon_months
+			FROM subscriptions s
+			LEFT JOIN coupons c ON c.code = s.coupon_code
+			LEFT JOIN service_catalog cat ON cat.service_name = s.service_name
+			WHERE ($3::text IS NULL OR s.service_name = $3)
+			  AND ($4::text IS NULL OR cat.category = $4)
+		)`
+)
+
+func analyticsArgs(filter *domain.AnalyticsFilter) []interface{} {
+	return []interface{}{filter.StartDate, filter.EndDate, filter.ServiceName, filter.Category}
+}
+
+func (s *Storage) ActiveByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ActiveMonth, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH `+analyticsMonths+`, `+analyticsSubscriptions+`
+		SELECT m.month, count(f.id), count(DISTINCT f.user_id)
+		FROM months m
+		LEFT JOIN filtered f ON f.start_month <= m.month AND (f.end_month IS NULL OR f.end_month >= m.month)
+		GROUP BY m.month
+		ORDER BY m.month`,
+		analyticsArgs(filter)...)
+	if err != nil {
+		slog.Error("Error querying active subscriptions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*domain.ActiveMonth, 0)
+	for rows.Next() {
+		var a domain.ActiveMonth
+		if err := rows.Scan(&a.Month, &a.Subscriptions, &a.Users); err != nil {
+			slog.Error("Error scanning active subscriptions", "error", err)
+			return nil, err
+		}
+		result = append(result, &a)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ARPUByMonth начисления считаются как domain.Subscription.ChargeAt: цена - последнее изменение
+// цены не позже месяца, иначе цена подписки, минус скидка промокода в месяцы его действия.
+// Стоимость совместной подписки делится между участниками по весам долей, как ShareOf,
+// платящие пользователи - владельцы подписок без участников и участники совместных.
+// Деление округляется половиной вверх, как Money.MulDiv.
+func (s *Storage) ARPUByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ARPUMonth, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH `+analyticsMonths+`, `+analyticsSubscriptions+`,
+		charges AS (
+			SELECT m.month, f.id, f.user_id, f.coupon_start, f.coupon_kind, f.coupon_percent,
+				f.coupon_amount, f.coupon_months, COALESCE(pc.price, f.price) AS price
+			FROM months m
+			JOIN filtered f ON f.start_month <= m.month AND (f.end_month IS NULL OR f.end_month >= m.month)
+			LEFT JOIN LATERAL (
+				SELECT price FROM price_changes
+				WHERE subscription_id = f.id AND effective_date <= m.month
+				ORDER BY effective_date DESC
+				LIMIT 1
+			) pc ON TRUE
+		),
+		net AS (
+			SELECT month, id, user_id, price - CASE
+				WHEN coupon_kind IS NULL OR coupon_start IS NULL OR month < coupon_start
+					OR month >= coupon_start + make_interval(months => coupon_months) THEN 0
+				WHEN coupon_kind = 'percent' THEN LEAST(price, (price * coupon_percent * 2 + 100) / 200)
+				ELSE LEAST(price, coupon_amount)
+			END AS amount
+			FROM charges
+		),
+		shares AS (
+			SELECT subscription_id, sum(share) AS total FROM subscription_members GROUP BY subscription_id
+		),
+		payers AS (
+			SELECT n.month, n.user_id, n.amount
+			FROM net n
+			WHERE NOT EXISTS (SELECT 1 FROM shares t WHERE t.subscription_id = n.id)
+			UNION ALL
+			SELECT n.month, sm.user_id, (n.amount * sm.share * 2 + t.total) / (2 * t.total)
+			FROM net n
+			JOIN shares t ON t.subscription_id = n.id
+			JOIN subscription_members sm ON sm.subscription_id = n.id
+		),
+		revenue AS (
+			SELECT m.month, COALESCE(sum(p.amount), 0)::bigint AS revenue,
+				count(DISTINCT p.user_id) AS users
+			FROM months m
+			LEFT JOIN payers p ON p.month = m.month
+			GROUP BY m.month
+		)
+		SELECT month, revenue, users,
+			CASE WHEN users > 0 THEN round(revenue::numeric / users)::bigint ELSE 0 END
+		FROM revenue
+		ORDER BY month`,
+		analyticsArgs(filter)...)
+	if err != nil {
+		slog.Error("Error querying ARPU", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*domain.ARPUMonth, 0)
+	for rows.Next() {
+		var a domain.ARPUMonth
+		if err := rows.Scan(&a.Month, &a.Revenue, &a.Users, &a.ARPU); err != nil {
+			slog.Error("Error scanning ARPU", "error", err)
+			return nil, err
+		}
+		result = append(result, &a)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Storage) ChurnByMonth(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.ChurnMonth, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH `+analyticsMonths+`, `+analyticsSubscriptions+`
+		SELECT m.month, f.service_name,
+			count(*) FILTER (WHERE f.start_month = m.month),
+			count(*) FILTER (WHERE f.end_month = m.month)
+		FROM months m
+		JOIN filtered f ON f.start_month = m.month OR f.end_month = m.month
+		GROUP BY m.month, f.service_name
+		ORDER BY m.month, f.service_name`,
+		analyticsArgs(filter)...)
+	if err != nil {
+		slog.Error("Error querying churn", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*domain.ChurnMonth, 0)
+	for rows.Next() {
+		var c domain.ChurnMonth
+		if err := rows.Scan(&c.Month, &c.ServiceName, &c.New, &c.Ended); err != nil {
+			slog.Error("Error scanning churn", "error", err)
+			return nil, err
+		}
+		result = append(result, &c)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// Cohorts когорты подписок, начавшихся в периоде, удержание считается до конца периода
+func (s *Storage) Cohorts(ctx context.Context, filter *domain.AnalyticsFilter) ([]*domain.Cohort, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH `+analyticsMonths+`, `+analyticsSubscriptions+`
+		SELECT f.start_month, m.month,
+			count(*) FILTER (WHERE f.end_month IS NULL OR f.end_month >= m.month)
+		FROM filtered f
+		JOIN months m ON m.month >= f.start_month
+		WHERE f.start_month BETWEEN $1 AND $2
+		GROUP BY f.start_month, m.month
+		ORDER BY f.start_month, m.month`,
+		analyticsArgs(filter)...)
+	if err != nil {
+		slog.Error("Error querying cohorts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*domain.Cohort, 0)
+	var current *domain.Cohort
+	for rows.Next() {
+		var cohort, month time.Time
+		var retained int
+		if err := rows.Scan(&cohort, &month, &retained); err != nil {
+			slog.Error("Error scanning cohort", "error", err)
+			return nil, err
+		}
+		if current == nil || !current.Cohort.Equal(cohort) {
+			// первая строка когорты - месяц начала, все подписки когорты действуют
+			current = &domain.Cohort{Cohort: cohort, Size: retained}
+			result = append(result, current)
+		}
+		current.Retained = append(current.Retained, retained)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
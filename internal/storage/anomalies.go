@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// SavePriceAnomaly уникальный индекс (subscription_id, kind, effective_date, price) гарантирует,
+// что об одной и той же цене сообщается один раз. Вместе с новой аномалией
+// в outbox записывается событие price.anomaly.
+func (s *Storage) SavePriceAnomaly(ctx context.Context, anomaly *domain.PriceAnomaly) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO price_anomalies (subscription_id, kind, price, reference_price, reference, deviation_bp, effective_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (subscription_id, kind, effective_date, price) DO NOTHING
+		RETURNING id, created_at`,
+		anomaly.SubscriptionID, anomaly.Kind, anomaly.Price, anomaly.ReferencePrice, anomaly.Reference,
+		anomaly.DeviationBP, anomaly.EffectiveDate).
+		Scan(&anomaly.ID, &anomaly.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("Error inserting price anomaly", "error", err)
+		return false, err
+	}
+
+	if err = writeOutbox(ctx, tx, domain.EventPriceAnomaly, anomaly.UserID, anomaly.ServiceName, anomaly); err != nil {
+		slog.Error("Error writing outbox event", "error", err)
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Storage) ListPriceAnomalies(ctx context.Context, filter *domain.PriceAnomalyFilter) ([]*domain.PriceAnomaly, error) {
+	query := `
+		SELECT a.id, a.subscription_id, s.user_id, s.service_name, a.kind, a.price, a.reference_price,
+		       a.reference, a.deviation_bp, a.effective_date, a.created_at
+		FROM price_anomalies a JOIN subscriptions s ON s.id = a.subscription_id`
+	args := []interface{}{}
+	conditions := []string{}
+	argIdx := 1
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "s.user_id = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.UserID)
+		argIdx++
+	}
+	if filter.ServiceName != nil {
+		conditions = append(conditions, "s.service_name = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.ServiceName)
+		argIdx++
+	}
+	if filter.Kind != nil {
+		conditions = append(conditions, "a.kind = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.Kind)
+		argIdx++
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.created_at DESC, a.id DESC"
+	if filter.Limit != nil {
+		query += " LIMIT $" + strconv.Itoa(argIdx)
+		args = append(args, *filter.Limit)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		slog.Error("Error querying price anomalies", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	anomalies := make([]*domain.PriceAnomaly, 0)
+	for rows.Next() {
+		var a domain.PriceAnomaly
+		err := rows.Scan(&a.ID, &a.SubscriptionID, &a.UserID, &a.ServiceName, &a.Kind, &a.Price, &a.ReferencePrice,
+			&a.Reference, &a.DeviationBP, &a.EffectiveDate, &a.CreatedAt)
+		if err != nil {
+			slog.Error("Error scanning price anomaly", "error", err)
+			return nil, err
+		}
+		anomalies = append(anomalies, &a)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return anomalies, nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ApplyBatch выполняет операции в одной транзакции, каждую в своей точке сохранения:
+// в режиме partial ошибка операции откатывает только ее, в режиме atomic - весь пакет
+func (s *Storage) ApplyBatch(ctx context.Context, ops []domain.BatchOperation, atomic bool, month time.Time) ([]domain.BatchResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]domain.BatchResult, len(ops))
+	for i := range ops {
+		results[i] = domain.BatchResult{Index: i, Op: ops[i].Op, Status: domain.BatchSkipped}
+	}
+
+	for i, op := range ops {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		id, err := applyOperation(ctx, savepoint, op, month)
+		if err != nil {
+			savepoint.Rollback(ctx)
+			msg, ok := batchItemError(err)
+			if !ok {
+				slog.Error("Error applying batch", "index", i, "error", err)
+				return nil, err
+			}
+			results[i].Status = domain.BatchFailed
+			results[i].Error = msg
+			if atomic {
+				for j := 0; j < i; j++ {
+					results[j].Status = domain.BatchRolledBack
+				}
+				return results, domain.ErrBatchAborted
+			}
+			continue
+		}
+		if err = savepoint.Commit(ctx); err != nil {
+			return nil, err
+		}
+		results[i].Status = domain.BatchOK
+		results[i].ID = id
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	slog.Info("Batch applied", "operations", len(ops), "atomic", atomic)
+	return results, nil
+}
+
+func applyOperation(ctx context.Context, tx pgx.Tx, op domain.BatchOperation, month time.Time) (int, error) {
+	switch op.Op {
+	case domain.BatchCreate:
+		if err := createSubscription(ctx, tx, op.Subscription); err != nil {
+			return 0, err
+		}
+		return op.Subscription.ID, nil
+	case domain.BatchUpdate:
+		if err := updateSubscription(ctx, tx, op.Subscription, month); err != nil {
+			return 0, err
+		}
+		return op.Subscription.ID, nil
+	case domain.BatchDelete:
+		return 0, deleteSubscription(ctx, tx, op.UserID, op.ServiceName)
+	}
+	return 0, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// batchItemError отличает ошибку данных операции от сбоя БД, ok=false - сбой всего пакета
+func batchItemError(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// класс 23 - нарушение ограничений целостности
+		if strings.HasPrefix(pgErr.Code, "23") {
+			return "constraint violation: " + pgErr.ConstraintName, true
+		}
+		return "", false
+	}
+	msg := err.Error()
+	if msg == "subscription not found" || msg == "subscription already exists" ||
+		strings.HasPrefix(msg, "no subscription found") || strings.HasPrefix(msg, "unknown operation") {
+		return msg, true
+	}
+	return "", false
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+func (s *Storage) CreateBudget(ctx context.Context, budget *domain.Budget) error {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO budgets (user_id, category, amount, thresholds, webhook_url)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		budget.UserID, budget.Category, budget.Amount, budget.Thresholds, budget.WebhookURL).Scan(&budget.ID)
+	if err != nil {
+		slog.Error("Error inserting budget", "error", err)
+		return err
+	}
+	slog.Info("Budget created successfully", "id", budget.ID)
+	return nil
+}
+
+func (s *Storage) UpdateBudget(ctx context.Context, budget *domain.Budget) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE budgets SET user_id = $1, category = $2, amount = $3, thresholds = $4, webhook_url = $5
+		WHERE id = $6`,
+		budget.UserID, budget.Category, budget.Amount, budget.Thresholds, budget.WebhookURL, budget.ID)
+	if err != nil {
+		slog.Error("Error updating budget", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No budget found to update", "id", budget.ID)
+		return fmt.Errorf("budget not found")
+	}
+	slog.Info("Budget updated successfully", "id", budget.ID)
+	return nil
+}
+
+func (s *Storage) ListBudgets(ctx context.Context, userID *string) ([]*domain.Budget, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, user_id, category, amount, thresholds, webhook_url
+		FROM budgets
+		WHERE ($1::text IS NULL OR user_id = $1)
+		ORDER BY id`,
+		userID)
+	if err != nil {
+		slog.Error("Error querying budgets", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := make([]*domain.Budget, 0)
+	for rows.Next() {
+		var b domain.Budget
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Category, &b.Amount, &b.Thresholds, &b.WebhookURL); err != nil {
+			slog.Error("Error scanning budget", "error", err)
+			return nil, err
+		}
+		budgets = append(budgets, &b)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (s *Storage) DeleteBudget(ctx context.Context, id int) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM budgets WHERE id = $1", id)
+	if err != nil {
+		slog.Error("Error deleting budget", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No budget found to delete", "id", id)
+		return fmt.Errorf("budget not found")
+	}
+	slog.Info("Budget deleted successfully", "id", id)
+	return nil
+}
+
+// SaveBudgetAlert уникальный индекс (budget_id, month, kind, threshold) гарантирует,
+// что оповещение о пороге создается один раз за месяц. Вместе с новым оповещением
+// в outbox записывается событие budget.alert, а если у бюджета задан webhook_url -
+// доставка на него в очередь вебхуков.
+func (s *Storage) SaveBudgetAlert(ctx context.Context, alert *domain.BudgetAlert) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO budget_alerts (budget_id, month, kind, threshold, spend, budget_amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (budget_id, month, kind, threshold) DO NOTHING
+		RETURNING id, created_at`,
+		alert.BudgetID, alert.Month, alert.Kind, alert.Threshold, alert.Spend, alert.BudgetAmount).
+		Scan(&alert.ID, &alert.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("Error inserting budget alert", "error", err)
+		return false, err
+	}
+
+	var userID string
+	if alert.UserID != nil {
+		userID = *alert.UserID
+	}
+	if err = writeOutbox(ctx, tx, domain.EventBudgetAlert, userID, "", alert); err != nil {
+		slog.Error("Error writing outbox event", "error", err)
+		return false, err
+	}
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_deliveries (budget_id, event_type, payload)
+		SELECT id, $2, $3 FROM budgets WHERE id = $1 AND webhook_url IS NOT NULL`,
+		alert.BudgetID, domain.EventBudgetAlert, payload)
+	if err != nil {
+		slog.Error("Error enqueueing budget webhook delivery", "error", err)
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Storage) ListBudgetAlerts(ctx context.Context, filter *domain.BudgetAlertFilter) ([]*domain.BudgetAlert, error) {
+	query := `
+		SELECT a.id, a.budget_id, b.user_id, b.category, a.month, a.kind, a.threshold, a.spend, a.budget_amount, a.created_at
+		FROM budget_alerts a JOIN budgets b ON b.id = a.budget_id`
+	args := []interface{}{}
+	conditions := []string{}
+	argIdx := 1
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "b.user_id = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.UserID)
+		argIdx++
+	}
+	if filter.BudgetID != nil {
+		conditions = append(conditions, "a.budget_id = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.BudgetID)
+		argIdx++
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.created_at DESC, a.id DESC"
+	if filter.Limit != nil {
+		query += " LIMIT $" + strconv.Itoa(argIdx)
+		args = append(args, *filter.Limit)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		slog.Error("Error querying budget alerts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]*domain.BudgetAlert, 0)
+	for rows.Next() {
+		var a domain.BudgetAlert
+		err := rows.Scan(&a.ID, &a.BudgetID, &a.UserID, &a.Category, &a.Month, &a.Kind, &a.Threshold,
+			&a.Spend, &a.BudgetAmount, &a.CreatedAt)
+		if err != nil {
+			slog.Error("Error scanning budget alert", "error", err)
+			return nil, err
+		}
+		alerts = append(alerts, &a)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return alerts, nil
+}
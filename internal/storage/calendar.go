@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SetCalendarToken создает или заменяет токен календаря пользователя
+func (s *Storage) SetCalendarToken(ctx context.Context, userID, tokenHash string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO calendar_tokens (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`,
+		userID, tokenHash)
+	if err != nil {
+		slog.Error("Error saving calendar token", "error", err)
+		return err
+	}
+	slog.Info("Calendar token issued", "user_id", userID)
+	return nil
+}
+
+func (s *Storage) CheckCalendarToken(ctx context.Context, userID, tokenHash string) (bool, error) {
+	var ok bool
+	err := s.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM calendar_tokens WHERE user_id = $1 AND token_hash = $2)",
+		userID, tokenHash).Scan(&ok)
+	if err != nil {
+		slog.Error("Error checking calendar token", "error", err)
+		return false, err
+	}
+	return ok, nil
+}
+
+func (s *Storage) DeleteCalendarToken(ctx context.Context, userID string) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM calendar_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		slog.Error("Error deleting calendar token", "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("calendar token not found")
+	}
+	slog.Info("Calendar token revoked", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+func (s *Storage) SetCatalogService(ctx context.Context, svc *domain.CatalogService) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO service_catalog (service_name, category, list_price) VALUES ($1, $2, $3)
+		ON CONFLICT (service_name) DO UPDATE SET category = EXCLUDED.category, list_price = EXCLUDED.list_price`,
+		svc.ServiceName, svc.Category, svc.ListPrice)
+	if err != nil {
+		slog.Error("Error saving catalog service", "error", err)
+		return err
+	}
+	slog.Info("Catalog service saved successfully", "service_name", svc.ServiceName, "category", svc.Category)
+	return nil
+}
+
+func (s *Storage) ListCatalog(ctx context.Context) ([]*domain.CatalogService, error) {
+	rows, err := s.pool.Query(ctx, "SELECT service_name, category, list_price FROM service_catalog ORDER BY service_name")
+	if err != nil {
+		slog.Error("Error querying catalog", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := make([]*domain.CatalogService, 0)
+	for rows.Next() {
+		var svc domain.CatalogService
+		if err := rows.Scan(&svc.ServiceName, &svc.Category, &svc.ListPrice); err != nil {
+			slog.Error("Error scanning catalog service", "error", err)
+			return nil, err
+		}
+		catalog = append(catalog, &svc)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return catalog, nil
+}
+
+func (s *Storage) DeleteCatalogService(ctx context.Context, serviceName string) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM service_catalog WHERE service_name = $1", serviceName)
+	if err != nil {
+		slog.Error("Error deleting catalog service", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No catalog service found to delete", "service_name", serviceName)
+		return fmt.Errorf("catalog service not found")
+	}
+	slog.Info("Catalog service deleted successfully", "service_name", serviceName)
+	return nil
+}
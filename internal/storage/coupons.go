@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+	"time"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func (s *Storage) CreateCoupon(ctx context.Context, coupon *domain.Coupon) error {
+	_, err := s.pool.Exec(ctx,
+		"INSERT INTO coupons (code, kind, percent, amount, duration_months) VALUES ($1, $2, $3, $4, $5)",
+		coupon.Code, coupon.Kind, nullIfZero(int64(coupon.Percent)), nullIfZero(int64(coupon.Amount)), coupon.DurationMonths)
+	if err != nil {
+		slog.Error("Error inserting coupon", "error", err)
+		return err
+	}
+	slog.Info("Coupon created successfully", "code", coupon.Code)
+	return nil
+}
+
+func (s *Storage) ListCoupons(ctx context.Context) ([]*domain.Coupon, error) {
+	rows, err := s.pool.Query(ctx, "SELECT code, kind, percent, amount, duration_months FROM coupons ORDER BY code")
+	if err != nil {
+		slog.Error("Error querying coupons", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := make([]*domain.Coupon, 0)
+	for rows.Next() {
+		var code, kind string
+		var percent, amount *int64
+		var duration int
+		if err := rows.Scan(&code, &kind, &percent, &amount, &duration); err != nil {
+			slog.Error("Error scanning coupon", "error", err)
+			return nil, err
+		}
+		coupons = append(coupons, newCoupon(code, kind, percent, amount, duration))
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func newCoupon(code, kind string, percent, amount *int64, duration int) *domain.Coupon {
+	c := &domain.Coupon{Code: code, Kind: kind, DurationMonths: duration}
+	if percent != nil {
+		c.Percent = int(*percent)
+	}
+	if amount != nil {
+		c.Amount = domain.Money(*amount)
+	}
+	return c
+}
+
+func nullIfZero(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// DeleteCoupon удаляет промокод, у привязанных подписок скидка перестает действовать
+func (s *Storage) DeleteCoupon(ctx context.Context, code string) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM coupons WHERE code = $1", code)
+	if err != nil {
+		slog.Error("Error deleting coupon", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No coupon found to delete", "code", code)
+		return fmt.Errorf("coupon not found")
+	}
+	slog.Info("Coupon deleted successfully", "code", code)
+	return nil
+}
+
+// AttachCoupon если start не указан, скидка действует с начала подписки
+func (s *Storage) AttachCoupon(ctx context.Context, userID, serviceName string, code *string, start *time.Time) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE subscriptions
+		SET coupon_code = $1::text,
+		    coupon_start = CASE WHEN $1::text IS NULL THEN NULL ELSE COALESCE($2::date, start_date) END
+		WHERE user_id = $3 AND service_name = $4`,
+		code, start, userID, serviceName)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("coupon not found")
+		}
+		slog.Error("Error attaching coupon", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No subscription found to attach coupon", "user_id", userID, "service_name", serviceName)
+		return fmt.Errorf("subscription not found")
+	}
+	slog.Info("Subscription coupon updated", "user_id", userID, "service_name", serviceName, "code", code)
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+// auditLimit сколько последних событий журнала подгружается для каждой подписки
+const auditLimit = 20
+
+// expandLoaders загрузчики связанных данных для каждого значения domain.ExpandFields
+var expandLoaders = map[string]func(s *Storage, ctx context.Context, subs []*domain.Subscription) error{
+	domain.ExpandPriceChanges: (*Storage).loadPriceChanges,
+	domain.ExpandCatalog:      (*Storage).loadCatalog,
+	domain.ExpandAudit:        (*Storage).loadAudit,
+}
+
+// expand подгружает связанные данные результатов поиска, по одному запросу на вид данных
+func (s *Storage) expand(ctx context.Context, subs []*domain.Subscription, expand []string) error {
+	if len(subs) == 0 {
+		return nil
+	}
+	for _, name := range expand {
+		load, ok := expandLoaders[name]
+		if !ok {
+			return fmt.Errorf("unknown expand %q", name)
+		}
+		if err := load(s, ctx, subs); err != nil {
+			slog.Error("Error expanding subscriptions", "expand", name, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) loadCatalog(ctx context.Context, subs []*domain.Subscription) error {
+	names := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		names = append(names, sub.ServiceName)
+	}
+	rows, err := s.pool.Query(ctx,
+		"SELECT service_name, category, list_price FROM service_catalog WHERE service_name = ANY($1)", names)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	catalog := make([]*domain.CatalogService, 0)
+	for rows.Next() {
+		var svc domain.CatalogService
+		if err := rows.Scan(&svc.ServiceName, &svc.Category, &svc.ListPrice); err != nil {
+			return err
+		}
+		catalog = append(catalog, &svc)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	attachCatalog(subs, catalog)
+	return nil
+}
+
+// attachCatalog записывает в подписки карточки каталога по названию сервиса
+func attachCatalog(subs []*domain.Subscription, catalog []*domain.CatalogService) {
+	byName := make(map[string]*domain.CatalogService, len(catalog))
+	for _, svc := range catalog {
+		byName[svc.ServiceName] = svc
+	}
+	for _, sub := range subs {
+		sub.Catalog = byName[sub.ServiceName]
+	}
+}
+
+// loadAudit последние события журнала outbox по паре (user_id, service_name) каждой подписки
+func (s *Storage) loadAudit(ctx context.Context, subs []*domain.Subscription) error {
+	userIDs := make([]string, 0, len(subs))
+	names := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		userIDs = append(userIDs, sub.UserID)
+		names = append(names, sub.ServiceName)
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, event_type, user_id, service_name, payload, created_at
+		FROM (
+			SELECT o.*, row_number() OVER (PARTITION BY o.user_id, o.service_name ORDER BY o.id DESC) AS rn
+			FROM outbox o
+			WHERE (o.user_id, o.service_name) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+			  AND o.event_type = ANY($3)
+		) a
+		WHERE rn <= $4
+		ORDER BY id`,
+		userIDs, names, domain.AuditEventTypes, auditLimit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	messages := make([]*domain.OutboxMessage, 0)
+	for rows.Next() {
+		var m domain.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventType, &m.UserID, &m.ServiceName, &m.Payload, &m.CreatedAt); err != nil {
+			return err
+		}
+		messages = append(messages, &m)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	attachAudit(subs, messages)
+	return nil
+}
+
+// attachAudit раскладывает события журнала по подпискам с той же парой (user_id, service_name)
+// с сохранением порядка messages
+func attachAudit(subs []*domain.Subscription, messages []*domain.OutboxMessage) {
+	type key struct{ userID, serviceName string }
+	audit := make(map[key][]*domain.OutboxMessage)
+	for _, m := range messages {
+		if m.UserID == nil || m.ServiceName == nil {
+			continue
+		}
+		k := key{userID: *m.UserID, serviceName: *m.ServiceName}
+		audit[k] = append(audit[k], m)
+	}
+	for _, sub := range subs {
+		sub.Audit = audit[key{userID: sub.UserID, serviceName: sub.ServiceName}]
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+)
+
+func TestExpandLoaders(t *testing.T) {
+	// каждое значение, которое пропускает api, должно иметь загрузчик, и наоборот
+	for name := range domain.ExpandFields {
+		if expandLoaders[name] == nil {
+			t.Errorf("no loader for expand %q", name)
+		}
+	}
+	for name := range expandLoaders {
+		if !domain.ExpandFields[name] {
+			t.Errorf("loader %q is not in domain.ExpandFields", name)
+		}
+	}
+}
+
+func TestExpand_Unknown(t *testing.T) {
+	s := &Storage{}
+	subs := []*domain.Subscription{{ID: 1}}
+	err := s.expand(context.Background(), subs, []string{"members"})
+	if err == nil || err.Error() != `unknown expand "members"` {
+		t.Errorf("expand() error = %v, want unknown expand", err)
+	}
+	// без подписок запросы не выполняются
+	if err := s.expand(context.Background(), nil, []string{domain.ExpandAudit}); err != nil {
+		t.Errorf("expand() without subscriptions error = %v", err)
+	}
+}
+
+func TestAttachPriceChanges(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*domain.Subscription{{ID: 1}, {ID: 2}}
+	changes := map[int][]domain.PriceChange{
+		1: {{EffectiveDate: jan, Price: 29900}, {EffectiveDate: mar, Price: 34900}},
+		3: {{EffectiveDate: jan, Price: 100}},
+	}
+	attachPriceChanges(subs, changes)
+	if !reflect.DeepEqual(subs[0].PriceChanges, changes[1]) {
+		t.Errorf("subscription 1 price changes = %+v", subs[0].PriceChanges)
+	}
+	if subs[1].PriceChanges != nil {
+		t.Errorf("subscription 2 price changes = %+v, want none", subs[1].PriceChanges)
+	}
+}
+
+func TestAttachCatalog(t *testing.T) {
+	listPrice := domain.Money(29900)
+	subs := []*domain.Subscription{
+		{ID: 1, ServiceName: "Yandex Plus"},
+		{ID: 2, ServiceName: "Netflix"},
+		{ID: 3, ServiceName: "Yandex Plus"},
+	}
+	plus := &domain.CatalogService{ServiceName: "Yandex Plus", Category: "music", ListPrice: &listPrice}
+	attachCatalog(subs, []*domain.CatalogService{plus})
+	if subs[0].Catalog != plus || subs[2].Catalog != plus {
+		t.Errorf("catalog = %+v, %+v, want %+v", subs[0].Catalog, subs[2].Catalog, plus)
+	}
+	if subs[1].Catalog != nil {
+		t.Errorf("subscription without catalog entry got %+v", subs[1].Catalog)
+	}
+}
+
+func TestAttachAudit(t *testing.T) {
+	u1, u2 := "123e4567-e89b-12d3-a456-426614174000", "223e4567-e89b-12d3-a456-426614174000"
+	plus, netflix := "Yandex Plus", "Netflix"
+	message := func(id int64, user, service *string) *domain.OutboxMessage {
+		return &domain.OutboxMessage{ID: id, EventType: domain.EventUpdated, UserID: user, ServiceName: service}
+	}
+	subs := []*domain.Subscription{
+		{ID: 1, UserID: u1, ServiceName: plus},
+		{ID: 2, UserID: u2, ServiceName: plus},
+		{ID: 3, UserID: u1, ServiceName: netflix},
+	}
+	messages := []*domain.OutboxMessage{
+		message(10, &u1, &plus),
+		message(11, &u2, &plus),
+		message(12, &u1, &plus),
+		// событие без подписки, например budget.alert, пропускается
+		message(13, &u1, nil),
+	}
+	attachAudit(subs, messages)
+
+	ids := func(audit []*domain.OutboxMessage) []int64 {
+		var res []int64
+		for _, m := range audit {
+			res = append(res, m.ID)
+		}
+		return res
+	}
+	if got := ids(subs[0].Audit); !reflect.DeepEqual(got, []int64{10, 12}) {
+		t.Errorf("audit of subscription 1 = %v, want [10 12]", got)
+	}
+	if got := ids(subs[1].Audit); !reflect.DeepEqual(got, []int64{11}) {
+		t.Errorf("audit of subscription 2 = %v, want [11]", got)
+	}
+	if subs[2].Audit != nil {
+		t.Errorf("audit of subscription 3 = %v, want none", ids(subs[2].Audit))
+	}
+}
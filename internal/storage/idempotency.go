@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+)
+
+// ReserveIdempotencyKey вставка и захват просроченного ключа выполняются одним запросом,
+// поэтому из одновременных запросов с одним ключом выполняется только один
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (*domain.IdempotencyRecord, error) {
+	// ключ могут удалить между попыткой захвата и чтением, тогда захват повторяется
+	for attempt := 0; attempt < 2; attempt++ {
+		var reserved bool
+		err := s.pool.QueryRow(ctx, `
+			INSERT INTO idempotency_keys (key, request_hash, expires_at)
+			VALUES ($1, $2, now() + make_interval(secs => $3))
+			ON CONFLICT (key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response_body = NULL,
+			    created_at = now(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < now()
+			   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < now() - make_interval(secs => $4))
+			RETURNING true`,
+			key, requestHash, ttl.Seconds(), lockTimeout.Seconds()).Scan(&reserved)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Error reserving idempotency key", "error", err)
+			return nil, err
+		}
+
+		rec := domain.IdempotencyRecord{Key: key}
+		var statusCode *int
+		var contentType *string
+		var body []byte
+		err = s.pool.QueryRow(ctx, `
+			SELECT request_hash, status_code, content_type, response_body, expires_at
+			FROM idempotency_keys WHERE key = $1`, key).
+			Scan(&rec.RequestHash, &statusCode, &contentType, &body, &rec.ExpiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			slog.Error("Error getting idempotency key", "error", err)
+			return nil, err
+		}
+		if statusCode != nil {
+			rec.Response = &domain.IdempotentResponse{StatusCode: *statusCode, Body: body}
+			if contentType != nil {
+				rec.Response.ContentType = *contentType
+			}
+		}
+		return &rec, nil
+	}
+	return nil, domain.ErrIdempotencyInProgress
+}
+
+func (s *Storage) SaveIdempotentResponse(ctx context.Context, key string, resp *domain.IdempotentResponse) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $1, content_type = $2, response_body = $3
+		WHERE key = $4`,
+		resp.StatusCode, resp.ContentType, resp.Body, key)
+	if err != nil {
+		slog.Error("Error saving idempotent response", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	if err != nil {
+		slog.Error("Error deleting idempotency key", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now()")
+	if err != nil {
+		slog.Error("Error deleting expired idempotency keys", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"time"
+)
+
+// StreamSubscriptions читает подписки по фильтру Search построчно и передает их в fn,
+// ошибка fn прерывает чтение
+func (s *Storage) StreamSubscriptions(ctx context.Context, filter *domain.Filter, fn func(*domain.Subscription) error) error {
+	query, args, err := searchQuery(filter)
+	if err != nil {
+		return err
+	}
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		slog.Error("Error querying subscriptions", "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			slog.Error("Error scanning subscription", "error", err)
+			return err
+		}
+		if err = fn(sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportSubscriptions записывает подписки в одной транзакции, каждую в своей точке сохранения:
+// существующая по (user_id, service_name) подписка обновляется, иначе создается.
+// Ошибка подписки откатывает только ее, при dryRun транзакция откатывается целиком.
+func (s *Storage) ImportSubscriptions(ctx context.Context, subs []*domain.Subscription, dryRun bool, month time.Time) ([]domain.ImportResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]domain.ImportResult, len(subs))
+	for i, sub := range subs {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var exists bool
+		err = savepoint.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM subscriptions WHERE user_id = $1 AND service_name = $2)",
+			sub.UserID, sub.ServiceName).Scan(&exists)
+		if err == nil {
+			if exists {
+				results[i].Status = domain.ImportUpdated
+				err = updateSubscription(ctx, savepoint, sub, month)
+			} else {
+				results[i].Status = domain.ImportCreated
+				err = createSubscription(ctx, savepoint, sub)
+			}
+		}
+		if err != nil {
+			savepoint.Rollback(ctx)
+			msg, ok := batchItemError(err)
+			if !ok {
+				slog.Error("Error importing subscriptions", "index", i, "error", err)
+				return nil, err
+			}
+			results[i] = domain.ImportResult{Status: domain.ImportFailed, Error: msg}
+			continue
+		}
+		if err = savepoint.Commit(ctx); err != nil {
+			return nil, err
+		}
+		results[i].ID = sub.ID
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	slog.Info("Subscriptions imported", "rows", len(subs))
+	return results, nil
+}
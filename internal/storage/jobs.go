@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+	"time"
+)
+
+const jobColumns = `id, kind, params, status, progress, attempts, error, content_type, octet_length(result),
+	created_at, started_at, finished_at`
+
+func scanJob(row pgx.Row) (*domain.Job, error) {
+	var job domain.Job
+	err := row.Scan(&job.ID, &job.Kind, &job.Params, &job.Status, &job.Progress, &job.Attempts, &job.Error,
+		&job.ContentType, &job.ResultSize, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// isInvalidJobID id задачи не является UUID, такой задачи быть не может
+func isInvalidJobID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02"
+}
+
+func (s *Storage) CreateJob(ctx context.Context, job *domain.Job) error {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO jobs (kind, params) VALUES ($1, $2)
+		RETURNING id, status, created_at`,
+		job.Kind, job.Params).Scan(&job.ID, &job.Status, &job.CreatedAt)
+	if err != nil {
+		slog.Error("Error creating job", "kind", job.Kind, "error", err)
+		return err
+	}
+	slog.Info("Job queued", "id", job.ID, "kind", job.Kind)
+	return nil
+}
+
+func (s *Storage) GetJob(ctx context.Context, id string) (*domain.Job, error) {
+	job, err := scanJob(s.pool.QueryRow(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidJobID(err) {
+		return nil, domain.ErrJobNotFound
+	}
+	if err != nil {
+		slog.Error("Error getting job", "id", id, "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *Storage) GetJobResult(ctx context.Context, id string) (*domain.JobResult, error) {
+	var res domain.JobResult
+	err := s.pool.QueryRow(ctx, `
+		SELECT content_type, file_name, result FROM jobs
+		WHERE id = $1 AND status = 'done'`, id).Scan(&res.ContentType, &res.FileName, &res.Data)
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidJobID(err) {
+		return nil, domain.ErrJobNotFound
+	}
+	if err != nil {
+		slog.Error("Error getting job result", "id", id, "error", err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ClaimJob SKIP LOCKED позволяет запускать несколько воркеров в одном и в разных процессах
+func (s *Storage) ClaimJob(ctx context.Context, stale time.Duration, maxAttempts int) (*domain.Job, error) {
+	job, err := scanJob(s.pool.QueryRow(ctx, `
+		WITH next AS (
+			SELECT id FROM jobs
+			WHERE status = 'queued'
+			   OR (status = 'running' AND heartbeat_at < now() - make_interval(secs => $1) AND attempts < $2)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running', attempts = j.attempts + 1, progress = 0, error = NULL,
+		    started_at = now(), heartbeat_at = now()
+		FROM next
+		WHERE j.id = next.id
+		RETURNING `+jobColumns,
+		stale.Seconds(), maxAttempts))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error claiming job", "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *Storage) UpdateJobProgress(ctx context.Context, id string, progress int) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE jobs SET progress = $1, heartbeat_at = now()
+		WHERE id = $2 AND status = 'running'`, progress, id)
+	if err != nil {
+		slog.Error("Error updating job progress", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) CompleteJob(ctx context.Context, id string, progress int, result *domain.JobResult) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = 'done', progress = $1, content_type = $2, file_name = $3, result = $4, finished_at = now()
+		WHERE id = $5`,
+		progress, result.ContentType, result.FileName, result.Data, id)
+	if err != nil {
+		slog.Error("Error completing job", "id", id, "error", err)
+		return err
+	}
+	slog.Info("Job done", "id", id, "bytes", len(result.Data))
+	return nil
+}
+
+func (s *Storage) FailJob(ctx context.Context, id string, message string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'failed', error = $1, finished_at = now()
+		WHERE id = $2`, message, id)
+	if err != nil {
+		slog.Error("Error failing job", "id", id, "error", err)
+		return err
+	}
+	slog.Warn("Job failed", "id", id, "error", message)
+	return nil
+}
+
+func (s *Storage) ReleaseJob(ctx context.Context, id string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'queued', attempts = attempts - 1, progress = 0, started_at = NULL, heartbeat_at = NULL
+		WHERE id = $1 AND status = 'running'`, id)
+	if err != nil {
+		slog.Error("Error releasing job", "id", id, "error", err)
+		return err
+	}
+	slog.Info("Job returned to queue", "id", id)
+	return nil
+}
+
+func (s *Storage) FailStaleJobs(ctx context.Context, stale time.Duration, maxAttempts int) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'failed', error = 'worker stopped responding', finished_at = now()
+		WHERE status = 'running' AND heartbeat_at < now() - make_interval(secs => $1) AND attempts >= $2`,
+		stale.Seconds(), maxAttempts)
+	if err != nil {
+		slog.Error("Error failing stale jobs", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) DeleteFinishedJobs(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM jobs WHERE status IN ('done', 'failed') AND finished_at < $1`, before)
+	if err != nil {
+		slog.Error("Error deleting finished jobs", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
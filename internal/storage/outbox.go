@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+)
+
+// writeOutbox записывает событие в outbox в транзакции изменения, поэтому событие
+// сохраняется тогда и только тогда, когда фиксируется само изменение
+func writeOutbox(ctx context.Context, tx pgx.Tx, eventType, userID, serviceName string, data interface{}) error {
+	payload, err := json.Marshal(domain.Event{
+		Type:        eventType,
+		UserID:      userID,
+		ServiceName: serviceName,
+		OccurredAt:  time.Now().UTC(),
+		Data:        data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO outbox (event_type, user_id, service_name, payload) VALUES ($1, $2, $3, $4)",
+		eventType, nullIfEmpty(userID), nullIfEmpty(serviceName), payload)
+	return err
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// outboxRelayLock ключ advisory lock, под которым relay публикуют сообщения по очереди
+const outboxRelayLock = 7301
+
+func (s *Storage) RelayOutbox(ctx context.Context, limit int,
+	publish func(ctx context.Context, msg *domain.OutboxMessage) error) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// relay из разных процессов публикуют по очереди до фиксации транзакции, поэтому номера seq
+	// становятся видимыми в журнале в порядке возрастания и читатель журнала их не пропускает
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", outboxRelayLock); err != nil {
+		slog.Error("Error locking outbox relay", "error", err)
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_type, user_id, service_name, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE`, limit)
+	if err != nil {
+		slog.Error("Error querying outbox", "error", err)
+		return 0, err
+	}
+	messages := make([]*domain.OutboxMessage, 0)
+	for rows.Next() {
+		var m domain.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventType, &m.UserID, &m.ServiceName, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			slog.Error("Error scanning outbox message", "error", err)
+			return 0, err
+		}
+		messages = append(messages, &m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return 0, err
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	// номера публикации выделяются блоком, неиспользованные при ошибке остаются пропуском
+	var lastSeq int64
+	err = tx.QueryRow(ctx, "SELECT setval('outbox_publish_seq', nextval('outbox_publish_seq') + $1 - 1)",
+		len(messages)).Scan(&lastSeq)
+	if err != nil {
+		slog.Error("Error allocating outbox sequence", "error", err)
+		return 0, err
+	}
+	firstSeq := lastSeq - int64(len(messages)) + 1
+
+	published := make([]int64, 0, len(messages))
+	seqs := make([]int64, 0, len(messages))
+	var publishErr error
+	for i, m := range messages {
+		m.Seq = firstSeq + int64(i)
+		if publishErr = publish(ctx, m); publishErr != nil {
+			break
+		}
+		published = append(published, m.ID)
+		seqs = append(seqs, m.Seq)
+	}
+
+	if len(published) > 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE outbox o SET published_at = now(), seq = p.seq
+			FROM unnest($1::bigint[], $2::bigint[]) AS p(id, seq)
+			WHERE o.id = p.id`, published, seqs)
+		if err != nil {
+			slog.Error("Error marking outbox messages published", "error", err)
+			return 0, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return len(published), publishErr
+}
+
+func (s *Storage) LastOutboxSeq(ctx context.Context, userID string) (int64, error) {
+	var seq int64
+	err := s.pool.QueryRow(ctx,
+		"SELECT COALESCE(MAX(seq), 0) FROM outbox WHERE user_id = $1 AND seq IS NOT NULL", userID).Scan(&seq)
+	if err != nil {
+		slog.Error("Error querying outbox sequence", "error", err)
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (s *Storage) ListOutbox(ctx context.Context, filter *domain.OutboxFilter) ([]*domain.OutboxMessage, error) {
+	var eventTypes []string
+	if len(filter.EventTypes) > 0 {
+		eventTypes = filter.EventTypes
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, seq, event_type, user_id, service_name, payload, created_at
+		FROM outbox
+		WHERE seq > $1
+		  AND ($2::text IS NULL OR user_id = $2)
+		  AND ($3::text[] IS NULL OR event_type = ANY($3))
+		ORDER BY seq
+		LIMIT $4`,
+		filter.AfterSeq, filter.UserID, eventTypes, filter.Limit)
+	if err != nil {
+		slog.Error("Error querying outbox", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*domain.OutboxMessage, 0)
+	for rows.Next() {
+		var m domain.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.Seq, &m.EventType, &m.UserID, &m.ServiceName, &m.Payload, &m.CreatedAt); err != nil {
+			slog.Error("Error scanning outbox message", "error", err)
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// SearchPage поиск по курсору (keyset): вместо OFFSET страница начинается со строк,
+// идущих в порядке сортировки после последней строки предыдущей страницы
+func (s *Storage) SearchPage(ctx context.Context, filter *domain.Filter) (*domain.Page, error) {
+	conditions, args, argIdx, err := searchConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	sort := domain.PageSort(filter.Sort)
+	orderBy, err := sortClause(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page{}
+	if filter.WithTotal {
+		countQuery := "SELECT count(*) FROM " + subscriptionSource
+		if len(conditions) > 0 {
+			countQuery += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		var total int
+		if err := s.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			slog.Error("Error counting subscriptions", "error", err)
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	if filter.Cursor != nil {
+		cursor, err := domain.DecodeCursor(*filter.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		var cond string
+		cond, args, argIdx = keysetCondition(sort, cursor.Values, args, argIdx)
+		conditions = append(conditions, cond)
+	}
+
+	query := "SELECT " + subscriptionColumns + " FROM " + subscriptionSource
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// лишняя строка показывает, что есть следующая страница
+	query += " ORDER BY " + orderBy + " LIMIT $" + strconv.Itoa(argIdx)
+	args = append(args, *filter.Limit+1)
+
+	subs, err := s.querySubscriptions(ctx, query, args...)
+	if err != nil {
+		slog.Error("Error querying subscriptions page", "error", err)
+		return nil, err
+	}
+	if len(subs) > *filter.Limit {
+		subs = subs[:*filter.Limit]
+		next := domain.EncodeCursor(sort, subs[len(subs)-1])
+		page.NextCursor = &next
+	}
+	if err = s.expand(ctx, subs, filter.Expand); err != nil {
+		return nil, err
+	}
+	page.Items = subs
+	return page, nil
+}
+
+// keysetCondition условие "строка после курсора" для сортировки sort:
+// (f1 после v1) OR (f1 = v1 AND f2 после v2) OR ...
+// Postgres ставит NULL последними при ASC и первыми при DESC, условия это учитывают.
+func keysetCondition(sort []domain.SortField, values []interface{}, args []interface{}, argIdx int) (string, []interface{}, int) {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			args = append(args, v)
+			placeholders[i] = "$" + strconv.Itoa(argIdx)
+			argIdx++
+		}
+	}
+
+	var disjuncts []string
+	for i, f := range sort {
+		column := sortColumns[f.Field]
+		var after string
+		switch {
+		case values[i] == nil && f.Desc:
+			after = column + " IS NOT NULL"
+		case values[i] == nil:
+			// после NULL при ASC ничего нет
+			continue
+		case f.Desc:
+			after = column + " < " + placeholders[i]
+		case domain.QueryFields[f.Field].Nullable:
+			after = "(" + column + " > " + placeholders[i] + " OR " + column + " IS NULL)"
+		default:
+			after = column + " > " + placeholders[i]
+		}
+
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			prev := sortColumns[sort[j].Field]
+			if values[j] == nil {
+				parts = append(parts, prev+" IS NULL")
+			} else {
+				parts = append(parts, prev+" = "+placeholders[j])
+			}
+		}
+		parts = append(parts, after)
+		disjuncts = append(disjuncts, "("+strings.Join(parts, " AND ")+")")
+	}
+	if len(disjuncts) == 0 {
+		return "FALSE", args, argIdx
+	}
+	return "(" + strings.Join(disjuncts, " OR ") + ")", args, argIdx
+}
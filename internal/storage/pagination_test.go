@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := domain.Money(29900)
+
+	tests := []struct {
+		name   string
+		sort   []domain.SortField
+		values []interface{}
+		// args уже содержит параметры фильтра, нумерация продолжается с argIdx
+		args   []interface{}
+		argIdx int
+		want   string
+		// wantArgs параметры после условия
+		wantArgs []interface{}
+	}{
+		{name: "id only", sort: []domain.SortField{{Field: "id"}}, values: []interface{}{7},
+			args: []interface{}{}, argIdx: 1,
+			want: "((s.id > $1))", wantArgs: []interface{}{7}},
+		{name: "id desc", sort: []domain.SortField{{Field: "id", Desc: true}}, values: []interface{}{7},
+			args: []interface{}{}, argIdx: 1,
+			want: "((s.id < $1))", wantArgs: []interface{}{7}},
+		// равные значения первого поля упорядочены по id
+		{name: "tiebreak by id", sort: []domain.SortField{{Field: "price"}, {Field: "id"}}, values: []interface{}{price, 7},
+			args: []interface{}{"u1"}, argIdx: 2,
+			want:     "((s.price > $2) OR (s.price = $2 AND s.id > $3))",
+			wantArgs: []interface{}{"u1", price, 7}},
+		{name: "desc then asc", sort: []domain.SortField{{Field: "start_date", Desc: true}, {Field: "service_name"}, {Field: "id"}},
+			values: []interface{}{jan, "Netflix", 3}, args: []interface{}{}, argIdx: 1,
+			want: "((s.start_date < $1) OR (s.start_date = $1 AND s.service_name > $2) OR " +
+				"(s.start_date = $1 AND s.service_name = $2 AND s.id > $3))",
+			wantArgs: []interface{}{jan, "Netflix", 3}},
+		// при ASC null идут последними: после значения следуют большие значения и все null
+		{name: "nullable asc value", sort: []domain.SortField{{Field: "end_date"}, {Field: "id"}}, values: []interface{}{jan, 3},
+			args: []interface{}{}, argIdx: 1,
+			want:     "(((s.end_date > $1 OR s.end_date IS NULL)) OR (s.end_date = $1 AND s.id > $2))",
+			wantArgs: []interface{}{jan, 3}},
+		// после null при ASC остаются только строки с тем же null и большим id
+		{name: "nullable asc null", sort: []domain.SortField{{Field: "category"}, {Field: "id"}}, values: []interface{}{nil, 3},
+			args: []interface{}{}, argIdx: 1,
+			want:     "((cat.category IS NULL AND s.id > $1))",
+			wantArgs: []interface{}{3}},
+		// при DESC null идут первыми: после null следуют все значения
+		{name: "nullable desc null", sort: []domain.SortField{{Field: "end_date", Desc: true}, {Field: "id"}}, values: []interface{}{nil, 3},
+			args: []interface{}{}, argIdx: 1,
+			want:     "((s.end_date IS NOT NULL) OR (s.end_date IS NULL AND s.id > $1))",
+			wantArgs: []interface{}{3}},
+		{name: "nullable desc value", sort: []domain.SortField{{Field: "end_date", Desc: true}, {Field: "id"}}, values: []interface{}{jan, 3},
+			args: []interface{}{}, argIdx: 1,
+			want:     "((s.end_date < $1) OR (s.end_date = $1 AND s.id > $2))",
+			wantArgs: []interface{}{jan, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, argIdx := keysetCondition(tt.sort, tt.values, tt.args, tt.argIdx)
+			if got != tt.want {
+				t.Errorf("keysetCondition() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+			if argIdx != len(tt.wantArgs)+1 {
+				t.Errorf("argIdx = %d, want %d", argIdx, len(tt.wantArgs)+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+)
+
+func (s *Storage) SetPriceChange(ctx context.Context, userID, serviceName string, change *domain.PriceChange) error {
+	res, err := s.pool.Exec(ctx, `
+		INSERT INTO price_changes (subscription_id, effective_date, price)
+		SELECT id, $3, $4 FROM subscriptions WHERE user_id = $1 AND service_name = $2
+		ON CONFLICT (subscription_id, effective_date) DO UPDATE SET price = EXCLUDED.price`,
+		userID, serviceName, change.EffectiveDate, change.Price)
+	if err != nil {
+		slog.Error("Error saving price change", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No subscription found for price change", "user_id", userID, "service_name", serviceName)
+		return fmt.Errorf("subscription not found")
+	}
+	slog.Info("Price change saved successfully", "user_id", userID, "service_name", serviceName,
+		"effective_date", change.EffectiveDate, "price", change.Price)
+	return nil
+}
+
+func (s *Storage) ListPriceChanges(ctx context.Context, userID, serviceName string) ([]domain.PriceChange, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT pc.effective_date, pc.price
+		FROM price_changes pc JOIN subscriptions s ON s.id = pc.subscription_id
+		WHERE s.user_id = $1 AND s.service_name = $2
+		ORDER BY pc.effective_date`,
+		userID, serviceName)
+	if err != nil {
+		slog.Error("Error querying price changes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]domain.PriceChange, 0)
+	for rows.Next() {
+		var c domain.PriceChange
+		if err := rows.Scan(&c.EffectiveDate, &c.Price); err != nil {
+			slog.Error("Error scanning price change", "error", err)
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+// DeletePriceChange удаляет изменение цены и в той же транзакции пересчитывает цену подписки
+// на месяц month, если удаленное изменение уже действовало
+func (s *Storage) DeletePriceChange(ctx context.Context, userID, serviceName string, effective, month time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var subID int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM price_changes pc USING subscriptions s
+		WHERE s.id = pc.subscription_id AND s.user_id = $1 AND s.service_name = $2 AND pc.effective_date = $3
+		RETURNING pc.subscription_id`,
+		userID, serviceName, effective).Scan(&subID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		slog.Warn("No price change found to delete", "user_id", userID, "service_name", serviceName, "effective_date", effective)
+		return fmt.Errorf("price change not found")
+	}
+	if err != nil {
+		slog.Error("Error deleting price change", "error", err)
+		return err
+	}
+	if _, err = applyDuePrices(ctx, tx, month, []int{subID}); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	slog.Info("Price change deleted successfully", "user_id", userID, "service_name", serviceName, "effective_date", effective)
+	return nil
+}
+
+// ApplyDuePriceChanges прежняя цена сохраняется в истории с даты начала, если история ее
+// не покрывает, чтобы прошлые месяцы считались по ней. Вместе с новой ценой в outbox
+// записывается subscription.updated.
+func (s *Storage) ApplyDuePriceChanges(ctx context.Context, month time.Time) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	n, err := applyDuePrices(ctx, tx, month, nil)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// dueHistory последнее изменение цены каждой подписки, вступившее в силу не позже $1
+const dueHistory = `
+		SELECT DISTINCT ON (subscription_id) subscription_id, price
+		FROM price_changes
+		WHERE effective_date <= $1
+		ORDER BY subscription_id, effective_date DESC`
+
+// applyDuePrices записывает в цену подписок последнее изменение, вступившее в силу не позже month,
+// и пишет subscription.updated для изменившихся. only ограничивает подписки, nil - все.
+func applyDuePrices(ctx context.Context, tx pgx.Tx, month time.Time, only []int) (int, error) {
+	rows, err := tx.Query(ctx, `
+		WITH due AS (`+dueHistory+`)
+		SELECT s.id FROM subscriptions s JOIN due ON due.subscription_id = s.id
+		WHERE s.price <> due.price AND ($2::int[] IS NULL OR s.id = ANY($2))
+		FOR UPDATE OF s`, month, only)
+	if err != nil {
+		slog.Error("Error querying due price changes", "error", err)
+		return 0, err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			slog.Error("Error scanning due price changes", "error", err)
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_changes (subscription_id, effective_date, price)
+		SELECT s.id, s.start_date, s.price FROM subscriptions s
+		WHERE s.id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM price_changes pc WHERE pc.subscription_id = s.id AND pc.effective_date <= s.start_date)`,
+		ids)
+	if err != nil {
+		slog.Error("Error recording base price", "error", err)
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `
+		WITH due AS (`+dueHistory+`)
+		UPDATE subscriptions s SET price = due.price
+		FROM due WHERE due.subscription_id = s.id AND s.id = ANY($2)`,
+		month, ids)
+	if err != nil {
+		slog.Error("Error applying price changes", "error", err)
+		return 0, err
+	}
+
+	rows, err = tx.Query(ctx, "SELECT "+subscriptionColumns+" FROM "+subscriptionSource+" WHERE s.id = ANY($1)", ids)
+	if err != nil {
+		return 0, err
+	}
+	subs := make([]*domain.Subscription, 0, len(ids))
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		subs = append(subs, sub)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, sub := range subs {
+		if err = writeOutbox(ctx, tx, domain.EventUpdated, sub.UserID, sub.ServiceName, sub); err != nil {
+			slog.Error("Error writing outbox event", "error", err)
+			return 0, err
+		}
+	}
+	return len(subs), nil
+}
+
+// recordsPriceHistory нужно ли записывать в историю новую цену подписки, начавшейся в startDate,
+// при обновлении в месяце month. До начала подписки прошлых месяцев нет, и достаточно самой цены.
+func recordsPriceHistory(startDate, month time.Time, oldPrice, newPrice domain.Money) bool {
+	return oldPrice != newPrice && !startDate.After(month)
+}
+
+// recordPriceChange фиксирует в истории новую цену с месяца month, чтобы ее не перекрывали
+// изменения, вступившие в силу раньше. Если история не покрывает дату начала, прежняя цена
+// записывается с нее, чтобы прошлые месяцы считались по ней.
+func recordPriceChange(ctx context.Context, tx pgx.Tx, subID int, oldStart, month time.Time, oldPrice, newPrice domain.Money) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO price_changes (subscription_id, effective_date, price)
+		SELECT $1, $2, $3 WHERE NOT EXISTS (
+			SELECT 1 FROM price_changes WHERE subscription_id = $1 AND effective_date <= $2)`,
+		subID, oldStart, oldPrice)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_changes (subscription_id, effective_date, price)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (subscription_id, effective_date) DO UPDATE SET price = EXCLUDED.price`,
+		subID, month, newPrice)
+	return err
+}
+
+// loadPriceChanges подгружает историю цен для расчетов по месяцам одним запросом
+func (s *Storage) loadPriceChanges(ctx context.Context, subs []*domain.Subscription) error {
+	if len(subs) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT subscription_id, effective_date, price FROM price_changes WHERE subscription_id = ANY($1) ORDER BY effective_date",
+		ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	changes := make(map[int][]domain.PriceChange)
+	for rows.Next() {
+		var subID int
+		var c domain.PriceChange
+		if err := rows.Scan(&subID, &c.EffectiveDate, &c.Price); err != nil {
+			return err
+		}
+		changes[subID] = append(changes[subID], c)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	attachPriceChanges(subs, changes)
+	return nil
+}
+
+// attachPriceChanges записывает в подписки их изменения цены по ID подписки
+func attachPriceChanges(subs []*domain.Subscription, changes map[int][]domain.PriceChange) {
+	for _, sub := range subs {
+		sub.PriceChanges = changes[sub.ID]
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+)
+
+func TestRecordsPriceHistory(t *testing.T) {
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		oldPrice domain.Money
+		newPrice domain.Money
+		want     bool
+	}{
+		{name: "started earlier", start: feb, oldPrice: 29900, newPrice: 34900, want: true},
+		{name: "same price", start: feb, oldPrice: 29900, newPrice: 29900, want: false},
+		// изменение, действующее с начала, перекрыло бы новую цену
+		{name: "starts this month", start: mar, oldPrice: 29900, newPrice: 34900, want: true},
+		{name: "not started", start: apr, oldPrice: 29900, newPrice: 34900, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordsPriceHistory(tt.start, mar, tt.oldPrice, tt.newPrice); got != tt.want {
+				t.Errorf("recordsPriceHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceHistory_Update(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	// история, как ее пишет recordPriceChange при обновлении цены в марте: базовая строка
+	// с началом подписки, новая цена с текущего месяца и ранее запланированное изменение
+	sub := &domain.Subscription{Price: 34900, StartDate: jan, PriceChanges: []domain.PriceChange{
+		{EffectiveDate: jan, Price: 29900},
+		{EffectiveDate: mar, Price: 34900},
+		{EffectiveDate: jun, Price: 39900},
+	}}
+
+	tests := []struct {
+		month time.Time
+		want  domain.Money
+	}{
+		{month: feb, want: 29900},
+		{month: mar, want: 34900},
+		{month: jun.AddDate(0, -1, 0), want: 34900},
+		{month: jun, want: 39900},
+	}
+	for _, tt := range tests {
+		if got := sub.PriceAt(tt.month); got != tt.want {
+			t.Errorf("PriceAt(%s) = %d, want %d", tt.month.Format("01-2006"), got, tt.want)
+		}
+	}
+}
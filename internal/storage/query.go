@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"strconv"
+	"strings"
+)
+
+// queryColumns колонки полей выражения фильтра, поля вне списка не компилируются
+var queryColumns = map[string]string{
+	"user_id":      "s.user_id",
+	"service_name": "s.service_name",
+	"category":     "cat.category",
+	"price":        "s.price",
+	"start_date":   "s.start_date",
+	"end_date":     "s.end_date",
+}
+
+// sortColumns колонки полей сортировки результатов поиска
+var sortColumns = map[string]string{
+	"id":           "s.id",
+	"user_id":      "s.user_id",
+	"service_name": "s.service_name",
+	"category":     "cat.category",
+	"price":        "s.price",
+	"start_date":   "s.start_date",
+	"end_date":     "s.end_date",
+}
+
+var queryOperators = map[string]string{
+	domain.OpEq: "=",
+	domain.OpNe: "<>",
+	domain.OpLt: "<",
+	domain.OpLe: "<=",
+	domain.OpGt: ">",
+	domain.OpGe: ">=",
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы значение искалось как подстрока
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryCompiler переводит выражение фильтра в условие WHERE. Значения передаются
+// только параметрами, начиная с argIdx.
+type queryCompiler struct {
+	args   []interface{}
+	argIdx int
+}
+
+func (c *queryCompiler) compile(expr domain.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *domain.AndExpr:
+		return c.compileBinary("AND", e.Left, e.Right)
+	case *domain.OrExpr:
+		return c.compileBinary("OR", e.Left, e.Right)
+	case *domain.NotExpr:
+		inner, err := c.compile(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	case *domain.Comparison:
+		return c.compileComparison(e)
+	}
+	return "", fmt.Errorf("unsupported expression %T", expr)
+}
+
+func (c *queryCompiler) compileBinary(op string, left, right domain.Expr) (string, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.compile(right)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+func (c *queryCompiler) compileComparison(cmp *domain.Comparison) (string, error) {
+	column, ok := queryColumns[cmp.Field]
+	if !ok {
+		return "", fmt.Errorf("unknown field %q", cmp.Field)
+	}
+	if cmp.Value == nil {
+		if cmp.Op == domain.OpNe {
+			return column + " IS NOT NULL", nil
+		}
+		return column + " IS NULL", nil
+	}
+
+	var cond string
+	if cmp.Op == domain.OpContains {
+		cond = column + " ILIKE '%' || " + c.arg(likeEscaper.Replace(cmp.Value.(string))) + " || '%'"
+	} else {
+		op, ok := queryOperators[cmp.Op]
+		if !ok {
+			return "", fmt.Errorf("unknown operator %q", cmp.Op)
+		}
+		cond = column + " " + op + " " + c.arg(cmp.Value)
+	}
+	// null не должен давать NULL в NOT, иначе результат разойдется с domain.Expr.Eval
+	if domain.QueryFields[cmp.Field].Nullable {
+		cond = "(" + column + " IS NOT NULL AND " + cond + ")"
+	}
+	return cond, nil
+}
+
+func (c *queryCompiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	placeholder := "$" + strconv.Itoa(c.argIdx)
+	c.argIdx++
+	return placeholder
+}
+
+// sortClause список ORDER BY по белому списку колонок. Последним добавляется s.id,
+// чтобы порядок строк с равными значениями не менялся между страницами.
+func sortClause(sort []domain.SortField) (string, error) {
+	parts := make([]string, 0, len(sort)+1)
+	hasID := false
+	for _, f := range sort {
+		column, ok := sortColumns[f.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown sort field %q", f.Field)
+		}
+		if f.Desc {
+			column += " DESC"
+		}
+		parts = append(parts, column)
+		hasID = hasID || f.Field == "id"
+	}
+	if !hasID {
+		parts = append(parts, "s.id")
+	}
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agidelle/effectivemobile/internal/domain"
+)
+
+func TestSortClause(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    []domain.SortField
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "s.id"},
+		{name: "desc with id tiebreak", sort: []domain.SortField{{Field: "start_date", Desc: true}, {Field: "price"}},
+			want: "s.start_date DESC, s.price, s.id"},
+		{name: "category from catalog", sort: []domain.SortField{{Field: "category"}}, want: "cat.category, s.id"},
+		// id уже задан, второй раз не добавляется
+		{name: "explicit id", sort: []domain.SortField{{Field: "id", Desc: true}}, want: "s.id DESC"},
+		// поле вне белого списка колонок не попадает в запрос, даже если обошло проверку api
+		{name: "unknown field", sort: []domain.SortField{{Field: "s.price; DROP TABLE subscriptions"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortClause(tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortClause() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("sortClause() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchConditions(t *testing.T) {
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	minPrice, maxPrice := domain.Money(10000), domain.Money(50000)
+
+	tests := []struct {
+		name       string
+		filter     *domain.Filter
+		conditions []string
+		args       []interface{}
+	}{
+		{name: "empty", filter: &domain.Filter{}, conditions: []string{}, args: []interface{}{}},
+		{name: "price range", filter: &domain.Filter{PriceMin: &minPrice, PriceMax: &maxPrice},
+			conditions: []string{"s.price >= $1", "s.price <= $2"},
+			args:       []interface{}{minPrice, maxPrice}},
+		{name: "active period", filter: &domain.Filter{ActiveFrom: &jan, ActiveTo: &jun},
+			conditions: []string{"(s.end_date IS NULL OR s.end_date >= $1)", "s.start_date <= $2"},
+			args:       []interface{}{jan, jun}},
+		{name: "started bounds", filter: &domain.Filter{StartedAfter: &jan, StartedBefore: &jun},
+			conditions: []string{"s.start_date > $1", "s.start_date < $2"},
+			args:       []interface{}{jan, jun}},
+		// нумерация параметров продолжается после остальных условий
+		{name: "combined", filter: &domain.Filter{UserID: &userID, PriceMax: &maxPrice, ActiveFrom: &jan, StartedBefore: &jun},
+			conditions: []string{"s.user_id = $1", "s.price <= $2", "(s.end_date IS NULL OR s.end_date >= $3)", "s.start_date < $4"},
+			args:       []interface{}{userID, maxPrice, jan, jun}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, args, argIdx, err := searchConditions(tt.filter)
+			if err != nil {
+				t.Fatalf("searchConditions() error = %v", err)
+			}
+			if !reflect.DeepEqual(conditions, tt.conditions) {
+				t.Errorf("conditions = %q, want %q", conditions, tt.conditions)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+			if argIdx != len(tt.args)+1 {
+				t.Errorf("argIdx = %d, want %d", argIdx, len(tt.args)+1)
+			}
+		})
+	}
+}
+
+// TestSearchParity один и тот же фильтр компилируется в SQL и вычисляется в памяти через
+// domain.Filter.Match: ожидаемые строки записаны для условий SQL, которые проверяет тест
+func TestSearchParity(t *testing.T) {
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	music := "music"
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*domain.Subscription{
+		{ID: 1, UserID: userID, ServiceName: "Yandex Music", Price: 29900, StartDate: jan, Category: &music},
+		{ID: 2, UserID: userID, ServiceName: "Netflix", Price: 99900, StartDate: jun},
+		{ID: 3, UserID: userID, ServiceName: "Spotify", Price: 19900, StartDate: jun, EndDate: &jun, Category: &music},
+		{ID: 4, UserID: "223e4567-e89b-12d3-a456-426614174000", ServiceName: "Okko", Price: 39900, StartDate: dec},
+	}
+
+	cmp := func(field, op, literal string) domain.Expr {
+		c, err := domain.NewComparison(field, op, literal, false)
+		if err != nil {
+			t.Fatalf("NewComparison(%s %s %s): %v", field, op, literal, err)
+		}
+		return c
+	}
+	money := func(m domain.Money) *domain.Money { return &m }
+	month := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name       string
+		filter     *domain.Filter
+		conditions []string
+		args       []interface{}
+		ids        []int
+	}{
+		{name: "category and price",
+			filter: &domain.Filter{Query: &domain.AndExpr{Left: cmp("category", domain.OpEq, "music"),
+				Right: cmp("price", domain.OpGe, "250")}},
+			conditions: []string{"((cat.category IS NOT NULL AND cat.category = $1) AND s.price >= $2)"},
+			args:       []interface{}{"music", domain.Money(25000)},
+			ids:        []int{1}},
+		// null в nullable поле не проходит NOT, как и в Eval
+		{name: "not of nullable comparison",
+			filter:     &domain.Filter{Query: &domain.NotExpr{Expr: cmp("end_date", domain.OpLt, "2024-12")}},
+			conditions: []string{"NOT ((s.end_date IS NOT NULL AND s.end_date < $1))"},
+			args:       []interface{}{dec},
+			ids:        []int{1, 2, 4}},
+		{name: "category ne skips null",
+			filter:     &domain.Filter{Query: cmp("category", domain.OpNe, "video")},
+			conditions: []string{"(cat.category IS NOT NULL AND cat.category <> $1)"},
+			args:       []interface{}{"video"},
+			ids:        []int{1, 3}},
+		{name: "or with is null",
+			filter: &domain.Filter{Query: &domain.OrExpr{Left: cmp("end_date", domain.OpNe, "null"),
+				Right: cmp("service_name", domain.OpContains, "OKK")}},
+			conditions: []string{"(s.end_date IS NOT NULL OR s.service_name ILIKE '%' || $1 || '%')"},
+			args:       []interface{}{"OKK"},
+			ids:        []int{3, 4}},
+		// выражение применяется вместе с диапазонами и остальными полями фильтра
+		{name: "expression with ranges",
+			filter: &domain.Filter{UserID: &userID, PriceMin: money(20000), ActiveFrom: month(dec),
+				Query: cmp("start_date", domain.OpGe, "2024-01")},
+			conditions: []string{"s.user_id = $1", "s.price >= $2", "(s.end_date IS NULL OR s.end_date >= $3)",
+				"s.start_date >= $4"},
+			args: []interface{}{userID, domain.Money(20000), dec, jan},
+			ids:  []int{1, 2}},
+		{name: "category and started bounds",
+			filter: &domain.Filter{Category: &music, StartedAfter: month(jan), StartedBefore: month(dec),
+				Query: &domain.NotExpr{Expr: cmp("price", domain.OpGt, "250")}},
+			conditions: []string{"cat.category = $1", "s.start_date > $2", "s.start_date < $3", "NOT (s.price > $4)"},
+			args:       []interface{}{music, jan, dec, domain.Money(25000)},
+			ids:        []int{3}},
+		{name: "or with user",
+			filter: &domain.Filter{UserID: &userID, Query: &domain.OrExpr{Left: cmp("price", domain.OpGe, "500"),
+				Right: cmp("end_date", domain.OpNe, "null")}},
+			conditions: []string{"s.user_id = $1", "(s.price >= $2 OR s.end_date IS NOT NULL)"},
+			args:       []interface{}{userID, domain.Money(50000)},
+			ids:        []int{2, 3}},
+		{name: "not of required field",
+			filter:     &domain.Filter{UserID: &userID, Query: &domain.NotExpr{Expr: cmp("start_date", domain.OpLt, "2024-06")}},
+			conditions: []string{"s.user_id = $1", "NOT (s.start_date < $2)"},
+			args:       []interface{}{userID, jun},
+			ids:        []int{2, 3}},
+		{name: "no match",
+			filter:     &domain.Filter{Query: cmp("service_name", domain.OpContains, "kinopoisk")},
+			conditions: []string{"s.service_name ILIKE '%' || $1 || '%'"},
+			args:       []interface{}{"kinopoisk"},
+			ids:        nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, args, _, err := searchConditions(tt.filter)
+			if err != nil {
+				t.Fatalf("searchConditions() error = %v", err)
+			}
+			if !reflect.DeepEqual(conditions, tt.conditions) {
+				t.Errorf("conditions = %q, want %q", conditions, tt.conditions)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+			var ids []int
+			for _, sub := range subs {
+				if tt.filter.Match(sub) {
+					ids = append(ids, sub.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("Match() ids = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
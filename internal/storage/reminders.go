@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+)
+
+const preferencesColumns = "user_id, channel, email, webhook_url, days_before, renewal, ending"
+
+// SetNotificationPreferences создает или заменяет настройки напоминаний пользователя
+func (s *Storage) SetNotificationPreferences(ctx context.Context, prefs *domain.NotificationPreferences) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO notification_preferences (`+preferencesColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET channel = EXCLUDED.channel, email = EXCLUDED.email,
+			webhook_url = EXCLUDED.webhook_url, days_before = EXCLUDED.days_before,
+			renewal = EXCLUDED.renewal, ending = EXCLUDED.ending`,
+		prefs.UserID, prefs.Channel, prefs.Email, prefs.WebhookURL, prefs.DaysBefore, prefs.Renewal, prefs.Ending)
+	if err != nil {
+		slog.Error("Error saving notification preferences", "error", err)
+		return err
+	}
+	slog.Info("Notification preferences saved successfully", "user_id", prefs.UserID)
+	return nil
+}
+
+func (s *Storage) GetNotificationPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	var p domain.NotificationPreferences
+	err := s.pool.QueryRow(ctx,
+		"SELECT "+preferencesColumns+" FROM notification_preferences WHERE user_id = $1", userID).
+		Scan(&p.UserID, &p.Channel, &p.Email, &p.WebhookURL, &p.DaysBefore, &p.Renewal, &p.Ending)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("notification preferences not found")
+	}
+	if err != nil {
+		slog.Error("Error querying notification preferences", "error", err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Storage) ListNotificationPreferences(ctx context.Context) ([]*domain.NotificationPreferences, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+preferencesColumns+" FROM notification_preferences")
+	if err != nil {
+		slog.Error("Error querying notification preferences", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*domain.NotificationPreferences, 0)
+	for rows.Next() {
+		var p domain.NotificationPreferences
+		if err := rows.Scan(&p.UserID, &p.Channel, &p.Email, &p.WebhookURL, &p.DaysBefore, &p.Renewal, &p.Ending); err != nil {
+			slog.Error("Error scanning notification preferences", "error", err)
+			return nil, err
+		}
+		list = append(list, &p)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *Storage) DeleteNotificationPreferences(ctx context.Context, userID string) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		slog.Error("Error deleting notification preferences", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No notification preferences found to delete", "user_id", userID)
+		return fmt.Errorf("notification preferences not found")
+	}
+	slog.Info("Notification preferences deleted successfully", "user_id", userID)
+	return nil
+}
+
+func (s *Storage) ClaimReminder(ctx context.Context, reminder *domain.Reminder) (bool, error) {
+	res, err := s.pool.Exec(ctx, `
+		INSERT INTO reminders_sent (subscription_id, kind, charge_date) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		reminder.SubscriptionID, reminder.Kind, reminder.ChargeDate)
+	if err != nil {
+		slog.Error("Error claiming reminder", "error", err)
+		return false, err
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+func (s *Storage) ReleaseReminder(ctx context.Context, reminder *domain.Reminder) error {
+	_, err := s.pool.Exec(ctx,
+		"DELETE FROM reminders_sent WHERE subscription_id = $1 AND kind = $2 AND charge_date = $3",
+		reminder.SubscriptionID, reminder.Kind, reminder.ChargeDate)
+	if err != nil {
+		slog.Error("Error releasing reminder", "error", err)
+		return err
+	}
+	return nil
+}
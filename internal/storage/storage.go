@@ -2,9 +2,12 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/agidelle/effectivemobile/internal/config"
 	"github.com/agidelle/effectivemobile/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
 	"log/slog"
@@ -17,6 +20,18 @@ type Storage struct {
 	pool *pgxpool.Pool
 }
 
+// Колонки подписки вместе с привязанным промокодом, ставкой налога и категорией из справочника,
+// порядок совпадает со scanSubscription
+const (
+	subscriptionColumns = `s.id, s.user_id, s.service_name, s.price, s.start_date, s.end_date,
+		s.coupon_start, c.code, c.kind, c.percent, c.amount, c.duration_months,
+		s.tax_inclusive, s.tax_region, t.rate_bp, cat.category`
+	subscriptionSource = `subscriptions s
+		LEFT JOIN coupons c ON c.code = s.coupon_code
+		LEFT JOIN tax_rates t ON t.region = s.tax_region
+		LEFT JOIN service_catalog cat ON cat.service_name = s.service_name`
+)
+
 func NewPool(ctx context.Context, cfg *config.Config) *Storage {
 	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s",
 		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
@@ -51,51 +66,142 @@ func (s *Storage) CloseDB() {
 }
 
 func (s *Storage) Search(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
-	subs := make([]*domain.Subscription, 0)
-	query := "SELECT user_id, service_name, price, start_date, end_date FROM subscriptions"
+	query, args, err := searchQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.querySubscriptions(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.expand(ctx, subs, filter.Expand); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// searchQuery запрос поиска подписок по фильтру с сортировкой, limit и offset
+func searchQuery(filter *domain.Filter) (string, []interface{}, error) {
+	conditions, args, argIdx, err := searchConditions(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	query := "SELECT " + subscriptionColumns + " FROM " + subscriptionSource
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// без явной сортировки результаты q упорядочены по сходству, остальные - по ID
+	orderBy, err := sortClause(filter.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+	if filter.Q != nil && len(filter.Sort) == 0 {
+		orderBy = "word_similarity($" + strconv.Itoa(argIdx) + ", s.service_name) DESC, " + orderBy
+		args = append(args, *filter.Q)
+		argIdx++
+	}
+	query += " ORDER BY " + orderBy
+	if filter.Limit != nil {
+		query += " LIMIT $" + strconv.Itoa(argIdx)
+		args = append(args, *filter.Limit)
+		argIdx++
+	}
+	if filter.Offset != nil {
+		query += " OFFSET $" + strconv.Itoa(argIdx)
+		args = append(args, *filter.Offset)
+	}
+	return query, args, nil
+}
+
+// searchConditions условия WHERE поиска подписок и их параметры, argIdx - номер следующего параметра
+func searchConditions(filter *domain.Filter) ([]string, []interface{}, int, error) {
 	args := []interface{}{}
 	conditions := []string{}
 	argIdx := 1
 
 	if filter.UserID != nil {
-		conditions = append(conditions, "user_id = $"+strconv.Itoa(argIdx))
+		conditions = append(conditions, "s.user_id = $"+strconv.Itoa(argIdx))
 		args = append(args, *filter.UserID)
 		argIdx++
 	}
 	if filter.ServiceName != nil {
-		conditions = append(conditions, "service_name = $"+strconv.Itoa(argIdx))
+		conditions = append(conditions, "s.service_name = $"+strconv.Itoa(argIdx))
 		args = append(args, *filter.ServiceName)
 		argIdx++
 	}
 	if filter.Price != nil {
-		conditions = append(conditions, "price = $"+strconv.Itoa(argIdx))
+		conditions = append(conditions, "s.price = $"+strconv.Itoa(argIdx))
 		args = append(args, *filter.Price)
 		argIdx++
 	}
 	if filter.StartDate != nil {
-		conditions = append(conditions, "start_date = $"+strconv.Itoa(argIdx))
+		conditions = append(conditions, "s.start_date = $"+strconv.Itoa(argIdx))
 		args = append(args, *filter.StartDate)
 		argIdx++
 	}
 	if filter.EndDate != nil {
-		conditions = append(conditions, "end_date = $"+strconv.Itoa(argIdx))
+		conditions = append(conditions, "s.end_date = $"+strconv.Itoa(argIdx))
 		args = append(args, *filter.EndDate)
 		argIdx++
 	}
-
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if filter.Category != nil {
+		conditions = append(conditions, "cat.category = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.Category)
+		argIdx++
 	}
-	if filter.Limit != nil {
-		query += " LIMIT $" + strconv.Itoa(argIdx)
-		args = append(args, *filter.Limit)
+	if filter.PriceMin != nil {
+		conditions = append(conditions, "s.price >= $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.PriceMin)
 		argIdx++
 	}
-	if filter.Offset != nil {
-		query += " OFFSET $" + strconv.Itoa(argIdx)
-		args = append(args, *filter.Offset)
+	if filter.PriceMax != nil {
+		conditions = append(conditions, "s.price <= $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.PriceMax)
+		argIdx++
+	}
+	// пересечение с периодом: подписка началась до его конца и не закончилась до его начала
+	if filter.ActiveFrom != nil {
+		conditions = append(conditions, "(s.end_date IS NULL OR s.end_date >= $"+strconv.Itoa(argIdx)+")")
+		args = append(args, *filter.ActiveFrom)
+		argIdx++
+	}
+	if filter.ActiveTo != nil {
+		conditions = append(conditions, "s.start_date <= $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.ActiveTo)
+		argIdx++
+	}
+	if filter.StartedAfter != nil {
+		conditions = append(conditions, "s.start_date > $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.StartedAfter)
+		argIdx++
+	}
+	if filter.StartedBefore != nil {
+		conditions = append(conditions, "s.start_date < $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.StartedBefore)
+		argIdx++
+	}
+	if filter.Q != nil {
+		conditions = append(conditions, "(s.service_name ILIKE '%' || $"+strconv.Itoa(argIdx)+" || '%' OR $"+
+			strconv.Itoa(argIdx+1)+" <% s.service_name)")
+		args = append(args, likeEscaper.Replace(*filter.Q), *filter.Q)
+		argIdx += 2
+	}
+	if filter.Query != nil {
+		compiler := &queryCompiler{args: args, argIdx: argIdx}
+		cond, err := compiler.compile(filter.Query)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		conditions = append(conditions, cond)
+		args, argIdx = compiler.args, compiler.argIdx
 	}
 
+	return conditions, args, argIdx, nil
+}
+
+// querySubscriptions выполняет выборку подписок и подгружает участников
+func (s *Storage) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]*domain.Subscription, error) {
+	subs := make([]*domain.Subscription, 0)
 	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -103,89 +209,205 @@ func (s *Storage) Search(ctx context.Context, filter *domain.Filter) ([]*domain.
 	defer rows.Close()
 
 	for rows.Next() {
-		var s domain.Subscription
-		err = rows.Scan(&s.UserID, &s.ServiceName, &s.Price, &s.StartDate, &s.EndDate)
+		sub, err := scanSubscription(rows)
 		if err != nil {
 			return nil, err
 		}
-		subs = append(subs, &s)
+		subs = append(subs, sub)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
 
+	if err = s.loadMembers(ctx, subs); err != nil {
+		return nil, err
+	}
 	return subs, nil
 }
 
 func (s *Storage) Create(ctx context.Context, sub *domain.Subscription) error {
-	res, err := s.pool.Exec(ctx,
-		"INSERT INTO subscriptions (user_id, service_name, price, start_date, end_date) VALUES ($1, $2, $3, $4, $5)",
-		sub.UserID, sub.ServiceName, sub.Price, sub.StartDate, sub.EndDate)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		slog.Error("Error inserting subscription", "error", err)
 		return err
 	}
-	if res.RowsAffected() != 1 {
-		errMsg := fmt.Sprintf("expected to affect 1 row, affected: %d", res.RowsAffected())
-		slog.Error(errMsg)
-		return fmt.Errorf(errMsg)
+	defer tx.Rollback(ctx)
+
+	if err = createSubscription(ctx, tx, sub); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
 	}
 	slog.Info("Subscription created successfully", "user_id", sub.UserID, "service_name", sub.ServiceName)
 	return nil
 }
 
-func (s *Storage) Update(ctx context.Context, sub *domain.Subscription) error {
-	query := "UPDATE subscriptions SET price = $1, start_date = $2"
+// createSubscription создает подписку с участниками и событием subscription.created в транзакции tx
+func createSubscription(ctx context.Context, tx pgx.Tx, sub *domain.Subscription) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO subscriptions (user_id, service_name, price, start_date, end_date, tax_inclusive, tax_region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		sub.UserID, sub.ServiceName, sub.Price, sub.StartDate, sub.EndDate, sub.TaxInclusive, sub.TaxRegion).Scan(&sub.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("subscription already exists")
+		}
+		slog.Error("Error inserting subscription", "error", err)
+		return err
+	}
+	if err = insertMembers(ctx, tx, sub.ID, sub.Members); err != nil {
+		slog.Error("Error inserting subscription members", "error", err)
+		return err
+	}
+	if err = writeOutbox(ctx, tx, domain.EventCreated, sub.UserID, sub.ServiceName, sub); err != nil {
+		slog.Error("Error writing outbox event", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Update month - текущий расчетный месяц: с него в истории действует новая цена
+func (s *Storage) Update(ctx context.Context, sub *domain.Subscription, month time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = updateSubscription(ctx, tx, sub, month); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	slog.Info("Subscription updated successfully", "user_id", sub.UserID, "service_name", sub.ServiceName)
+	return nil
+}
+
+// updateSubscription обновляет подписку, пишет историю цен и событие subscription.updated в транзакции tx.
+// month - текущий расчетный месяц, как в Update
+func updateSubscription(ctx context.Context, tx pgx.Tx, sub *domain.Subscription, month time.Time) error {
+	query := "UPDATE subscriptions s SET price = $1, start_date = $2"
 	args := []interface{}{sub.Price, sub.StartDate}
 	argIdx := 3
 
+	// поля, не указанные в запросе, сохраняют прежние значения
+	if sub.TaxInclusiveSet {
+		query += ", tax_inclusive = $" + strconv.Itoa(argIdx)
+		args = append(args, sub.TaxInclusive)
+		argIdx++
+	}
 	if sub.EndDate != nil {
 		query += ", end_date = $" + strconv.Itoa(argIdx)
 		args = append(args, *sub.EndDate)
 		argIdx++
 	}
+	if sub.TaxRegion != nil {
+		query += ", tax_region = $" + strconv.Itoa(argIdx)
+		args = append(args, *sub.TaxRegion)
+		argIdx++
+	}
 
-	query += " WHERE user_id = $" + strconv.Itoa(argIdx) + " AND service_name = $" + strconv.Itoa(argIdx+1)
+	// прежние цена и дата начала нужны для записи истории цен
+	query = "WITH old AS (SELECT id, price, start_date FROM subscriptions WHERE user_id = $" + strconv.Itoa(argIdx) +
+		" AND service_name = $" + strconv.Itoa(argIdx+1) + " FOR UPDATE) " +
+		query + " FROM old WHERE s.id = old.id RETURNING s.id, s.end_date, s.tax_inclusive, s.tax_region, old.price, old.start_date"
 	args = append(args, sub.UserID, sub.ServiceName)
 
-	res, err := s.pool.Exec(ctx, query, args...)
+	var oldPrice domain.Money
+	var oldStart time.Time
+	err := tx.QueryRow(ctx, query, args...).
+		Scan(&sub.ID, &sub.EndDate, &sub.TaxInclusive, &sub.TaxRegion, &oldPrice, &oldStart)
+	if errors.Is(err, pgx.ErrNoRows) {
+		slog.Warn("No subscription found to update", "user_id", sub.UserID, "service_name", sub.ServiceName)
+		return fmt.Errorf("no subscription found for user %s and service %s", sub.UserID, sub.ServiceName)
+	}
 	if err != nil {
 		slog.Error("Error updating subscription", "error", err)
 		return err
 	}
 
-	if res.RowsAffected() == 0 {
-		slog.Warn("No subscription found to update", "user_id", sub.UserID, "service_name", sub.ServiceName)
-		return fmt.Errorf("no subscription found for user %s and service %s", sub.UserID, sub.ServiceName)
+	if recordsPriceHistory(oldStart, month, oldPrice, sub.Price) {
+		if err = recordPriceChange(ctx, tx, sub.ID, oldStart, month, oldPrice, sub.Price); err != nil {
+			slog.Error("Error recording price change", "error", err)
+			return err
+		}
+	}
+
+	// nil - участники не меняются, пустой список - подписка перестает быть совместной
+	if sub.Members != nil {
+		if _, err = tx.Exec(ctx, "DELETE FROM subscription_members WHERE subscription_id = $1", sub.ID); err != nil {
+			slog.Error("Error deleting subscription members", "error", err)
+			return err
+		}
+		if err = insertMembers(ctx, tx, sub.ID, sub.Members); err != nil {
+			slog.Error("Error inserting subscription members", "error", err)
+			return err
+		}
+	}
+	if err = writeOutbox(ctx, tx, domain.EventUpdated, sub.UserID, sub.ServiceName, sub); err != nil {
+		slog.Error("Error writing outbox event", "error", err)
+		return err
 	}
-	slog.Info("Subscription updated successfully", "user_id", sub.UserID, "service_name", sub.ServiceName)
 	return nil
 }
 
 // Delete подразумевается, что пользователь отменяет подписку и не важны сроки ее действия
 func (s *Storage) Delete(ctx context.Context, filter *domain.Filter) error {
-	res, err := s.pool.Exec(ctx, "DELETE FROM subscriptions WHERE user_id = $1 AND service_name = $2", filter.UserID, filter.ServiceName)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		slog.Error("Error deleting subscription", "error", err)
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		slog.Warn("No subscription found to delete", "user_id", filter.UserID, "service_name", filter.ServiceName)
-		return fmt.Errorf("subscription not found")
+	defer tx.Rollback(ctx)
+
+	if err = deleteSubscription(ctx, tx, *filter.UserID, *filter.ServiceName); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
 	}
 	slog.Info("Subscription deleted successfully", "user_id", filter.UserID, "service_name", filter.ServiceName)
 	return nil
 }
 
+// deleteSubscription удаляет подписку и пишет событие subscription.deleted в транзакции tx
+func deleteSubscription(ctx context.Context, tx pgx.Tx, userID, serviceName string) error {
+	// удаленная подписка попадает в событие subscription.deleted
+	var sub domain.Subscription
+	err := tx.QueryRow(ctx, `
+		DELETE FROM subscriptions WHERE user_id = $1 AND service_name = $2
+		RETURNING id, user_id, service_name, price, start_date, end_date, tax_inclusive, tax_region`,
+		userID, serviceName).
+		Scan(&sub.ID, &sub.UserID, &sub.ServiceName, &sub.Price, &sub.StartDate, &sub.EndDate, &sub.TaxInclusive, &sub.TaxRegion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		slog.Warn("No subscription found to delete", "user_id", userID, "service_name", serviceName)
+		return fmt.Errorf("subscription not found")
+	}
+	if err != nil {
+		slog.Error("Error deleting subscription", "error", err)
+		return err
+	}
+	if err = writeOutbox(ctx, tx, domain.EventDeleted, sub.UserID, sub.ServiceName, &sub); err != nil {
+		slog.Error("Error writing outbox event", "error", err)
+		return err
+	}
+	return nil
+}
+
 func (s *Storage) GetSubscriptionsForPeriod(ctx context.Context, filter *domain.Filter) ([]*domain.Subscription, error) {
+	// Пользователь попадает в выборку и как владелец, и как участник совместной подписки
 	query := `
-		SELECT user_id, service_name, price, start_date, end_date
-		FROM subscriptions
-		WHERE start_date <= $1 AND (end_date IS NULL OR end_date >= $2)
-		  AND ($3::text IS NULL OR user_id = $3)
-		  AND ($4::text IS NULL OR service_name = $4)
+		SELECT ` + subscriptionColumns + `
+		FROM ` + subscriptionSource + `
+		WHERE s.start_date <= $1 AND (s.end_date IS NULL OR s.end_date >= $2)
+		  AND ($3::text IS NULL OR s.user_id = $3
+		       OR EXISTS (SELECT 1 FROM subscription_members m WHERE m.subscription_id = s.id AND m.user_id = $3))
+		  AND ($4::text IS NULL OR s.service_name = $4)
+		  AND ($5::text IS NULL OR cat.category = $5)
 	`
-	args := []interface{}{filter.EndDate, filter.StartDate, filter.UserID, filter.ServiceName}
+	args := []interface{}{filter.EndDate, filter.StartDate, filter.UserID, filter.ServiceName, filter.Category}
 
 	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
@@ -196,18 +418,94 @@ func (s *Storage) GetSubscriptionsForPeriod(ctx context.Context, filter *domain.
 
 	var subs []*domain.Subscription
 	for rows.Next() {
-		var sub domain.Subscription
-		if err := rows.Scan(&sub.UserID, &sub.ServiceName, &sub.Price, &sub.StartDate, &sub.EndDate); err != nil {
+		sub, err := scanSubscription(rows)
+		if err != nil {
 			slog.Error("Error scanning subscription", "error", err)
 			return nil, err
 		}
-		subs = append(subs, &sub)
+		subs = append(subs, sub)
 	}
 
 	if err = rows.Err(); err != nil {
 		slog.Error("Error iterating over rows", "error", err)
 		return nil, err
 	}
+	rows.Close()
 
+	if err = s.loadMembers(ctx, subs); err != nil {
+		slog.Error("Error loading subscription members", "error", err)
+		return nil, err
+	}
+	if err = s.loadPriceChanges(ctx, subs); err != nil {
+		slog.Error("Error loading price changes", "error", err)
+		return nil, err
+	}
 	return subs, nil
 }
+
+func scanSubscription(row pgx.Row) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	var code, kind *string
+	var percent, amount *int64
+	var duration, rateBP *int
+	err := row.Scan(&sub.ID, &sub.UserID, &sub.ServiceName, &sub.Price, &sub.StartDate, &sub.EndDate,
+		&sub.CouponStart, &code, &kind, &percent, &amount, &duration,
+		&sub.TaxInclusive, &sub.TaxRegion, &rateBP, &sub.Category)
+	if err != nil {
+		return nil, err
+	}
+	if rateBP != nil {
+		sub.TaxRate = &domain.TaxRate{Region: *sub.TaxRegion, RateBP: *rateBP}
+	}
+	if code != nil {
+		sub.Coupon = newCoupon(*code, *kind, percent, amount, *duration)
+	} else {
+		sub.CouponStart = nil
+	}
+	return &sub, nil
+}
+
+func insertMembers(ctx context.Context, tx pgx.Tx, subID int, members []domain.Member) error {
+	for _, m := range members {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO subscription_members (subscription_id, user_id, share) VALUES ($1, $2, $3)",
+			subID, m.UserID, m.Share)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMembers подгружает участников совместных подписок одним запросом
+func (s *Storage) loadMembers(ctx context.Context, subs []*domain.Subscription) error {
+	if len(subs) == 0 {
+		return nil
+	}
+	byID := make(map[int]*domain.Subscription, len(subs))
+	ids := make([]int, 0, len(subs))
+	for _, sub := range subs {
+		byID[sub.ID] = sub
+		ids = append(ids, sub.ID)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT subscription_id, user_id, share FROM subscription_members WHERE subscription_id = ANY($1) ORDER BY user_id",
+		ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subID int
+		var m domain.Member
+		if err := rows.Scan(&subID, &m.UserID, &m.Share); err != nil {
+			return err
+		}
+		if sub, ok := byID[subID]; ok {
+			sub.Members = append(sub.Members, m)
+		}
+	}
+	return rows.Err()
+}
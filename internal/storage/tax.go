@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+)
+
+// SetTaxRate создает или обновляет ставку налога региона
+func (s *Storage) SetTaxRate(ctx context.Context, rate *domain.TaxRate) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO tax_rates (region, rate_bp) VALUES ($1, $2)
+		ON CONFLICT (region) DO UPDATE SET rate_bp = EXCLUDED.rate_bp`,
+		rate.Region, rate.RateBP)
+	if err != nil {
+		slog.Error("Error saving tax rate", "error", err)
+		return err
+	}
+	slog.Info("Tax rate saved successfully", "region", rate.Region, "rate_bp", rate.RateBP)
+	return nil
+}
+
+func (s *Storage) ListTaxRates(ctx context.Context) ([]*domain.TaxRate, error) {
+	rows, err := s.pool.Query(ctx, "SELECT region, rate_bp FROM tax_rates ORDER BY region")
+	if err != nil {
+		slog.Error("Error querying tax rates", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]*domain.TaxRate, 0)
+	for rows.Next() {
+		var r domain.TaxRate
+		if err := rows.Scan(&r.Region, &r.RateBP); err != nil {
+			slog.Error("Error scanning tax rate", "error", err)
+			return nil, err
+		}
+		rates = append(rates, &r)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s *Storage) DeleteTaxRate(ctx context.Context, region string) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM tax_rates WHERE region = $1", region)
+	if err != nil {
+		slog.Error("Error deleting tax rate", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No tax rate found to delete", "region", region)
+		return fmt.Errorf("tax rate not found")
+	}
+	slog.Info("Tax rate deleted successfully", "region", region)
+	return nil
+}
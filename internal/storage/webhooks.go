@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/agidelle/effectivemobile/internal/domain"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const deliveryColumns = `d.id, d.endpoint_id, d.budget_id, d.event_type, d.payload, d.status, d.attempts, d.response_code,
+		d.last_error, d.next_attempt_at, d.created_at, d.delivered_at`
+
+func (s *Storage) CreateWebhookEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_endpoints (url, secret, events, active)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		endpoint.URL, endpoint.Secret, endpoint.Events, endpoint.Active).Scan(&endpoint.ID, &endpoint.CreatedAt)
+	if err != nil {
+		slog.Error("Error inserting webhook endpoint", "error", err)
+		return err
+	}
+	slog.Info("Webhook endpoint created successfully", "id", endpoint.ID)
+	return nil
+}
+
+// ListWebhookEndpoints секреты не возвращаются
+func (s *Storage) ListWebhookEndpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT id, url, events, active, created_at FROM webhook_endpoints ORDER BY id")
+	if err != nil {
+		slog.Error("Error querying webhook endpoints", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := make([]*domain.WebhookEndpoint, 0)
+	for rows.Next() {
+		var e domain.WebhookEndpoint
+		if err := rows.Scan(&e.ID, &e.URL, &e.Events, &e.Active, &e.CreatedAt); err != nil {
+			slog.Error("Error scanning webhook endpoint", "error", err)
+			return nil, err
+		}
+		endpoints = append(endpoints, &e)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (s *Storage) DeleteWebhookEndpoint(ctx context.Context, id int) error {
+	res, err := s.pool.Exec(ctx, "DELETE FROM webhook_endpoints WHERE id = $1", id)
+	if err != nil {
+		slog.Error("Error deleting webhook endpoint", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No webhook endpoint found to delete", "id", id)
+		return fmt.Errorf("webhook endpoint not found")
+	}
+	slog.Info("Webhook endpoint deleted successfully", "id", id)
+	return nil
+}
+
+func (s *Storage) EnqueueWebhookDeliveries(ctx context.Context, eventType string, payload []byte) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT id, $1, $2 FROM webhook_endpoints
+		WHERE active AND (cardinality(events) = 0 OR $1 = ANY(events))`,
+		eventType, payload)
+	if err != nil {
+		slog.Error("Error enqueueing webhook deliveries", "event", eventType, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ClaimDueDeliveries SKIP LOCKED позволяет запускать несколько диспетчеров одновременно
+func (s *Storage) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id, COALESCE(e.url, b.webhook_url, '') AS url, COALESCE(e.secret, '') AS secret
+			FROM webhook_deliveries d
+			LEFT JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			LEFT JOIN budgets b ON b.id = d.budget_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= now()
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM due
+		WHERE d.id = due.id
+		RETURNING `+deliveryColumns+`, due.url, due.secret`,
+		limit, lease.Seconds())
+	if err != nil {
+		slog.Error("Error claiming webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.EndpointID, &d.BudgetID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+		if err != nil {
+			slog.Error("Error scanning webhook delivery", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) SaveDeliveryAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, response_code = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7`,
+		delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.LastError,
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		slog.Error("Error saving webhook delivery attempt", "id", delivery.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, filter *domain.WebhookDeliveryFilter) ([]*domain.WebhookDelivery, error) {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries d"
+	args := []interface{}{}
+	conditions := []string{}
+	argIdx := 1
+
+	if filter.EndpointID != nil {
+		conditions = append(conditions, "d.endpoint_id = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.EndpointID)
+		argIdx++
+	}
+	if filter.Status != nil {
+		conditions = append(conditions, "d.status = $"+strconv.Itoa(argIdx))
+		args = append(args, *filter.Status)
+		argIdx++
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY d.id DESC"
+	if filter.Limit != nil {
+		query += " LIMIT $" + strconv.Itoa(argIdx)
+		args = append(args, *filter.Limit)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		slog.Error("Error querying webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.EndpointID, &d.BudgetID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			slog.Error("Error scanning webhook delivery", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating over rows", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery сбрасывает счетчик попыток, доставка снова проходит полный цикл повторов
+func (s *Storage) ReplayWebhookDelivery(ctx context.Context, id int64) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("Error replaying webhook delivery", "error", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		slog.Warn("No webhook delivery found to replay", "id", id)
+		return fmt.Errorf("webhook delivery not found")
+	}
+	slog.Info("Webhook delivery queued for replay", "id", id)
+	return nil
+}
+
+// PublishExpiringSubscriptions expiry_notified хранит дату окончания, о которой уже сообщено,
+// поэтому при продлении подписки событие будет отправлено снова. Отметка и события
+// subscription.expiring в outbox записываются одной транзакцией.
+func (s *Storage) PublishExpiringSubscriptions(ctx context.Context, from, to time.Time) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH marked AS (
+			UPDATE subscriptions SET expiry_notified = end_date
+			WHERE end_date BETWEEN $1 AND $2 AND expiry_notified IS DISTINCT FROM end_date
+			RETURNING id
+		)
+		SELECT `+subscriptionColumns+`
+		FROM `+subscriptionSource+`
+		WHERE s.id IN (SELECT id FROM marked)`,
+		from, to)
+	if err != nil {
+		slog.Error("Error marking expiring subscriptions", "error", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	subs := make([]*domain.Subscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return 0, err
+		}
+		subs = append(subs, sub)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, sub := range subs {
+		if err = writeOutbox(ctx, tx, domain.EventExpiring, sub.UserID, sub.ServiceName, sub); err != nil {
+			slog.Error("Error writing outbox event", "error", err)
+			return 0, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(subs), nil
+}
@@ -0,0 +1,2 @@
+-- Журнал событий пользователя для возобновления потока по Last-Event-ID
+CREATE INDEX idx_outbox_user ON outbox (user_id, id);
@@ -0,0 +1,18 @@
+CREATE TABLE notification_preferences (
+                                          user_id VARCHAR(36) PRIMARY KEY,
+                                          channel VARCHAR(16) NOT NULL DEFAULT 'log' CHECK (channel IN ('log', 'email', 'webhook')),
+                                          email VARCHAR(255),
+                                          webhook_url TEXT,
+                                          days_before INTEGER CHECK (days_before > 0),
+                                          renewal BOOLEAN NOT NULL DEFAULT TRUE,
+                                          ending BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- Отправленные напоминания, повторно о том же списании не напоминаем
+CREATE TABLE reminders_sent (
+                                subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+                                kind VARCHAR(16) NOT NULL,
+                                charge_date DATE NOT NULL,
+                                sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                PRIMARY KEY (subscription_id, kind, charge_date)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS price_anomalies;
+ALTER TABLE service_catalog
+    DROP COLUMN IF EXISTS list_price;
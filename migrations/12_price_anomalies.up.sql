@@ -0,0 +1,18 @@
+-- Рекомендованная цена сервиса, с ней сравниваются цены подписок
+ALTER TABLE service_catalog
+    ADD COLUMN list_price BIGINT CHECK (list_price > 0);
+
+-- Обнаруженные аномалии цены. effective_date - месяц, с которого действует цена,
+-- поэтому об одной и той же цене подписки сообщается один раз
+CREATE TABLE price_anomalies (
+                                 id BIGSERIAL PRIMARY KEY,
+                                 subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+                                 kind VARCHAR(16) NOT NULL CHECK (kind IN ('outlier', 'jump')),
+                                 price BIGINT NOT NULL,
+                                 reference_price BIGINT NOT NULL,
+                                 reference VARCHAR(16) NOT NULL,
+                                 deviation_bp INTEGER NOT NULL,
+                                 effective_date DATE NOT NULL,
+                                 created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                 UNIQUE (subscription_id, kind, effective_date, price)
+);
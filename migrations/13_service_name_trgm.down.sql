@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriptions_service_name_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,4 @@
+-- Нечеткий поиск по названию сервиса (параметр q): ILIKE и word_similarity используют индекс
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX idx_subscriptions_service_name_trgm
+    ON subscriptions USING gin (service_name gin_trgm_ops);
@@ -0,0 +1,6 @@
+-- Токены ссылок на календарь списаний, хранится только sha256 токена
+CREATE TABLE calendar_tokens (
+                                 user_id VARCHAR(36) PRIMARY KEY,
+                                 token_hash CHAR(64) NOT NULL,
+                                 created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
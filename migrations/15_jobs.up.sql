@@ -0,0 +1,21 @@
+-- Очередь фоновых задач: выгрузки и сводки, которые не укладываются в таймаут запроса.
+-- Воркер забирает queued или running с устаревшим heartbeat_at (процесс упал во время выполнения)
+CREATE TABLE jobs (
+                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                      kind VARCHAR(16) NOT NULL CHECK (kind IN ('export', 'summary')),
+                      params JSONB NOT NULL DEFAULT '{}',
+                      status VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'done', 'failed')),
+                      progress INTEGER NOT NULL DEFAULT 0,
+                      attempts INTEGER NOT NULL DEFAULT 0,
+                      error TEXT,
+                      result BYTEA,
+                      content_type VARCHAR(128),
+                      file_name VARCHAR(255),
+                      created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                      started_at TIMESTAMPTZ,
+                      heartbeat_at TIMESTAMPTZ,
+                      finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_jobs_active ON jobs (created_at) WHERE status IN ('queued', 'running');
+CREATE INDEX idx_jobs_finished ON jobs (finished_at) WHERE status IN ('done', 'failed');
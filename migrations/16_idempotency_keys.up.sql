@@ -0,0 +1,13 @@
+-- Ключи идемпотентности изменяющих запросов: хэш запроса и ответ для повтора.
+-- key включает user_id из JWT, если авторизация включена; status_code пустой, пока первый запрос выполняется
+CREATE TABLE idempotency_keys (
+                                  key TEXT PRIMARY KEY,
+                                  request_hash CHAR(64) NOT NULL,
+                                  status_code INTEGER,
+                                  content_type VARCHAR(128),
+                                  response_body BYTEA,
+                                  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys (expires_at);
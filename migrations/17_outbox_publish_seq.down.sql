@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_user_seq;
+CREATE INDEX idx_outbox_user ON outbox (user_id, id);
+ALTER TABLE outbox DROP COLUMN IF EXISTS seq;
+DROP SEQUENCE IF EXISTS outbox_publish_seq;
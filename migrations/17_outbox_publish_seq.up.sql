@@ -0,0 +1,12 @@
+-- Порядковый номер публикации: relay присваивает его при отправке, поэтому номера растут в порядке
+-- публикации, а не вставки. BIGSERIAL id выдается до фиксации транзакции, и событие с меньшим id
+-- может стать видимым позже события с большим.
+CREATE SEQUENCE outbox_publish_seq;
+ALTER TABLE outbox ADD COLUMN seq BIGINT;
+
+-- уже опубликованные события сохраняют номер, равный id, чтобы Last-Event-ID клиентов остался верным
+UPDATE outbox SET seq = id WHERE published_at IS NOT NULL;
+SELECT setval('outbox_publish_seq', (SELECT COALESCE(MAX(id), 0) + 1 FROM outbox), false);
+
+DROP INDEX IF EXISTS idx_outbox_user;
+CREATE INDEX idx_outbox_user_seq ON outbox (user_id, seq) WHERE seq IS NOT NULL;
@@ -0,0 +1,5 @@
+DELETE FROM webhook_deliveries WHERE endpoint_id IS NULL;
+ALTER TABLE webhook_deliveries
+    DROP CONSTRAINT IF EXISTS webhook_deliveries_target,
+    DROP COLUMN IF EXISTS budget_id,
+    ALTER COLUMN endpoint_id SET NOT NULL;
@@ -0,0 +1,6 @@
+-- Оповещения бюджетов доставляются на webhook_url бюджета через общую очередь доставок
+-- с повторами; у такой доставки нет зарегистрированного получателя
+ALTER TABLE webhook_deliveries
+    ALTER COLUMN endpoint_id DROP NOT NULL,
+    ADD COLUMN budget_id INTEGER REFERENCES budgets (id) ON DELETE CASCADE,
+    ADD CONSTRAINT webhook_deliveries_target CHECK (num_nonnulls(endpoint_id, budget_id) = 1);
@@ -0,0 +1,9 @@
+CREATE TABLE subscription_members (
+                                      subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+                                      user_id VARCHAR(36) NOT NULL,
+                                      share INTEGER NOT NULL CHECK (share > 0),
+                                      PRIMARY KEY (subscription_id, user_id)
+);
+
+CREATE INDEX idx_subscription_members_user
+    ON subscription_members (user_id);
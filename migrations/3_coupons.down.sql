@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS coupon_start,
+    DROP COLUMN IF EXISTS coupon_code;
+DROP TABLE IF EXISTS coupons;
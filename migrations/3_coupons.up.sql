@@ -0,0 +1,10 @@
+CREATE TABLE coupons (
+                         code VARCHAR(64) PRIMARY KEY,
+                         kind VARCHAR(16) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+                         value INTEGER NOT NULL CHECK (value > 0),
+                         duration_months INTEGER NOT NULL CHECK (duration_months > 0)
+);
+
+ALTER TABLE subscriptions
+    ADD COLUMN coupon_code VARCHAR(64) REFERENCES coupons (code) ON DELETE SET NULL,
+    ADD COLUMN coupon_start DATE;
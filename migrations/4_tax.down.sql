@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS tax_region,
+    DROP COLUMN IF EXISTS tax_inclusive;
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,8 @@
+CREATE TABLE tax_rates (
+                           region VARCHAR(8) PRIMARY KEY,
+                           rate_bp INTEGER NOT NULL CHECK (rate_bp >= 0 AND rate_bp <= 10000)
+);
+
+ALTER TABLE subscriptions
+    ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN tax_region VARCHAR(8);
@@ -0,0 +1,13 @@
+ALTER TABLE coupons
+    DROP CONSTRAINT IF EXISTS coupons_kind_value_check,
+    ADD COLUMN value INTEGER;
+UPDATE coupons SET value = percent WHERE kind = 'percent';
+UPDATE coupons SET value = (amount / 100)::integer WHERE kind = 'fixed';
+ALTER TABLE coupons
+    ALTER COLUMN value SET NOT NULL,
+    ADD CONSTRAINT coupons_value_check CHECK (value > 0),
+    DROP COLUMN amount,
+    DROP COLUMN percent;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN price TYPE INTEGER USING (price / 100)::integer;
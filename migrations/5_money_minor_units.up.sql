@@ -0,0 +1,15 @@
+-- Суммы хранятся в минимальных единицах валюты (копейках), масштаб 2
+ALTER TABLE subscriptions
+    ALTER COLUMN price TYPE BIGINT USING price::bigint * 100;
+COMMENT ON COLUMN subscriptions.price IS 'minor units, scale 2';
+
+ALTER TABLE coupons
+    ADD COLUMN percent INTEGER CHECK (percent > 0 AND percent <= 100),
+    ADD COLUMN amount BIGINT CHECK (amount > 0);
+UPDATE coupons SET percent = value WHERE kind = 'percent';
+UPDATE coupons SET amount = value::bigint * 100 WHERE kind = 'fixed';
+ALTER TABLE coupons
+    DROP COLUMN value,
+    ADD CONSTRAINT coupons_kind_value_check CHECK (
+        (kind = 'percent' AND percent IS NOT NULL) OR (kind = 'fixed' AND amount IS NOT NULL));
+COMMENT ON COLUMN coupons.amount IS 'minor units, scale 2';
@@ -0,0 +1,8 @@
+-- История и запланированные изменения цены: с effective_date действует цена price
+CREATE TABLE price_changes (
+                               subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+                               effective_date DATE NOT NULL,
+                               price BIGINT NOT NULL CHECK (price > 0),
+                               PRIMARY KEY (subscription_id, effective_date)
+);
+COMMENT ON COLUMN price_changes.price IS 'minor units, scale 2';
@@ -0,0 +1,28 @@
+-- Справочник сервисов: категория используется в бюджетах и аналитике
+CREATE TABLE service_catalog (
+                                 service_name VARCHAR(255) PRIMARY KEY,
+                                 category VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE budgets (
+                         id SERIAL PRIMARY KEY,
+                         user_id VARCHAR(36),
+                         category VARCHAR(64),
+                         amount BIGINT NOT NULL CHECK (amount > 0),
+                         thresholds INTEGER[] NOT NULL DEFAULT '{80,100}',
+                         webhook_url TEXT,
+                         CHECK (user_id IS NOT NULL OR category IS NOT NULL)
+);
+COMMENT ON COLUMN budgets.amount IS 'minor units, scale 2';
+
+CREATE TABLE budget_alerts (
+                               id SERIAL PRIMARY KEY,
+                               budget_id INTEGER NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+                               month DATE NOT NULL,
+                               kind VARCHAR(16) NOT NULL CHECK (kind IN ('actual', 'forecast')),
+                               threshold INTEGER NOT NULL,
+                               spend BIGINT NOT NULL,
+                               budget_amount BIGINT NOT NULL,
+                               created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                               UNIQUE (budget_id, month, kind, threshold)
+);
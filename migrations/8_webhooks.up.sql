@@ -0,0 +1,30 @@
+CREATE TABLE webhook_endpoints (
+                                   id SERIAL PRIMARY KEY,
+                                   url TEXT NOT NULL,
+                                   secret VARCHAR(128) NOT NULL,
+                                   events TEXT[] NOT NULL DEFAULT '{}',
+                                   active BOOLEAN NOT NULL DEFAULT TRUE,
+                                   created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Журнал доставок, он же очередь: диспетчер забирает pending с наступившим next_attempt_at
+CREATE TABLE webhook_deliveries (
+                                    id BIGSERIAL PRIMARY KEY,
+                                    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+                                    event_type VARCHAR(64) NOT NULL,
+                                    payload JSONB NOT NULL,
+                                    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+                                    attempts INTEGER NOT NULL DEFAULT 0,
+                                    response_code INTEGER,
+                                    last_error TEXT,
+                                    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- Дата окончания, о которой уже отправлено событие subscription.expiring
+ALTER TABLE subscriptions
+    ADD COLUMN expiry_notified DATE;
@@ -0,0 +1,12 @@
+-- События пишутся в той же транзакции, что и изменение данных, и публикуются relay
+CREATE TABLE outbox (
+                        id BIGSERIAL PRIMARY KEY,
+                        event_type VARCHAR(64) NOT NULL,
+                        user_id VARCHAR(36),
+                        service_name VARCHAR(255),
+                        payload JSONB NOT NULL,
+                        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                        published_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;